@@ -2,6 +2,7 @@ package base
 
 import (
 	"slices"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -9,6 +10,7 @@ import (
 func NewDb() *DbTopLevel {
 	db := &DbTopLevel{}
 	db.Elements = map[Ref]any{}
+	db.Log = DefaultLog
 	return db
 }
 
@@ -16,7 +18,7 @@ func (db *DbTopLevel) newId() Ref {
 	// Create a Version 4 UUID.
 	u2, err := uuid.NewV4()
 	if err != nil {
-		Error.Fatalf("Failed to generate UUID: %v", err)
+		db.Log.Error.Fatalf("Failed to generate UUID: %v", err)
 	}
 	return Ref(u2.String())
 }
@@ -27,7 +29,7 @@ func (db *DbTopLevel) addElement(ref Ref, element any) Ref {
 	}
 	_, nok := db.Elements[ref]
 	if nok {
-		Error.Fatalf("Element Id defined more than once:\n  %s\n", ref)
+		db.Log.Error.Fatalf("Element Id defined more than once:\n  %s\n", ref)
 	}
 	db.Elements[ref] = element
 	return ref
@@ -124,18 +126,62 @@ func (db *DbTopLevel) NewLesson(ref Ref) *Lesson {
 	return e
 }
 
+func (db *DbTopLevel) NewEpochPlan(ref Ref) *EpochPlan {
+	e := &EpochPlan{}
+	e.Id = db.addElement(ref, e)
+	db.EpochPlans = append(db.EpochPlans, e)
+	return e
+}
+
 func (db *DbTopLevel) PrepareDb() {
-	if db.Info.MiddayBreak == nil {
+	if len(db.Info.MiddayBreak) == 0 {
 		db.Info.MiddayBreak = []int{}
 	} else {
 		// Sort and check contiguity.
 		slices.Sort(db.Info.MiddayBreak)
 		mb := db.Info.MiddayBreak
 		if mb[len(mb)-1]-mb[0] >= len(mb) {
-			Error.Fatalln("MiddayBreak hours not contiguous")
+			db.Log.Error.Fatalln("MiddayBreak hours not contiguous")
+		}
+	}
+
+	db.linkElements()
+
+	// Check the courses of the EpochPlans
+	for _, ep := range db.EpochPlans {
+		for _, epoch := range ep.Epochs {
+			switch db.Elements[epoch.Course].(type) {
+			case *Course, *SubCourse:
+			default:
+				db.Log.Error.Fatalf("EpochPlan %s: Invalid Course: %s\n",
+					ep.Tag, epoch.Course)
+			}
 		}
 	}
 
+	// Check that all groups belong to a class
+	for _, g := range db.Groups {
+		if g.Class == "" {
+			// This is a loader failure, it should not be possible.
+			db.Log.Bug.Fatalf("Group not in Class: %s\n", g.Id)
+		}
+	}
+	db.buildIndex()
+}
+
+// linkElements sets the fields which are derived from the references in
+// other elements (SuperCourse.SubCourses, the Lessons of the courses and
+// Group.Class). It is called by PrepareDb and again after changes to the
+// db (see mutate.go), so the old values are cleared first.
+func (db *DbTopLevel) linkElements() {
+	for _, spc := range db.SuperCourses {
+		spc.SubCourses = nil
+		spc.Lessons = nil
+	}
+	for _, c := range db.Courses {
+		c.Lessons = nil
+	}
+
 	// Collect the SubCourses for each SuperCourse
 	for _, sbc := range db.SubCourses {
 		for _, spcref := range sbc.SuperCourses {
@@ -162,35 +208,28 @@ func (db *DbTopLevel) PrepareDb() {
 			}
 		}
 	}
-	// Check that all groups belong to a class
-	for _, g := range db.Groups {
-		if g.Class == "" {
-			// This is a loader failure, it should not be possible.
-			Bug.Fatalf("Group not in Class: %s\n", g.Id)
-		}
-	}
 }
 
 func (db *DbTopLevel) CheckDbBasics() {
 	// This function is provided for use by code which needs the following
 	// Elements to be provided.
 	if len(db.Days) == 0 {
-		Error.Fatalln("No Days")
+		db.Log.Error.Fatalln("No Days")
 	}
 	if len(db.Hours) == 0 {
-		Error.Fatalln("No Hours")
+		db.Log.Error.Fatalln("No Hours")
 	}
 	if len(db.Teachers) == 0 {
-		Error.Fatalln("No Teachers")
+		db.Log.Error.Fatalln("No Teachers")
 	}
 	if len(db.Subjects) == 0 {
-		Error.Fatalln("No Subjects")
+		db.Log.Error.Fatalln("No Subjects")
 	}
 	if len(db.Rooms) == 0 {
-		Error.Fatalln("No Rooms")
+		db.Log.Error.Fatalln("No Rooms")
 	}
 	if len(db.Classes) == 0 {
-		Error.Fatalln("No Classes")
+		db.Log.Error.Fatalln("No Classes")
 	}
 }
 
@@ -213,3 +252,17 @@ func (c *Course) GetSubject() Ref       { return c.Subject }
 func (c *SubCourse) GetSubject() Ref    { return c.Subject }
 func (c *Course) GetRoom() Ref          { return c.Room }
 func (c *SubCourse) GetRoom() Ref       { return c.Room }
+
+// ValidColour reports whether c is a colour of the form "#RRGGBB" (upper
+// or lower case hexadecimal digits).
+func ValidColour(c string) bool {
+	if len(c) != 7 || c[0] != '#' {
+		return false
+	}
+	for _, r := range c[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -10,11 +10,11 @@ func (db *DbTopLevel) SaveDb(fpath string) bool {
 	// Save as JSON
 	j, err := json.MarshalIndent(db, "", "  ")
 	if err != nil {
-		Error.Println(err)
+		db.Log.Error.Println(err)
 		return false
 	}
 	if err := os.WriteFile(fpath, j, 0666); err != nil {
-		Error.Println(err)
+		db.Log.Error.Println(err)
 		return false
 	}
 	return true
@@ -42,11 +42,11 @@ func LoadDb(fpath string) *DbTopLevel {
 
 func (db *DbTopLevel) testElement(ref Ref, element any) {
 	if ref == "" {
-		Error.Fatalf("Element has no Id:\n  -- %+v\n", element)
+		db.Log.Error.Fatalf("Element has no Id:\n  -- %+v\n", element)
 	}
 	_, nok := db.Elements[ref]
 	if nok {
-		Error.Fatalf("Element Id defined more than once:\n  %s\n", ref)
+		db.Log.Error.Fatalf("Element Id defined more than once:\n  %s\n", ref)
 	}
 	db.Elements[ref] = element
 }
@@ -91,5 +91,8 @@ func (db *DbTopLevel) initElements() {
 	for _, e := range db.Lessons {
 		db.testElement(e.Id, e)
 	}
+	for _, e := range db.EpochPlans {
+		db.testElement(e.Id, e)
+	}
 	//TODO: Handle Constraints
 }
@@ -1,15 +1,108 @@
 package base
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"os"
 )
 
+// The program reports problems using four loggers (Message, Warning, Error
+// and Bug), collected in a LogSet. The command-line programs use a single
+// log file, opened by OpenLog, which is also available through the package
+// variables. Each DbTopLevel has its own LogSet (by default the one opened
+// by OpenLog), so that several timetables can be handled at the same time
+// – e.g. in a server – each with its own log.
+
+// A Logger is a log.Logger whose "Fatal" methods can be used to abort the
+// handling of a single timetable, instead of ending the program.
+type Logger struct {
+	*log.Logger
+	abort bool
+}
+
+// Abort is the panic value used by the Fatal methods of an aborting Logger.
+// It can be caught with CatchAbort.
+type Abort struct {
+	Message string
+}
+
+func (a Abort) Error() string {
+	return a.Message
+}
+
+func (l *Logger) fatal(s string) {
+	l.Output(3, s)
+	if l.abort {
+		panic(Abort{s})
+	}
+	os.Exit(1)
+}
+
+func (l *Logger) Fatal(v ...any) {
+	l.fatal(fmt.Sprint(v...))
+}
+
+func (l *Logger) Fatalf(format string, v ...any) {
+	l.fatal(fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Fatalln(v ...any) {
+	l.fatal(fmt.Sprintln(v...))
+}
+
+type LogSet struct {
+	Message *Logger
+	Warning *Logger
+	Error   *Logger
+	Bug     *Logger
+}
+
+// NewLogSet returns a LogSet writing to w. If abort is true, the Fatal
+// methods panic with an Abort value instead of ending the program.
+func NewLogSet(w io.Writer, abort bool) *LogSet {
+	return NewSplitLogSet(w, w, abort)
+}
+
+// NewSplitLogSet returns a LogSet writing messages to w and problems
+// (warnings, errors and bugs) to pw.
+func NewSplitLogSet(w io.Writer, pw io.Writer, abort bool) *LogSet {
+	newLogger := func(w io.Writer, prefix string) *Logger {
+		return &Logger{log.New(w, prefix, log.Lshortfile), abort}
+	}
+	return &LogSet{
+		Message: newLogger(w, "*INFO* "),
+		Warning: newLogger(pw, "*WARNING* "),
+		Error:   newLogger(pw, "*ERROR* "),
+		Bug:     newLogger(pw, "*BUG* "),
+	}
+}
+
+// CatchAbort runs f, returning the Abort (as error) if one of the Fatal
+// methods of an aborting Logger was called. Other panics are passed on.
+func CatchAbort(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(Abort)
+			if !ok {
+				panic(r)
+			}
+			err = a
+		}
+	}()
+	f()
+	return nil
+}
+
+// DefaultLog is the LogSet opened by OpenLog. It keeps its address when
+// OpenLog is called again, so it can be referenced before the log is opened.
+var DefaultLog = &LogSet{}
+
 var (
-	Message *log.Logger
-	Warning *log.Logger
-	Error   *log.Logger
-	Bug     *log.Logger
+	Message *Logger
+	Warning *Logger
+	Error   *Logger
+	Bug     *Logger
 )
 
 func OpenLog(logpath string) {
@@ -25,8 +118,15 @@ func OpenLog(logpath string) {
 		}
 	}
 
-	Message = log.New(file, "*INFO* ", log.Lshortfile)
-	Warning = log.New(file, "*WARNING* ", log.Lshortfile)
-	Error = log.New(file, "*ERROR* ", log.Lshortfile)
-	Bug = log.New(file, "*BUG* ", log.Lshortfile)
+	SetDefaultLog(NewLogSet(file, false))
+}
+
+// SetDefaultLog makes the given LogSet the default one, also setting the
+// package variables.
+func SetDefaultLog(logset *LogSet) {
+	*DefaultLog = *logset
+	Message = DefaultLog.Message
+	Warning = DefaultLog.Warning
+	Error = DefaultLog.Error
+	Bug = DefaultLog.Bug
 }
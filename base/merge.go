@@ -0,0 +1,389 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// Merging several dbs ("parts", e.g. the upper and lower school, which
+// are planned separately but share teachers and rooms) into one:
+//
+//   - The day/hour grid is that of the first part. A part may have more
+//     days or hours, these are added. The tags (and the times of the
+//     hours) should be the same, differences are reported as conflicts.
+//     The Classes of a part with fewer days or hours are not available
+//     at the additional times.
+//   - Teachers, Subjects, Rooms and RoomGroups are unified: an element
+//     with the Id or the Tag of an element of an earlier part is taken to
+//     be the same element, the references to it are redirected. If the
+//     Tags (for the same Id) or the Ids (for the same Tag) or the other
+//     data differ, this is reported as a conflict and the earlier element
+//     is used. Rooms, RoomGroups and RoomChoiceGroups share one tag
+//     namespace: an element whose Tag is used by one of the other kinds
+//     gets a new Tag, this is also reported.
+//   - RoomChoiceGroups are made by the loaders, with generated Tags. They
+//     are unified if they have the same Rooms, otherwise they may get new
+//     Tags.
+//   - All other elements (Classes, Groups, courses, Lessons, EpochPlans)
+//     and the constraints are taken from each part. Elements whose Ids are
+//     already used get new Ids. Classes whose Tags are already used get
+//     new Tags (so that the class names in FET are unique), this is
+//     reported. Of the constraints which apply to all courses, like
+//     AutomaticDifferentDays, only the first is taken.
+//   - Info and PrintOptions are taken from the first part.
+//
+// The merged db is not prepared (PrepareDb). The parts are used to build
+// it, so they can't be used afterwards.
+
+// A MergeConflict reports a difference between the parts of a merge.
+type MergeConflict struct {
+	Part    int    // index of the part (0 is the first)
+	Kind    string // element type, e.g. "Teacher"
+	Tag     string // tag of the element (if any)
+	Problem string
+}
+
+func (c MergeConflict) String() string {
+	if c.Tag == "" {
+		return fmt.Sprintf("Part %d, %s: %s", c.Part+1, c.Kind, c.Problem)
+	}
+	return fmt.Sprintf("Part %d, %s %s: %s",
+		c.Part+1, c.Kind, c.Tag, c.Problem)
+}
+
+type merger struct {
+	db        *DbTopLevel
+	part      int
+	refmap    map[Ref]Ref    // part Id -> merged Id
+	newIds    map[string]int // kind -> number of new Ids in the part
+	conflicts []MergeConflict
+}
+
+func (m *merger) conflict(kind string, tag string, format string, a ...any) {
+	m.conflicts = append(m.conflicts, MergeConflict{
+		m.part, kind, tag, fmt.Sprintf(format, a...)})
+}
+
+// Merge builds a new db from the given parts, see above. The conflicts
+// are returned, an error only if there are no parts.
+func Merge(parts ...*DbTopLevel) (*DbTopLevel, []MergeConflict, error) {
+	if len(parts) == 0 {
+		return nil, nil, errors.New("no dbs to merge")
+	}
+	db := NewDb()
+	db.Log = parts[0].Log
+	db.Info = parts[0].Info
+	db.PrintOptions = parts[0].PrintOptions
+	m := &merger{db: db}
+	// The grid of each part is remembered for its classes
+	type grid struct{ days, hours int }
+	classGrids := map[*Class]grid{}
+	for i, part := range parts {
+		m.part = i
+		m.refmap = map[Ref]Ref{}
+		m.newIds = map[string]int{}
+		if i != 0 {
+			if part.Info.FirstAfternoonHour != db.Info.FirstAfternoonHour ||
+				!slices.Equal(part.Info.MiddayBreak, db.Info.MiddayBreak) {
+				m.conflict("Info", "",
+					"different afternoon or midday break hours")
+			}
+		}
+		m.mergeGrid(part)
+
+		unify(m, "Teacher", part.Teachers, &db.Teachers, sameData)
+		unify(m, "Subject", part.Subjects, &db.Subjects, sameData)
+		unify(m, "Room", part.Rooms, &db.Rooms, sameData)
+		unify(m, "RoomGroup", part.RoomGroups, &db.RoomGroups,
+			func(e, x *RoomGroup) bool {
+				c := *e
+				c.Rooms = m.mapRefs(e.Rooms)
+				return sameData(&c, x)
+			})
+		m.mergeRoomChoices(part)
+
+		for _, e := range part.Classes {
+			m.newClassTag(e)
+			m.add("Class", e)
+			db.Classes = append(db.Classes, e)
+			classGrids[e] = grid{len(part.Days), len(part.Hours)}
+		}
+		db.Groups = addAll(m, "Group", part.Groups, db.Groups)
+		db.Courses = addAll(m, "Course", part.Courses, db.Courses)
+		db.SuperCourses = addAll(m, "SuperCourse", part.SuperCourses,
+			db.SuperCourses)
+		db.SubCourses = addAll(m, "SubCourse", part.SubCourses,
+			db.SubCourses)
+		db.Lessons = addAll(m, "Lesson", part.Lessons, db.Lessons)
+		db.EpochPlans = addAll(m, "EpochPlan", part.EpochPlans,
+			db.EpochPlans)
+
+		for _, c := range part.Constraints {
+			switch c.(type) {
+			case *AutomaticDifferentDays, *DoubleLessonNotOverBreaks:
+				// Only one of these
+				i := slices.IndexFunc(db.Constraints,
+					func(x Constraint) bool { return x.CType() == c.CType() })
+				if i >= 0 {
+					if !reflect.DeepEqual(c, db.Constraints[i]) {
+						m.conflict("Constraint", c.CType(),
+							"different, the first is used")
+					}
+					continue
+				}
+			}
+			db.Constraints = append(db.Constraints, c)
+		}
+
+		// Redirect the references of the part
+		for _, s := range part.refSlots() {
+			if s.single != nil {
+				if ref, ok := m.refmap[*s.single]; ok {
+					*s.single = ref
+				}
+			} else {
+				*s.list = uniqueRefs(m.mapRefs(*s.list))
+			}
+		}
+		for _, l := range part.Lessons {
+			l.Parts = m.mapRefs(l.Parts)
+		}
+
+		// One conflict for each kind of element with new Ids
+		kinds := slices.Sorted(maps.Keys(m.newIds))
+		for _, kind := range kinds {
+			m.conflict(kind, "", "%d Ids already used, new Ids",
+				m.newIds[kind])
+		}
+	}
+
+	// Classes of parts with a smaller grid are not available at the
+	// additional times
+	for c, g := range classGrids {
+		for d := range db.Days {
+			for h := range db.Hours {
+				ts := TimeSlot{d, h}
+				if (d >= g.days || h >= g.hours) &&
+					!slices.Contains(c.NotAvailable, ts) {
+					c.NotAvailable = append(c.NotAvailable, ts)
+				}
+			}
+		}
+	}
+	return db, m.conflicts, nil
+}
+
+// mapRefs returns a new list with the references redirected.
+func (m *merger) mapRefs(refs []Ref) []Ref {
+	if refs == nil {
+		return nil
+	}
+	mapped := make([]Ref, len(refs))
+	for i, ref := range refs {
+		if r, ok := m.refmap[ref]; ok {
+			ref = r
+		}
+		mapped[i] = ref
+	}
+	return mapped
+}
+
+// uniqueRefs removes repeated references (after unifying elements) from
+// a list.
+func uniqueRefs(refs []Ref) []Ref {
+	if refs == nil {
+		return nil
+	}
+	unique := []Ref{}
+	for _, ref := range refs {
+		if !slices.Contains(unique, ref) {
+			unique = append(unique, ref)
+		}
+	}
+	return unique
+}
+
+// mergeRoomChoices takes the RoomChoiceGroups of a part, see above.
+func (m *merger) mergeRoomChoices(part *DbTopLevel) {
+	tags := m.roomTags()
+	for _, e := range part.RoomChoiceGroups {
+		rooms := m.mapRefs(e.Rooms)
+		i := slices.IndexFunc(m.db.RoomChoiceGroups,
+			func(x *RoomChoiceGroup) bool {
+				return slices.Equal(x.Rooms, rooms)
+			})
+		if i >= 0 {
+			m.refmap[e.Id] = m.db.RoomChoiceGroups[i].Id
+			continue
+		}
+		for n := len(m.db.RoomChoiceGroups) + 1; tags[e.Tag] != ""; n++ {
+			e.Tag = "[" + strconv.Itoa(n) + "]"
+		}
+		tags[e.Tag] = "RoomChoiceGroup"
+		m.add("RoomChoiceGroup", e)
+		m.db.RoomChoiceGroups = append(m.db.RoomChoiceGroups, e)
+	}
+}
+
+// roomTags maps the tags of the Rooms, RoomGroups and RoomChoiceGroups of
+// the merged db to their kinds, as these share one tag namespace.
+func (m *merger) roomTags() map[string]string {
+	tags := map[string]string{}
+	for _, r := range m.db.Rooms {
+		tags[r.Tag] = "Room"
+	}
+	for _, r := range m.db.RoomGroups {
+		tags[r.Tag] = "RoomGroup"
+	}
+	for _, r := range m.db.RoomChoiceGroups {
+		tags[r.Tag] = "RoomChoiceGroup"
+	}
+	return tags
+}
+
+// mergeGrid takes the Days and Hours of a part, by index.
+func (m *merger) mergeGrid(part *DbTopLevel) {
+	for i, d := range part.Days {
+		if i < len(m.db.Days) {
+			x := m.db.Days[i]
+			if d.Tag != x.Tag {
+				m.conflict("Day", d.Tag, "different from %s", x.Tag)
+			}
+			m.refmap[d.Id] = x.Id
+			continue
+		}
+		m.add("Day", d)
+		m.db.Days = append(m.db.Days, d)
+	}
+	for i, h := range part.Hours {
+		if i < len(m.db.Hours) {
+			x := m.db.Hours[i]
+			if h.Tag != x.Tag || h.Start != x.Start || h.End != x.End {
+				m.conflict("Hour", h.Tag, "different from %s (%s – %s)",
+					x.Tag, x.Start, x.End)
+			}
+			m.refmap[h.Id] = x.Id
+			continue
+		}
+		m.add("Hour", h)
+		m.db.Hours = append(m.db.Hours, h)
+	}
+}
+
+// add adds an element of a part to the merged db's Elements. If its Id is
+// already used it gets a new one (this is counted for the conflicts).
+func (m *merger) add(kind string, e any) {
+	id := reflect.ValueOf(e).Elem().FieldByName("Id")
+	ref := Ref(id.String())
+	if _, ok := m.db.Elements[ref]; ok {
+		m.newIds[kind]++
+		id.SetString(string(m.db.newId()))
+	}
+	m.refmap[ref] = Ref(id.String())
+	m.db.Elements[Ref(id.String())] = e
+}
+
+func addAll[T any](m *merger, kind string, list []*T, mlist []*T) []*T {
+	for _, e := range list {
+		m.add(kind, e)
+		mlist = append(mlist, e)
+	}
+	return mlist
+}
+
+func idTag(e any) (Ref, string) {
+	v := reflect.ValueOf(e).Elem()
+	return Ref(v.FieldByName("Id").String()), v.FieldByName("Tag").String()
+}
+
+// unify adds the elements of a part to the merged list, unless there is
+// already an element with the same Id or Tag, which is then used instead.
+// The function same compares the data of the two elements.
+func unify[T any](
+	m *merger,
+	kind string,
+	list []*T,
+	mlist *[]*T,
+	same func(e, x *T) bool,
+) {
+	for _, e := range list {
+		id, tag := idTag(e)
+		found, ok := m.db.Elements[id].(*T)
+		if ok {
+			if _, xtag := idTag(found); xtag != tag {
+				m.conflict(kind, tag, "same Id as %s", xtag)
+			}
+		} else if tag != "" {
+			for _, x := range *mlist {
+				if xid, xtag := idTag(x); xtag == tag {
+					m.conflict(kind, tag, "same tag, different Ids: %s, %s",
+						xid, id)
+					found = x
+					break
+				}
+			}
+		}
+		if found == nil {
+			if kind == "Room" || kind == "RoomGroup" {
+				m.newRoomTag(kind, e)
+			}
+			m.add(kind, e)
+			*mlist = append(*mlist, e)
+			continue
+		}
+		m.refmap[id], _ = idTag(found)
+		if !same(e, found) {
+			m.conflict(kind, tag, "different data, the first is used")
+		}
+	}
+}
+
+// newClassTag gives a Class a new Tag if its Tag is already used in the
+// merged db.
+func (m *merger) newClassTag(e *Class) {
+	tags := map[string]bool{}
+	for _, c := range m.db.Classes {
+		tags[c.Tag] = true
+	}
+	if e.Tag == "" || !tags[e.Tag] {
+		return
+	}
+	tag := e.Tag
+	for n := m.part + 1; tags[e.Tag]; n++ {
+		e.Tag = tag + "-" + strconv.Itoa(n)
+	}
+	m.conflict("Class", tag, "tag already used, renamed to %s", e.Tag)
+}
+
+// newRoomTag gives a Room or RoomGroup a new Tag if its Tag is used by
+// another kind of room in the merged db.
+func (m *merger) newRoomTag(kind string, e any) {
+	_, tag := idTag(e)
+	tags := m.roomTags()
+	other := tags[tag]
+	if tag == "" || other == "" {
+		return
+	}
+	newtag := tag
+	for n := m.part + 1; tags[newtag] != ""; n++ {
+		newtag = tag + "-" + strconv.Itoa(n)
+	}
+	reflect.ValueOf(e).Elem().FieldByName("Tag").SetString(newtag)
+	m.conflict(kind, tag, "tag used by a %s, renamed to %s", other, newtag)
+}
+
+// sameData compares two elements of the same type, apart from the Id.
+func sameData[T any](a, b *T) bool {
+	noId := func(e *T) any {
+		v := reflect.ValueOf(e).Elem()
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		c.FieldByName("Id").SetString("")
+		return c.Interface()
+	}
+	return reflect.DeepEqual(noId(a), noId(b))
+}
@@ -0,0 +1,152 @@
+package base_test
+
+import (
+	"W365toFET/base"
+	"W365toFET/readcsv"
+	"W365toFET/ttbase"
+	"W365toFET/w365tt"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const csvfolder = "../testdata/csv"
+
+func TestMerge(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestMerge")
+	// Two parts with the same teachers, rooms and subjects (but with
+	// different Ids), the second with other class tags and one hour less
+	db1 := base.NewDb()
+	readcsv.LoadCSV(db1, csvfolder)
+	db2 := base.NewDb()
+	readcsv.LoadCSV(db2, csvfolder)
+	for _, c := range db2.Classes {
+		if err := db2.RenameTag(c.Id, "U"+c.Tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A room group of the second part gets the tag of a room of the first
+	// part (rooms and room groups share their tags)
+	for _, r := range db2.Rooms {
+		if r.Tag == "r5" {
+			db2.RenameTag(r.Id, "r5x")
+		}
+	}
+	if err := db2.RenameTag(db2.RoomGroups[0].Id, "r5"); err != nil {
+		t.Fatal(err)
+	}
+	lasthour := db2.Hours[len(db2.Hours)-1]
+	db2.Hours = db2.Hours[:len(db2.Hours)-1]
+	delete(db2.Elements, lasthour.Id)
+	nteachers := len(db1.Teachers)
+	nclasses := len(db1.Classes) + len(db2.Classes)
+	ncourses := len(db1.Courses) + len(db2.Courses)
+	nlessons := len(db1.Lessons) + len(db2.Lessons)
+	nhours := len(db1.Hours)
+	classes2 := slices.Clone(db2.Classes)
+
+	db, conflicts, err := base.Merge(db1, db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsametag := 0
+	renamed := false
+	for _, c := range conflicts {
+		if strings.Contains(c.Problem, "same tag") {
+			nsametag++
+		} else if c.Kind == "RoomGroup" && c.Tag == "r5" &&
+			strings.Contains(c.Problem, "renamed") {
+			renamed = true
+			fmt.Printf("  -- %s\n", c)
+		} else {
+			t.Errorf("Conflict: %s", c)
+		}
+	}
+	if nsametag == 0 {
+		t.Errorf("No conflicts for the same tags")
+	}
+	if !renamed {
+		t.Errorf("Room group with a room tag not renamed")
+	}
+	rtags := map[string]bool{}
+	for _, tag := range slices.Concat(
+		tagList(db.Rooms), tagList(db.RoomGroups),
+		tagList(db.RoomChoiceGroups)) {
+		if rtags[tag] {
+			t.Errorf("Room tag used twice: %s", tag)
+		}
+		rtags[tag] = true
+	}
+	if len(db.Teachers) != nteachers || len(db.Classes) != nclasses ||
+		len(db.Courses) != ncourses || len(db.Lessons) != nlessons ||
+		len(db.Hours) != nhours {
+		t.Errorf("Merged: %d teachers, %d classes, %d courses, %d lessons,"+
+			" %d hours", len(db.Teachers), len(db.Classes), len(db.Courses),
+			len(db.Lessons), len(db.Hours))
+	}
+	for _, c := range db.Courses {
+		for _, tref := range c.Teachers {
+			tch, ok := db.Elements[tref].(*base.Teacher)
+			if !ok || !slices.Contains(db.Teachers, tch) {
+				t.Errorf("Course %s: teacher not merged: %s", c.Id, tref)
+			}
+		}
+	}
+	for _, c := range classes2 {
+		ts := base.TimeSlot{Day: 0, Hour: nhours - 1}
+		if !slices.Contains(c.NotAvailable, ts) {
+			t.Errorf("Class %s: available in the additional hour", c.Tag)
+		}
+	}
+	checkRefs(t, db)
+	db.PrepareDb()
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+	fmt.Printf("  -- %d teachers, %d classes, %d activities\n",
+		len(db.Teachers), len(db.Classes), len(ttinfo.Activities))
+
+	// The same data twice: the teachers and rooms keep their Ids, the
+	// classes (and all the other elements) are reported, the classes of
+	// the second part get new tags
+	db1 = base.NewDb()
+	w365tt.LoadJSON(db1, inputfile)
+	db2 = base.NewDb()
+	w365tt.LoadJSON(db2, inputfile)
+	nteachers = len(db1.Teachers)
+	db, conflicts, _ = base.Merge(db1, db2)
+	nclasses = 0
+	for _, c := range conflicts {
+		switch c.Kind {
+		case "Teacher", "Room", "RoomGroup", "RoomChoiceGroup":
+			t.Errorf("Conflict: %s", c)
+		case "Class":
+			if c.Tag != "" &&
+				strings.HasPrefix(c.Problem, "tag already used") {
+				nclasses++
+			}
+		}
+	}
+	if nclasses == 0 || len(db.Teachers) != nteachers {
+		t.Errorf("Same data: %d class conflicts, %d teachers",
+			nclasses, len(db.Teachers))
+	}
+	ctags := tagList(db.Classes)
+	slices.Sort(ctags)
+	if len(slices.Compact(ctags)) != len(db.Classes) {
+		t.Errorf("Same data: class tags not unique")
+	}
+	fmt.Printf("  -- same data: %d conflicts\n", len(conflicts))
+}
+
+// tagList returns the tags of a list of elements.
+func tagList[T any](list []*T) []string {
+	tags := []string{}
+	for _, e := range list {
+		tag := reflect.ValueOf(e).Elem().FieldByName("Tag")
+		tags = append(tags, tag.String())
+	}
+	return tags
+}
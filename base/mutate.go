@@ -0,0 +1,450 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Changing the db: removing and replacing elements and renaming tags.
+//
+// There are no back-references in the elements, so the uses of an element
+// are found by walking through all references in the db (see
+// docs/internal_data_representation.md). This is not fast, but the
+// operations are rare.
+//
+// Remove(ref) removes a Teacher, Subject, Room, RoomGroup, RoomChoiceGroup,
+// Group, Course, SuperCourse or SubCourse. What happens to its uses
+// depends on the kind of reference:
+//
+//   - from a list (e.g. Course.Teachers, Lesson.Rooms, a division of a
+//     Class, NotOnSameDay.Subjects): the entry is removed;
+//   - an optional reference (Course.Room, SuperCourse.EpochPlan): cleared;
+//   - a reference to the "owner" of an element: the element goes too –
+//     the Lessons of a course, Epochs and constraints with single course
+//     references (e.g. LessonsEndDay);
+//   - a required reference (e.g. Course.Subject, Class.ClassGroup), or
+//     the last SuperCourse of a SubCourse: the removal is refused with an
+//     InUseError, and nothing is changed.
+//
+// Replace(old, new) makes all uses of old refer to new (an element of the
+// same type), then removes old. Lists don't get duplicate entries. A Group
+// can only be replaced by a Group of the same Class (it is dropped from
+// its division).
+//
+// After a change the derived fields (Course.Lessons, etc.) and the query
+// indexes are rebuilt, if the db has been prepared.
+
+// A Use is a reference to an element from another element or a constraint.
+type Use struct {
+	Element any    // the referring element or constraint
+	Field   string // the field with the reference, e.g. "Teachers"
+}
+
+func (u Use) String() string {
+	switch e := u.Element.(type) {
+	case Constraint:
+		return fmt.Sprintf("%s.%s", e.CType(), u.Field)
+	default:
+		id := reflect.ValueOf(e).Elem().FieldByName("Id")
+		return fmt.Sprintf("%s %s.%s", typeName(e), id, u.Field)
+	}
+}
+
+// An InUseError reports an element which can't be removed because of
+// uses which can't be updated.
+type InUseError struct {
+	Ref  Ref
+	Uses []Use
+}
+
+func (e *InUseError) Error() string {
+	uses := make([]string, len(e.Uses))
+	for i, u := range e.Uses {
+		uses[i] = u.String()
+	}
+	return fmt.Sprintf("element %s is still used: %s",
+		e.Ref, strings.Join(uses, ", "))
+}
+
+// What happens to a reference when the element is removed
+const (
+	refDrop     = iota // remove the list entry
+	refClear           // set the reference to ""
+	refRefuse          // refuse the removal
+	refCascade         // remove the referring element too
+	refNotEmpty        // remove the list entry, refuse if it is the last
+)
+
+// A refSlot is a place in the db where references are stored: a single
+// reference or a list.
+type refSlot struct {
+	Use
+	single   *Ref
+	list     *[]Ref
+	onRemove int
+	owned    bool // the referenced element belongs to the referrer
+}
+
+func (s *refSlot) refers(ref Ref) bool {
+	if s.single != nil {
+		return *s.single == ref
+	}
+	return slices.Contains(*s.list, ref)
+}
+
+// refSlots returns all the places in the db where references are stored.
+// Derived fields (Course.Lessons, Group.Class, etc.) are not included.
+func (db *DbTopLevel) refSlots() []*refSlot {
+	slots := []*refSlot{}
+	single := func(e any, field string, ref *Ref, onRemove int) {
+		slots = append(slots, &refSlot{
+			Use: Use{e, field}, single: ref, onRemove: onRemove})
+	}
+	list := func(e any, field string, refs *[]Ref, onRemove int) {
+		slots = append(slots, &refSlot{
+			Use: Use{e, field}, list: refs, onRemove: onRemove})
+	}
+	for _, e := range db.RoomGroups {
+		list(e, "Rooms", &e.Rooms, refDrop)
+	}
+	for _, e := range db.RoomChoiceGroups {
+		list(e, "Rooms", &e.Rooms, refDrop)
+	}
+	for _, e := range db.Classes {
+		slots = append(slots, &refSlot{
+			Use:    Use{e, "ClassGroup"},
+			single: &e.ClassGroup, onRemove: refRefuse, owned: true})
+		for i := range e.Divisions {
+			slots = append(slots, &refSlot{
+				Use:  Use{e, "Divisions." + e.Divisions[i].Name},
+				list: &e.Divisions[i].Groups, onRemove: refDrop, owned: true})
+		}
+	}
+	for _, e := range db.Courses {
+		single(e, "Subject", &e.Subject, refRefuse)
+		list(e, "Groups", &e.Groups, refDrop)
+		list(e, "Teachers", &e.Teachers, refDrop)
+		single(e, "Room", &e.Room, refClear)
+	}
+	for _, e := range db.SuperCourses {
+		single(e, "Subject", &e.Subject, refRefuse)
+		single(e, "EpochPlan", &e.EpochPlan, refClear)
+	}
+	for _, e := range db.SubCourses {
+		list(e, "SuperCourses", &e.SuperCourses, refNotEmpty)
+		single(e, "Subject", &e.Subject, refRefuse)
+		list(e, "Groups", &e.Groups, refDrop)
+		list(e, "Teachers", &e.Teachers, refDrop)
+		single(e, "Room", &e.Room, refClear)
+	}
+	for _, e := range db.Lessons {
+		single(e, "Course", &e.Course, refCascade)
+		list(e, "Rooms", &e.Rooms, refDrop)
+	}
+	for _, e := range db.EpochPlans {
+		for i := range e.Epochs {
+			single(e, "Epochs.Course", &e.Epochs[i].Course, refCascade)
+		}
+	}
+	for _, c := range db.Constraints {
+		switch c := c.(type) {
+		case *LessonsEndDay:
+			single(c, "Course", &c.Course, refCascade)
+		case *BeforeAfterHour:
+			list(c, "Courses", &c.Courses, refDrop)
+		case *DaysBetween:
+			list(c, "Courses", &c.Courses, refDrop)
+		case *DaysBetweenJoin:
+			single(c, "Course1", &c.Course1, refCascade)
+			single(c, "Course2", &c.Course2, refCascade)
+		case *ParallelCourses:
+			list(c, "Courses", &c.Courses, refDrop)
+		case *NotOnSameDay:
+			list(c, "Subjects", &c.Subjects, refDrop)
+		case *MinHoursFollowing:
+			single(c, "Course1", &c.Course1, refCascade)
+			single(c, "Course2", &c.Course2, refCascade)
+		}
+	}
+	return slots
+}
+
+// Uses returns the references to an element from other elements and
+// constraints.
+func (db *DbTopLevel) Uses(ref Ref) []Use {
+	uses := []Use{}
+	for _, s := range db.refSlots() {
+		if s.refers(ref) {
+			uses = append(uses, s.Use)
+		}
+	}
+	return uses
+}
+
+// removable checks that the element can be removed or replaced.
+func (db *DbTopLevel) removable(ref Ref) (any, error) {
+	e, ok := db.Elements[ref]
+	if !ok {
+		return nil, fmt.Errorf("unknown element: %s", ref)
+	}
+	switch e.(type) {
+	case *Teacher, *Subject, *Room, *RoomGroup, *RoomChoiceGroup,
+		*Group, *Course, *SuperCourse, *SubCourse:
+		return e, nil
+	}
+	return nil, fmt.Errorf("element %s (%s) can't be removed",
+		ref, typeName(e))
+}
+
+// Remove removes an element and updates its uses, see above.
+func (db *DbTopLevel) Remove(ref Ref) error {
+	if _, err := db.removable(ref); err != nil {
+		return err
+	}
+	return db.replace(ref, "")
+}
+
+// Replace makes all uses of the element old refer to new instead and
+// removes old.
+func (db *DbTopLevel) Replace(old, new Ref) error {
+	e, err := db.removable(old)
+	if err != nil {
+		return err
+	}
+	if old == new {
+		return fmt.Errorf("element %s can't replace itself", old)
+	}
+	enew, ok := db.Elements[new]
+	if !ok || reflect.TypeOf(enew) != reflect.TypeOf(e) {
+		return &ElementError{new, typeName(e), enew}
+	}
+	if _, ok := e.(*Group); ok {
+		if db.groupClass(old) != db.groupClass(new) {
+			return fmt.Errorf("group %s is not in the class of group %s",
+				new, old)
+		}
+	}
+	return db.replace(old, new)
+}
+
+// replace does the work for Remove (new = "") and Replace.
+func (db *DbTopLevel) replace(old, new Ref) error {
+	slots := []*refSlot{}
+	refused := []Use{}
+	for _, s := range db.refSlots() {
+		if !s.refers(old) {
+			continue
+		}
+		slots = append(slots, s)
+		if new != "" && !s.owned {
+			continue
+		}
+		switch s.onRemove {
+		case refRefuse:
+			refused = append(refused, s.Use)
+		case refNotEmpty:
+			if len(*s.list) == 1 {
+				refused = append(refused, s.Use)
+			}
+		}
+	}
+	if len(refused) != 0 {
+		return &InUseError{old, refused}
+	}
+
+	cascade := []any{} // elements and constraints to remove
+	for _, s := range slots {
+		switch {
+		case new != "" && !s.owned:
+			if s.single != nil {
+				*s.single = new
+			} else {
+				l := *s.list
+				i := slices.Index(l, old)
+				if slices.Contains(l, new) {
+					*s.list = slices.Delete(l, i, i+1)
+				} else {
+					l[i] = new
+				}
+			}
+		case s.onRemove == refClear:
+			*s.single = ""
+		case s.onRemove == refCascade:
+			cascade = append(cascade, s.Element)
+		default:
+			*s.list = slices.DeleteFunc(*s.list,
+				func(r Ref) bool { return r == old })
+		}
+	}
+	for _, e := range cascade {
+		switch e := e.(type) {
+		case *Lesson:
+			db.removeElement(e.Id)
+		case *EpochPlan:
+			e.Epochs = slices.DeleteFunc(e.Epochs,
+				func(ep Epoch) bool { return ep.Course == old })
+		case Constraint:
+			db.Constraints = slices.DeleteFunc(db.Constraints,
+				func(c Constraint) bool { return c == e })
+		}
+	}
+	// Empty divisions are dropped
+	for _, c := range db.Classes {
+		c.Divisions = slices.DeleteFunc(c.Divisions,
+			func(d Division) bool { return len(d.Groups) == 0 })
+	}
+	db.removeElement(old)
+	db.refresh()
+	return nil
+}
+
+// removeElement removes an element from Elements and its list.
+func (db *DbTopLevel) removeElement(ref Ref) {
+	switch e := db.Elements[ref].(type) {
+	case *Teacher:
+		db.Teachers = removeFrom(db.Teachers, e)
+	case *Subject:
+		db.Subjects = removeFrom(db.Subjects, e)
+	case *Room:
+		db.Rooms = removeFrom(db.Rooms, e)
+	case *RoomGroup:
+		db.RoomGroups = removeFrom(db.RoomGroups, e)
+	case *RoomChoiceGroup:
+		db.RoomChoiceGroups = removeFrom(db.RoomChoiceGroups, e)
+	case *Group:
+		db.Groups = removeFrom(db.Groups, e)
+	case *Course:
+		db.Courses = removeFrom(db.Courses, e)
+	case *SuperCourse:
+		db.SuperCourses = removeFrom(db.SuperCourses, e)
+	case *SubCourse:
+		db.SubCourses = removeFrom(db.SubCourses, e)
+	case *Lesson:
+		db.Lessons = removeFrom(db.Lessons, e)
+	default:
+		db.Log.Bug.Fatalf("Can't remove element %s (%s)\n",
+			ref, typeName(e))
+	}
+	delete(db.Elements, ref)
+}
+
+func removeFrom[T comparable](list []T, e T) []T {
+	return slices.DeleteFunc(list, func(x T) bool { return x == e })
+}
+
+// refresh rebuilds the derived fields and the indexes after a change, if
+// the db has been prepared.
+func (db *DbTopLevel) refresh() {
+	if db.index.prepared {
+		db.linkElements()
+		db.buildIndex()
+	}
+}
+
+// groupClass returns the Class of a Group, also if the db has not been
+// prepared. For a Group which is in no Class it returns "".
+func (db *DbTopLevel) groupClass(gref Ref) Ref {
+	for _, c := range db.Classes {
+		if c.ClassGroup == gref {
+			return c.Id
+		}
+		for _, d := range c.Divisions {
+			if slices.Contains(d.Groups, gref) {
+				return c.Id
+			}
+		}
+	}
+	return ""
+}
+
+// ErrTagInUse is returned by RenameTag if the tag is already used.
+var ErrTagInUse = errors.New("tag already in use")
+
+// RenameTag sets the Tag of an element. The tag may not be empty and must
+// be unique among the elements of the same kind: Rooms, RoomGroups and
+// RoomChoiceGroups share their tags, Group tags are only unique within
+// their Class.
+func (db *DbTopLevel) RenameTag(ref Ref, tag string) error {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return fmt.Errorf("element %s: empty tag", ref)
+	}
+	var ptag *string
+	others := []string{}
+	switch e := db.Elements[ref].(type) {
+	case *Day:
+		ptag = &e.Tag
+		for _, x := range db.Days {
+			others = append(others, x.Tag)
+		}
+	case *Hour:
+		ptag = &e.Tag
+		for _, x := range db.Hours {
+			others = append(others, x.Tag)
+		}
+	case *Teacher:
+		ptag = &e.Tag
+		for _, x := range db.Teachers {
+			others = append(others, x.Tag)
+		}
+	case *Subject:
+		ptag = &e.Tag
+		for _, x := range db.Subjects {
+			others = append(others, x.Tag)
+		}
+	case *Room, *RoomGroup, *RoomChoiceGroup:
+		switch e := e.(type) {
+		case *Room:
+			ptag = &e.Tag
+		case *RoomGroup:
+			ptag = &e.Tag
+		case *RoomChoiceGroup:
+			ptag = &e.Tag
+		}
+		for _, x := range db.Rooms {
+			others = append(others, x.Tag)
+		}
+		for _, x := range db.RoomGroups {
+			others = append(others, x.Tag)
+		}
+		for _, x := range db.RoomChoiceGroups {
+			others = append(others, x.Tag)
+		}
+	case *Class:
+		ptag = &e.Tag
+		for _, x := range db.Classes {
+			others = append(others, x.Tag)
+		}
+	case *Group:
+		cref := db.groupClass(ref)
+		if c, ok := db.Elements[cref].(*Class); !ok || c.ClassGroup == ref {
+			return fmt.Errorf("group %s: not in a division", ref)
+		}
+		ptag = &e.Tag
+		for _, x := range db.Groups {
+			if db.groupClass(x.Id) == cref {
+				others = append(others, x.Tag)
+			}
+		}
+	case *EpochPlan:
+		ptag = &e.Tag
+		for _, x := range db.EpochPlans {
+			others = append(others, x.Tag)
+		}
+	case nil:
+		return fmt.Errorf("unknown element: %s", ref)
+	default:
+		return fmt.Errorf("element %s (%s) has no tag", ref, typeName(e))
+	}
+	if *ptag == tag {
+		return nil
+	}
+	if slices.Contains(others, tag) {
+		return fmt.Errorf("%w: %s", ErrTagInUse, tag)
+	}
+	*ptag = tag
+	return nil
+}
@@ -0,0 +1,169 @@
+package base_test
+
+import (
+	"W365toFET/base"
+	"W365toFET/w365tt"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMutate(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestMutate")
+	db := base.NewDb()
+	w365tt.LoadJSON(db, inputfile)
+	db.PrepareDb()
+
+	// Remove a teacher: the courses lose the teacher
+	tch := db.Teachers[0]
+	ntch := len(db.Teachers)
+	if len(db.Uses(tch.Id)) == 0 {
+		t.Fatalf("Teacher %s: no uses", tch.Tag)
+	}
+	if err := db.Remove(tch.Id); err != nil {
+		t.Fatalf("Remove teacher: %v", err)
+	}
+	if len(db.Teachers) != ntch-1 || db.Elements[tch.Id] != nil ||
+		len(db.Uses(tch.Id)) != 0 {
+		t.Errorf("Remove teacher: still there")
+	}
+	if _, err := db.CoursesForTeacher(tch.Id); err == nil {
+		t.Errorf("Remove teacher: still indexed")
+	}
+
+	// A subject used by courses can't be removed, but it can be replaced
+	s1, s2 := db.Subjects[0], db.Subjects[1]
+	c1, _ := db.CoursesForSubject(s1.Id)
+	c2, _ := db.CoursesForSubject(s2.Id)
+	err := db.Remove(s1.Id)
+	var uerr *base.InUseError
+	if !errors.As(err, &uerr) || len(uerr.Uses) != len(c1) {
+		t.Errorf("Remove used subject: %v", err)
+	} else {
+		fmt.Printf("  -- %d uses of subject %s\n", len(uerr.Uses), s1.Tag)
+	}
+	if db.Elements[s1.Id] == nil {
+		t.Errorf("Remove used subject: removed")
+	}
+	if err := db.Replace(s1.Id, db.Teachers[0].Id); err == nil {
+		t.Errorf("Replace subject by teacher: no error")
+	}
+	if err := db.Replace(s1.Id, s2.Id); err != nil {
+		t.Errorf("Replace subject: %v", err)
+	}
+	if c, _ := db.CoursesForSubject(s2.Id); len(c) != len(c1)+len(c2) {
+		t.Errorf("Replace subject: %d courses, expected %d",
+			len(c), len(c1)+len(c2))
+	}
+
+	// Remove a room: the courses have no room, the room groups and lessons
+	// lose the room
+	room := db.Rooms[0]
+	if err := db.Remove(room.Id); err != nil {
+		t.Errorf("Remove room: %v", err)
+	}
+	for _, c := range db.Courses {
+		if c.Room == room.Id {
+			t.Errorf("Remove room: still in course %s", c.Id)
+		}
+	}
+	for _, rg := range db.RoomGroups {
+		if slices.Contains(rg.Rooms, room.Id) {
+			t.Errorf("Remove room: still in room group %s", rg.Tag)
+		}
+	}
+
+	// Remove a course: its lessons go too
+	var course *base.Course
+	for _, c := range db.Courses {
+		if len(c.Lessons) != 0 {
+			course = c
+			break
+		}
+	}
+	nlessons := len(db.Lessons) - len(course.Lessons)
+	if err := db.Remove(course.Id); err != nil {
+		t.Errorf("Remove course: %v", err)
+	}
+	if len(db.Lessons) != nlessons {
+		t.Errorf("Remove course: %d lessons, expected %d",
+			len(db.Lessons), nlessons)
+	}
+
+	// A class group can't be removed, a division group can
+	cl := db.Classes[0]
+	if err := db.Remove(cl.ClassGroup); err == nil {
+		t.Errorf("Remove class group: no error")
+	}
+	for _, c := range db.Classes {
+		if len(c.Divisions) != 0 {
+			cl = c
+			break
+		}
+	}
+	g := cl.Divisions[0].Groups[0]
+	if err := db.Remove(g); err != nil {
+		t.Errorf("Remove group: %v", err)
+	}
+	if len(db.Uses(g)) != 0 {
+		t.Errorf("Remove group: still used")
+	}
+
+	// Lessons can't be removed on their own
+	if err := db.Remove(db.Lessons[0].Id); err == nil {
+		t.Errorf("Remove lesson: no error")
+	}
+
+	// Rename tags
+	t1, t2 := db.Teachers[0], db.Teachers[1]
+	if err := db.RenameTag(t1.Id, t2.Tag); !errors.Is(
+		err, base.ErrTagInUse) {
+		t.Errorf("RenameTag to used tag: %v", err)
+	}
+	if err := db.RenameTag(t1.Id, "NEU"); err != nil || t1.Tag != "NEU" {
+		t.Errorf("RenameTag: %v", err)
+	}
+	if err := db.RenameTag(db.Rooms[0].Id, db.RoomGroups[0].Tag); err == nil {
+		t.Errorf("RenameTag room to room group tag: no error")
+	}
+	if err := db.RenameTag(db.Lessons[0].Id, "X"); err == nil {
+		t.Errorf("RenameTag lesson: no error")
+	}
+
+	// All references must still be valid
+	checkRefs(t, db)
+}
+
+// checkRefs tests the references in the courses and lessons.
+func checkRefs(t *testing.T, db *base.DbTopLevel) {
+	check := func(what string, refs ...base.Ref) {
+		for _, ref := range refs {
+			if ref != "" && db.Elements[ref] == nil {
+				t.Errorf("%s: invalid reference %s", what, ref)
+			}
+		}
+	}
+	for _, c := range db.Courses {
+		check("Course "+string(c.Id), c.Subject, c.Room)
+		check("Course "+string(c.Id), c.Groups...)
+		check("Course "+string(c.Id), c.Teachers...)
+	}
+	for _, c := range db.SubCourses {
+		check("SubCourse "+string(c.Id), c.Subject, c.Room)
+		check("SubCourse "+string(c.Id), c.SuperCourses...)
+		check("SubCourse "+string(c.Id), c.Groups...)
+		check("SubCourse "+string(c.Id), c.Teachers...)
+	}
+	for _, l := range db.Lessons {
+		check("Lesson "+string(l.Id), l.Course)
+		check("Lesson "+string(l.Id), l.Rooms...)
+	}
+	for _, rg := range db.RoomGroups {
+		check("RoomGroup "+rg.Tag, rg.Rooms...)
+	}
+	for _, rg := range db.RoomChoiceGroups {
+		check("RoomChoiceGroup "+rg.Tag, rg.Rooms...)
+	}
+}
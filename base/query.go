@@ -0,0 +1,242 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The query layer: indexes over the db, built by PrepareDb, which answer
+// the common questions without walking the element lists (with type
+// assertions) each time:
+//
+//	CoursesForTeacher, CoursesForGroup, CoursesForClass,
+//	CoursesForSubject, CoursesForRoom – the Courses and SubCourses
+//	LessonsForCourse – the Lessons of a Course, SuperCourse or SubCourse
+//	DivisionGroups   – the Groups of a division of a Class
+//	GroupClass       – the Class of a Group
+//
+// The lookups are typed and return an error for an unknown reference or a
+// reference to an element of the wrong type. The returned lists are in
+// the order of the db's element lists (Courses before SubCourses) and may
+// be changed by the caller.
+
+// ErrNotPrepared is returned by the lookups if PrepareDb has not been run.
+var ErrNotPrepared = errors.New("db not prepared (PrepareDb)")
+
+// An ElementError reports a reference which doesn't refer to an element
+// of the expected type.
+type ElementError struct {
+	Ref      Ref
+	Expected string // the type name, e.g. "Teacher"
+	Found    any    // the element found, nil if none
+}
+
+func (e *ElementError) Error() string {
+	if e.Found == nil {
+		return fmt.Sprintf("unknown element (expected %s): %s",
+			e.Expected, e.Ref)
+	}
+	return fmt.Sprintf("element %s is %s, not %s",
+		e.Ref, typeName(e.Found), e.Expected)
+}
+
+func typeName(v any) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*base.")
+}
+
+// Element returns the element with the given reference, which must be of
+// type T (e.g. *Teacher, or an interface like GeneralRoom).
+func Element[T any](db *DbTopLevel, ref Ref) (T, error) {
+	e, ok := db.Elements[ref].(T)
+	if !ok {
+		var zero T
+		t := reflect.TypeFor[T]()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		return zero, &ElementError{ref, t.Name(), db.Elements[ref]}
+	}
+	return e, nil
+}
+
+type dbIndex struct {
+	prepared       bool
+	teacherCourses map[Ref][]CourseInterface
+	groupCourses   map[Ref][]CourseInterface
+	classCourses   map[Ref][]CourseInterface
+	subjectCourses map[Ref][]CourseInterface
+	roomCourses    map[Ref][]CourseInterface
+}
+
+// buildIndex builds the indexes for the lookups. It is called at the end
+// of PrepareDb, when the Groups have their Classes.
+func (db *DbTopLevel) buildIndex() {
+	index := dbIndex{
+		prepared:       true,
+		teacherCourses: map[Ref][]CourseInterface{},
+		groupCourses:   map[Ref][]CourseInterface{},
+		classCourses:   map[Ref][]CourseInterface{},
+		subjectCourses: map[Ref][]CourseInterface{},
+		roomCourses:    map[Ref][]CourseInterface{},
+	}
+	add := func(m map[Ref][]CourseInterface, ref Ref, c CourseInterface) {
+		// A course is only added once for each key
+		if l := m[ref]; len(l) == 0 || l[len(l)-1] != c {
+			m[ref] = append(l, c)
+		}
+	}
+	courses := []CourseInterface{}
+	for _, c := range db.Courses {
+		courses = append(courses, c)
+	}
+	for _, c := range db.SubCourses {
+		courses = append(courses, c)
+	}
+	for _, c := range courses {
+		for _, t := range c.GetTeachers() {
+			add(index.teacherCourses, t, c)
+		}
+		for _, g := range c.GetGroups() {
+			add(index.groupCourses, g, c)
+			if grp, ok := db.Elements[g].(*Group); ok {
+				add(index.classCourses, grp.Class, c)
+			}
+		}
+		add(index.subjectCourses, c.GetSubject(), c)
+		if r := c.GetRoom(); r != "" {
+			add(index.roomCourses, r, c)
+			// Also the rooms of room groups and room choices
+			var rooms []Ref
+			switch rg := db.Elements[r].(type) {
+			case *RoomGroup:
+				rooms = rg.Rooms
+			case *RoomChoiceGroup:
+				rooms = rg.Rooms
+			}
+			for _, rr := range rooms {
+				add(index.roomCourses, rr, c)
+			}
+		}
+	}
+	db.index = index
+}
+
+// coursesFor checks that ref is an element of type T and returns the
+// courses indexed for it.
+func coursesFor[T any](
+	db *DbTopLevel,
+	m map[Ref][]CourseInterface,
+	ref Ref,
+) ([]CourseInterface, error) {
+	if !db.index.prepared {
+		return nil, ErrNotPrepared
+	}
+	if _, err := Element[T](db, ref); err != nil {
+		return nil, err
+	}
+	return slices.Clone(m[ref]), nil
+}
+
+// CoursesForTeacher returns the Courses and SubCourses of a Teacher.
+func (db *DbTopLevel) CoursesForTeacher(ref Ref) ([]CourseInterface, error) {
+	return coursesFor[*Teacher](db, db.index.teacherCourses, ref)
+}
+
+// CoursesForGroup returns the Courses and SubCourses with the given Group
+// (not those with other Groups of its Class).
+func (db *DbTopLevel) CoursesForGroup(ref Ref) ([]CourseInterface, error) {
+	return coursesFor[*Group](db, db.index.groupCourses, ref)
+}
+
+// CoursesForClass returns the Courses and SubCourses with any Group of the
+// given Class (including the whole class).
+func (db *DbTopLevel) CoursesForClass(ref Ref) ([]CourseInterface, error) {
+	return coursesFor[*Class](db, db.index.classCourses, ref)
+}
+
+// CoursesForSubject returns the Courses and SubCourses of a Subject.
+func (db *DbTopLevel) CoursesForSubject(ref Ref) ([]CourseInterface, error) {
+	return coursesFor[*Subject](db, db.index.subjectCourses, ref)
+}
+
+// CoursesForRoom returns the Courses and SubCourses with the given room,
+// which can be a Room, RoomGroup or RoomChoiceGroup. For a Room also the
+// courses with a RoomGroup or RoomChoiceGroup containing it are included.
+func (db *DbTopLevel) CoursesForRoom(ref Ref) ([]CourseInterface, error) {
+	return coursesFor[GeneralRoom](db, db.index.roomCourses, ref)
+}
+
+// LessonsForCourse returns the Lessons of a Course or SuperCourse. For a
+// SubCourse the Lessons of its SuperCourses are returned.
+func (db *DbTopLevel) LessonsForCourse(ref Ref) ([]*Lesson, error) {
+	if !db.index.prepared {
+		return nil, ErrNotPrepared
+	}
+	var lrefs []Ref
+	switch c := db.Elements[ref].(type) {
+	case *Course:
+		lrefs = c.Lessons
+	case *SuperCourse:
+		lrefs = c.Lessons
+	case *SubCourse:
+		for _, spc := range c.SuperCourses {
+			sc, err := Element[*SuperCourse](db, spc)
+			if err != nil {
+				return nil, err
+			}
+			lrefs = append(lrefs, sc.Lessons...)
+		}
+	default:
+		return nil, &ElementError{ref, "Course", db.Elements[ref]}
+	}
+	lessons := make([]*Lesson, 0, len(lrefs))
+	for _, lref := range lrefs {
+		l, err := Element[*Lesson](db, lref)
+		if err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, l)
+	}
+	return lessons, nil
+}
+
+// DivisionGroups returns the Groups of the named division of a Class.
+func (db *DbTopLevel) DivisionGroups(
+	cref Ref,
+	division string,
+) ([]*Group, error) {
+	c, err := Element[*Class](db, cref)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range c.Divisions {
+		if d.Name != division {
+			continue
+		}
+		groups := make([]*Group, 0, len(d.Groups))
+		for _, gref := range d.Groups {
+			g, err := Element[*Group](db, gref)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, g)
+		}
+		return groups, nil
+	}
+	return nil, fmt.Errorf("class %s has no division %q", c.Tag, division)
+}
+
+// GroupClass returns the Class of a Group.
+func (db *DbTopLevel) GroupClass(gref Ref) (*Class, error) {
+	if !db.index.prepared {
+		return nil, ErrNotPrepared
+	}
+	g, err := Element[*Group](db, gref)
+	if err != nil {
+		return nil, err
+	}
+	return Element[*Class](db, g.Class)
+}
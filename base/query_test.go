@@ -0,0 +1,167 @@
+package base_test
+
+import (
+	"W365toFET/base"
+	"W365toFET/w365tt"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+const inputfile = "../testdata/Versuch_D_Margin_hour_constraint_w365.json"
+
+func TestQuery(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestQuery")
+	db := base.NewDb()
+	w365tt.LoadJSON(db, inputfile)
+
+	// Before PrepareDb
+	if _, err := db.CoursesForTeacher(db.Teachers[0].Id); !errors.Is(
+		err, base.ErrNotPrepared) {
+		t.Errorf("Not prepared: %v", err)
+	}
+	db.PrepareDb()
+
+	// Compare with walking the courses
+	contains := func(refs []base.Ref, ref base.Ref) bool {
+		return slices.Contains(refs, ref)
+	}
+	allCourses := []base.CourseInterface{}
+	for _, c := range db.Courses {
+		allCourses = append(allCourses, c)
+	}
+	for _, c := range db.SubCourses {
+		allCourses = append(allCourses, c)
+	}
+	check := func(
+		what string,
+		ref base.Ref,
+		courses []base.CourseInterface,
+		err error,
+		match func(c base.CourseInterface) bool,
+	) {
+		if err != nil {
+			t.Errorf("%s %s: %v", what, ref, err)
+			return
+		}
+		n := 0
+		for _, c := range allCourses {
+			if match(c) {
+				n++
+				if !slices.Contains(courses, c) {
+					t.Errorf("%s %s: course missing: %s", what, ref, c.GetId())
+				}
+			}
+		}
+		if n != len(courses) {
+			t.Errorf("%s %s: %d courses, expected %d",
+				what, ref, len(courses), n)
+		}
+	}
+	for _, tch := range db.Teachers {
+		courses, err := db.CoursesForTeacher(tch.Id)
+		check("Teacher", tch.Id, courses, err,
+			func(c base.CourseInterface) bool {
+				return contains(c.GetTeachers(), tch.Id)
+			})
+	}
+	for _, s := range db.Subjects {
+		courses, err := db.CoursesForSubject(s.Id)
+		check("Subject", s.Id, courses, err, func(c base.CourseInterface) bool {
+			return c.GetSubject() == s.Id
+		})
+	}
+	for _, g := range db.Groups {
+		courses, err := db.CoursesForGroup(g.Id)
+		check("Group", g.Id, courses, err, func(c base.CourseInterface) bool {
+			return contains(c.GetGroups(), g.Id)
+		})
+		cl, err := db.GroupClass(g.Id)
+		if err != nil || cl.Id != g.Class {
+			t.Errorf("GroupClass %s: %v", g.Id, err)
+		}
+	}
+	nclass := 0
+	for _, cl := range db.Classes {
+		courses, err := db.CoursesForClass(cl.Id)
+		check("Class", cl.Id, courses, err, func(c base.CourseInterface) bool {
+			for _, g := range c.GetGroups() {
+				if db.Elements[g].(*base.Group).Class == cl.Id {
+					return true
+				}
+			}
+			return false
+		})
+		nclass += len(courses)
+		for _, d := range cl.Divisions {
+			groups, err := db.DivisionGroups(cl.Id, d.Name)
+			if err != nil || len(groups) != len(d.Groups) {
+				t.Errorf("DivisionGroups %s/%s: %v", cl.Tag, d.Name, err)
+			}
+		}
+	}
+	for _, r := range db.Rooms {
+		courses, err := db.CoursesForRoom(r.Id)
+		check("Room", r.Id, courses, err, func(c base.CourseInterface) bool {
+			switch rr := db.Elements[c.GetRoom()].(type) {
+			case *base.Room:
+				return rr.Id == r.Id
+			case *base.RoomGroup:
+				return contains(rr.Rooms, r.Id)
+			case *base.RoomChoiceGroup:
+				return contains(rr.Rooms, r.Id)
+			}
+			return false
+		})
+	}
+	nlessons := 0
+	for _, c := range db.Courses {
+		lessons, err := db.LessonsForCourse(c.Id)
+		if err != nil {
+			t.Errorf("LessonsForCourse %s: %v", c.Id, err)
+		}
+		nlessons += len(lessons)
+	}
+	for _, c := range db.SuperCourses {
+		lessons, err := db.LessonsForCourse(c.Id)
+		if err != nil {
+			t.Errorf("LessonsForCourse %s: %v", c.Id, err)
+		}
+		nlessons += len(lessons)
+	}
+	if nlessons != len(db.Lessons) {
+		t.Errorf("LessonsForCourse: %d lessons, expected %d",
+			nlessons, len(db.Lessons))
+	}
+	fmt.Printf("  -- %d courses, %d class courses, %d lessons\n",
+		len(allCourses), nclass, nlessons)
+
+	// Wrong element types
+	tref := db.Teachers[0].Id
+	var eerr *base.ElementError
+	if _, err := db.CoursesForGroup(tref); !errors.As(err, &eerr) ||
+		eerr.Expected != "Group" {
+		t.Errorf("Teacher as Group: %v", err)
+	} else {
+		fmt.Printf("  -- %v\n", err)
+	}
+	if _, err := db.CoursesForRoom("xxx"); !errors.As(err, &eerr) {
+		t.Errorf("Unknown room: %v", err)
+	} else {
+		fmt.Printf("  -- %v\n", err)
+	}
+	if _, err := db.LessonsForCourse(tref); err == nil {
+		t.Errorf("Teacher as Course: no error")
+	}
+	if _, err := db.GroupClass(db.Classes[0].Id); err == nil {
+		t.Errorf("Class as Group: no error")
+	}
+	if _, err := db.DivisionGroups(db.Classes[0].Id, "???"); err == nil {
+		t.Errorf("Unknown division: no error")
+	}
+	if _, err := base.Element[*base.Teacher](db, tref); err != nil {
+		t.Errorf("Element: %v", err)
+	}
+}
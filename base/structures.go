@@ -49,12 +49,14 @@ type Teacher struct {
 	MaxGapsPerWeek   int // default = -1
 	MaxAfternoons    int // default = -1
 	LunchBreak       bool
+	Color            string `json:",omitempty"` // "#RRGGBB"
 }
 
 type Subject struct {
-	Id   Ref
-	Name string
-	Tag  string
+	Id    Ref
+	Name  string
+	Tag   string
+	Color string `json:",omitempty"` // "#RRGGBB"
 }
 
 type Room struct {
@@ -106,6 +108,7 @@ type Class struct {
 	LunchBreak       bool
 	ForceFirstHour   bool
 	ClassGroup       Ref
+	Color            string `json:",omitempty"` // "#RRGGBB"
 }
 
 type Group struct {
@@ -134,8 +137,9 @@ func (c *Course) AddLesson(lref Ref) {
 }
 
 type SuperCourse struct {
-	Id      Ref
-	Subject Ref
+	Id        Ref
+	Subject   Ref
+	EpochPlan Ref `json:",omitempty"`
 	// These fields do not belong in the JSON object:
 	SubCourses []Ref `json:"-"`
 	Lessons    []Ref `json:"-"`
@@ -158,6 +162,22 @@ type SubCourse struct {
 	Room         Ref // Room, RoomGroup or RoomChoiceGroup Element
 }
 
+// An EpochPlan covers the "main-lesson" blocks, whose subjects and
+// teachers rotate over the weeks of the school year. The block itself
+// (a SuperCourse referring to the EpochPlan) is timetabled like any other
+// course, the Epochs only record which course is taught in which weeks.
+type EpochPlan struct {
+	Id     Ref
+	Name   string
+	Tag    string
+	Epochs []Epoch
+}
+
+type Epoch struct {
+	Course Ref   // SubCourse or Course
+	Weeks  []int // school weeks, starting at 0
+}
+
 type GeneralRoom interface {
 	IsReal() bool
 }
@@ -172,6 +192,10 @@ type Lesson struct {
 	Rooms      []Ref
 	Flags      []string `json:",omitempty"`
 	Background string
+	// Parts is only used for input from W365 XML, where a multi-hour
+	// lesson is made up of single-hour lessons. It holds their Ids, in
+	// order.
+	Parts []Ref `json:",omitempty"`
 }
 
 type LessonCourse interface {
@@ -185,6 +209,7 @@ type Constraint interface {
 
 type PrintOptions struct {
 	PrintTables []string
+	ColorScheme string `json:",omitempty"`
 	Typst       map[string]any
 }
 
@@ -204,8 +229,11 @@ type DbTopLevel struct {
 	SuperCourses     []*SuperCourse `json:",omitempty"`
 	SubCourses       []*SubCourse   `json:",omitempty"`
 	Lessons          []*Lesson      `json:",omitempty"`
+	EpochPlans       []*EpochPlan   `json:",omitempty"`
 	Constraints      []Constraint   `json:",omitempty"`
 
 	// These fields do not belong in the JSON object:
 	Elements map[Ref]any `json:"-"`
+	Log      *LogSet     `json:"-"`
+	index    dbIndex     // see query.go
 }
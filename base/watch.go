@@ -0,0 +1,144 @@
+package base
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+// Watch mode: the command-line programs can keep running after producing
+// their output, regenerating it whenever one of the input files changes.
+// The changes are found by polling the modification times and sizes of the
+// files, so no platform-specific notification APIs are needed.
+
+// The default polling interval
+const WATCH_INTERVAL = time.Second
+
+type fileStamp struct {
+	modtime time.Time
+	size    int64
+}
+
+func (fs1 fileStamp) equal(fs2 fileStamp) bool {
+	return fs1.modtime.Equal(fs2.modtime) && fs1.size == fs2.size
+}
+
+// fileStamps returns the stamps of the given files. Folders are walked, all
+// the files in them are included. Missing files are skipped – they may be
+// in the middle of being replaced and will be seen at the next poll.
+func fileStamps(paths []string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, p := range paths {
+		filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if fi, err := d.Info(); err == nil {
+				stamps[path] = fileStamp{fi.ModTime(), fi.Size()}
+			}
+			return nil
+		})
+	}
+	return stamps
+}
+
+// Watch calls run and then calls it again each time one of the given files
+// (or a file in one of the given folders) changes, polling at the given
+// interval. It doesn't return, the program is ended with Ctrl-C.
+//
+// Each run has its own log, written to logpath (replacing that of the
+// previous run), which is the DefaultLog during the run. Its Fatal methods
+// end only the run, not the program. Warnings and errors are also written
+// to stderr, followed by a line with the result of the run. If logpath is
+// empty, the whole log is written to stderr.
+func Watch(
+	logpath string,
+	paths []string,
+	interval time.Duration,
+	run func(),
+) {
+	logrun := func() {
+		watchRun(logpath, os.Stderr, run)
+	}
+	logrun()
+	watch(paths, interval, logrun, nil)
+}
+
+// watch calls run each time the files change, until stop is closed (nil:
+// never). After a change has been seen, the files must stay unchanged for
+// one more interval before run is called, so that partly written files are
+// not read.
+func watch(
+	paths []string,
+	interval time.Duration,
+	run func(),
+	stop <-chan struct{},
+) {
+	stamps := fileStamps(paths)
+	changed := false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		newstamps := fileStamps(paths)
+		if !maps.EqualFunc(stamps, newstamps, fileStamp.equal) {
+			stamps = newstamps
+			changed = true
+		} else if changed {
+			changed = false
+			run()
+		}
+	}
+}
+
+// watchRun calls run with a new (aborting) DefaultLog, written to logpath.
+// Problems are also written to diag, as is the result of the run. An error
+// is returned if the run was aborted – or failed with a panic, which in
+// watch mode should also not end the program.
+func watchRun(logpath string, diag io.Writer, run func()) (err error) {
+	if logpath == "" {
+		// Everything goes to diag
+		SetDefaultLog(NewLogSet(diag, true))
+	} else {
+		var w io.Writer = io.Discard
+		os.Remove(logpath)
+		file, ferr := os.OpenFile(logpath, os.O_CREATE|os.O_WRONLY, 0666)
+		if ferr != nil {
+			fmt.Fprintf(diag, "*ERROR* Log file not opened: %v\n", ferr)
+		} else {
+			defer file.Close()
+			w = file
+		}
+		SetDefaultLog(NewSplitLogSet(w, io.MultiWriter(w, diag), true))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if a, ok := r.(Abort); ok {
+				err = a
+			} else {
+				Bug.Printf("%v\n%s", r, debug.Stack())
+				err = fmt.Errorf("%v", r)
+			}
+		}
+		now := time.Now().Format(time.TimeOnly)
+		if err != nil && logpath != "" {
+			fmt.Fprintf(diag, "[%s] FAILED, log: %s\n", now, logpath)
+		} else if err != nil {
+			fmt.Fprintf(diag, "[%s] FAILED\n", now)
+		} else {
+			fmt.Fprintf(diag, "[%s] OK, waiting for changes ...\n", now)
+		}
+	}()
+	run()
+	return nil
+}
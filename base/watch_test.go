@@ -0,0 +1,102 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	fmt.Println("\n############## TestWatch")
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.json")
+	subdir := filepath.Join(tmp, "scripts")
+	if err := os.Mkdir(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, []byte("{}"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := make(chan bool, 10)
+	stop := make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		watch([]string{input, subdir}, 10*time.Millisecond,
+			func() { runs <- true }, stop)
+		done <- true
+	}()
+	expectRun := func(what string) {
+		select {
+		case <-runs:
+			fmt.Printf("  -- Run after: %s\n", what)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("No run after: %s", what)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if len(runs) != 0 {
+		t.Fatal("Run without changes")
+	}
+	os.WriteFile(input, []byte(`{"Days": []}`), 0666)
+	expectRun("changed file")
+	os.WriteFile(filepath.Join(subdir, "x.typ"), []byte("x"), 0666)
+	expectRun("new file in folder")
+	os.Remove(input)
+	expectRun("removed file")
+	close(stop)
+	<-done
+}
+
+func TestWatchRun(t *testing.T) {
+	fmt.Println("\n############## TestWatchRun")
+	logpath := filepath.Join(t.TempDir(), "test.log")
+	diag := &bytes.Buffer{}
+
+	// An error ends the run, not the program
+	err := watchRun(logpath, diag, func() {
+		Message.Println("Message 1")
+		Error.Fatalln("Error 1")
+		Message.Println("Not reached")
+	})
+	if _, ok := err.(Abort); !ok {
+		t.Errorf("Run not aborted: %v", err)
+	}
+	logdata, _ := os.ReadFile(logpath)
+	if !strings.Contains(string(logdata), "Message 1") ||
+		strings.Contains(string(logdata), "Not reached") {
+		t.Errorf("Log:\n%s", logdata)
+	}
+	if strings.Contains(diag.String(), "Message 1") ||
+		!strings.Contains(diag.String(), "Error 1") ||
+		!strings.Contains(diag.String(), "FAILED") {
+		t.Errorf("Diagnostics:\n%s", diag)
+	}
+
+	// Also a panic ends only the run
+	err = watchRun(logpath, diag, func() {
+		var m map[string]int
+		m["x"] = 1
+	})
+	if err == nil {
+		t.Errorf("Panic not reported")
+	}
+
+	// The log is replaced on each run
+	diag.Reset()
+	err = watchRun(logpath, diag, func() {
+		Warning.Println("Warning 2")
+	})
+	logdata, _ = os.ReadFile(logpath)
+	if err != nil || strings.Contains(string(logdata), "Message 1") ||
+		!strings.Contains(diag.String(), "Warning 2") ||
+		!strings.Contains(diag.String(), "OK") {
+		t.Errorf("Log:\n%s\nDiagnostics:\n%s", logdata, diag)
+	}
+	fmt.Print(diag)
+}
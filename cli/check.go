@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/ttbase"
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+// The "check" subcommand reads and prepares the data as for the FET file,
+// including the placements, which are checked. The problems are written
+// to the log (by default stderr), a summary to stdout. The exit code is 1
+// if there were errors.
+
+// A problemCounter counts the warnings and errors written to a log.
+// Each log entry is written with a single call of Write.
+type problemCounter struct {
+	warnings int
+	errors   int
+}
+
+func (pc *problemCounter) Write(p []byte) (int, error) {
+	if bytes.HasPrefix(p, []byte("*WARNING*")) {
+		pc.warnings++
+	} else {
+		// Errors and bugs
+		pc.errors++
+	}
+	return len(p), nil
+}
+
+func runCheck(c *cmdline) int {
+	fetresult := c.flags.String("fet", "",
+		"Check the placements from a FET result (folder or activities file)")
+	in := c.input(c.parse(1)[0])
+	counter := &problemCounter{}
+	c.openLog(c.logPath(""), counter)
+
+	db := c.load(in)
+	if *fetresult != "" {
+		stempath := c.outStem(in)
+		actfile, err := fet.FindActivitiesFile(
+			*fetresult, filepath.Base(stempath))
+		if err != nil {
+			base.Error.Fatalf("FET result: %v\n", err)
+		}
+		err = fet.ApplyPlacements(db, stempath+".map", actfile)
+		if err != nil {
+			base.Error.Fatalf("FET result: %v\n", err)
+		}
+	}
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+
+	nplaced, nfixed, nrejected := 0, 0, 0
+	for _, a := range ttinfo.Activities[1:] {
+		if a.Placement >= 0 {
+			nplaced++
+			if a.Fixed {
+				nfixed++
+			}
+		} else if a.Lesson.Day >= 0 {
+			nrejected++
+		}
+	}
+	fmt.Printf("Errors: %d, warnings: %d\n", counter.errors, counter.warnings)
+	fmt.Printf("Activities: %d, placed: %d (fixed: %d), invalid: %d,"+
+		" not placed: %d\n", len(ttinfo.Activities)-1, nplaced, nfixed,
+		nrejected, len(ttinfo.Activities)-1-nplaced-nrejected)
+	if counter.errors != 0 {
+		return 1
+	}
+	return 0
+}
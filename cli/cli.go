@@ -0,0 +1,353 @@
+// Package cli implements the "w365" command, which brings together the
+// programs for converting, checking and printing timetables as
+// subcommands:
+//
+//	w365 fet            – make a FET file (and the lesson map)
+//	w365 print          – make the printed timetables (Typst, etc.)
+//	w365 check          – check the data and the placements
+//	w365 import-result  – take the placements from a FET result
+//	w365 stats          – show some numbers about a timetable
+//	w365 diff           – compare two timetables
+//	w365 merge          – merge several timetables into one
+//	w365 serve          – run the local HTTP service
+//
+// The older programs (W365toFET, W365toTypst, W365XMLtoFET, W365serve)
+// are thin wrappers around these subcommands.
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/readcsv"
+	"W365toFET/readfet"
+	"W365toFET/readxml"
+	"W365toFET/w365tt"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type Ref = base.Ref
+
+type command struct {
+	name  string
+	args  string // the arguments (after the flags), for the usage message
+	short string // one-line description
+	run   func(c *cmdline) int
+}
+
+// The subcommands, in the order of the usage message. This is filled in
+// init() because the usage message refers to it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"fet", "<input>", "Make a FET file", runFet},
+		{"print", "<input>", "Make the printed timetables", runPrint},
+		{"check", "<input>", "Check the data and the placements", runCheck},
+		{"import-result", "<input>",
+			"Take the placements from a FET result", runImportResult},
+		{"stats", "<input>", "Show some numbers about the timetable",
+			runStats},
+		{"diff", "<input1> <input2>", "Compare two timetables", runDiff},
+		{"merge", "<input1> <input2> ...",
+			"Merge several timetables into one", runMerge},
+		{"serve", "", "Run the local HTTP service", runServe},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Main runs the subcommand named in args[0], returning the exit code.
+func Main(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return 2
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd := findCommand(args[1]); cmd != nil {
+				newCmdline(cmd).flags.Usage()
+				return 0
+			}
+		}
+		usage(os.Stdout)
+		return 0
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+		usage(os.Stderr)
+		return 2
+	}
+	return Run(cmd.name, args[1:])
+}
+
+// Run runs the named subcommand with the given arguments (flags first),
+// returning the exit code. It is used by the wrapper programs.
+func Run(name string, args []string) int {
+	cmd := findCommand(name)
+	if cmd == nil {
+		log.Fatalf("*BUG* Unknown command: %s\n", name)
+	}
+	c := newCmdline(cmd)
+	c.args = args
+	return cmd.run(c)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: w365 <command> [flags] <arguments>\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(w, "\nUse \"w365 help <command>\" for its flags.\n")
+}
+
+// Input formats, chosen by the flag "-format". With "auto" the format
+// is chosen by the file name (a folder is taken as CSV).
+const (
+	FORMAT_AUTO = "auto"
+	FORMAT_W365 = "w365" // W365 JSON
+	FORMAT_XML  = "xml"  // W365 XML
+	FORMAT_CSV  = "csv"  // folder with CSV files
+	FORMAT_FET  = "fet"  // FET file
+	FORMAT_DB   = "db"   // JSON of the base db ("*_db.json")
+)
+
+// A cmdline holds the flags and arguments of a subcommand. The shared
+// flags (input format, output folder, log and verbosity) are added to the
+// flag set of each subcommand, which then adds its own.
+type cmdline struct {
+	cmd      *command
+	flags    *flag.FlagSet
+	args     []string
+	format   string
+	schedule string
+	outdir   string
+	outname  string
+	logdest  string
+	quiet    bool
+	verbose  bool
+}
+
+func newCmdline(cmd *command) *cmdline {
+	c := &cmdline{cmd: cmd}
+	c.flags = flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	c.flags.Usage = func() {
+		w := c.flags.Output()
+		fmt.Fprintf(w, "Usage: w365 %s [flags] %s\n\n%s\n\nFlags:\n",
+			cmd.name, cmd.args, cmd.short)
+		c.flags.PrintDefaults()
+	}
+	c.flags.StringVar(&c.format, "format", FORMAT_AUTO,
+		"Input format: auto, w365, xml, csv, fet or db")
+	c.flags.StringVar(&c.schedule, "s", readxml.SCHEDULE_NAME,
+		"Schedule to use (W365 XML input)")
+	c.flags.StringVar(&c.outdir, "o", "",
+		"Output folder (default: that of the input)")
+	c.flags.StringVar(&c.outname, "name", "",
+		"Name of the output files (default: input name without \"_w365\")")
+	c.flags.StringVar(&c.logdest, "log", "",
+		"Log file (\"-\": stderr, default depends on the command)")
+	c.flags.BoolVar(&c.quiet, "q", false,
+		"Only log problems (warnings and errors)")
+	c.flags.BoolVar(&c.verbose, "v", false,
+		"Also write the log to stderr")
+	return c
+}
+
+// parse parses the command line (after the subcommand has added its
+// flags), which must have n arguments after the flags. These are returned.
+func (c *cmdline) parse(n int) []string {
+	c.flags.Parse(c.args)
+	args := c.flags.Args()
+	if len(args) != n {
+		if len(args) < n {
+			fmt.Fprintf(os.Stderr, "*ERROR* Missing arguments\n\n")
+		} else {
+			fmt.Fprintf(os.Stderr,
+				"*ERROR* Too many command-line arguments:\n  %+v\n\n", args)
+		}
+		c.flags.Usage()
+		os.Exit(2)
+	}
+	return args
+}
+
+// An input is a timetable source given on the command line.
+type input struct {
+	path   string // absolute path
+	format string
+	stem   string // the file (or folder) name without extension
+}
+
+// input resolves an input argument and its format.
+func (c *cmdline) input(arg string) *input {
+	abspath, err := filepath.Abs(arg)
+	if err != nil {
+		log.Fatalf("*ERROR* Couldn't resolve file path: %s\n", arg)
+	}
+	fi, err := os.Stat(abspath)
+	if err != nil {
+		log.Fatalf("*ERROR* Couldn't read input: %s\n", arg)
+	}
+	in := &input{path: abspath, format: c.format}
+	name := filepath.Base(abspath)
+	if fi.IsDir() {
+		in.stem = name
+		if in.format == FORMAT_AUTO {
+			in.format = FORMAT_CSV
+		}
+	} else {
+		in.stem = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if in.format == FORMAT_AUTO {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".xml":
+			in.format = FORMAT_XML
+		case ".fet":
+			in.format = FORMAT_FET
+		default:
+			if strings.HasSuffix(in.stem, "_db") {
+				in.format = FORMAT_DB
+			} else {
+				in.format = FORMAT_W365
+			}
+		}
+	}
+	switch in.format {
+	case FORMAT_W365, FORMAT_XML, FORMAT_CSV, FORMAT_FET, FORMAT_DB:
+	default:
+		log.Fatalf("*ERROR* Unknown input format: %s\n", in.format)
+	}
+	if (in.format == FORMAT_CSV) != fi.IsDir() {
+		log.Fatalf("*ERROR* Input format %s doesn't fit: %s\n",
+			in.format, arg)
+	}
+	return in
+}
+
+// outDir returns the folder for the output files. A folder given on the
+// command line is created if necessary.
+func (c *cmdline) outDir(in *input) string {
+	if c.outdir != "" {
+		dir, err := filepath.Abs(c.outdir)
+		if err == nil {
+			err = os.MkdirAll(dir, 0755)
+		}
+		if err != nil {
+			log.Fatalf("*ERROR* Output folder %s: %v\n", c.outdir, err)
+		}
+		return dir
+	}
+	return filepath.Dir(in.path)
+}
+
+// outStem returns the path of the output files, without extension.
+func (c *cmdline) outStem(in *input) string {
+	name := c.outname
+	if name == "" {
+		name = strings.TrimSuffix(in.stem, "_w365")
+		if in.format == FORMAT_DB {
+			name = strings.TrimSuffix(name, "_db")
+		}
+	}
+	return filepath.Join(c.outDir(in), name)
+}
+
+// logPath returns the path of the log file ("" for stderr): that given on
+// the command line ("-" for stderr), otherwise deflt.
+func (c *cmdline) logPath(deflt string) string {
+	switch c.logdest {
+	case "-":
+		return ""
+	case "":
+		return deflt
+	}
+	return c.logdest
+}
+
+// inputLog returns the default log file for the given input:
+// "<input name>.log" in the output folder.
+func (c *cmdline) inputLog(in *input) string {
+	return filepath.Join(c.outDir(in), in.stem+".log")
+}
+
+// openLog opens the log file ("" for stderr) as base.DefaultLog, taking
+// the verbosity flags into account. If problems is not nil, warnings and
+// errors are also written to it.
+func (c *cmdline) openLog(logpath string, problems io.Writer) {
+	var w io.Writer = os.Stderr
+	if logpath != "" {
+		file, err := os.Create(logpath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w = file
+		if c.verbose {
+			w = io.MultiWriter(file, os.Stderr)
+		}
+	}
+	pw := w
+	if problems != nil {
+		pw = io.MultiWriter(w, problems)
+	}
+	if c.quiet {
+		w = io.Discard
+	}
+	base.SetDefaultLog(base.NewSplitLogSet(w, pw, false))
+}
+
+// load reads the input into a new base db, which is prepared for use
+// (PrepareDb).
+func (c *cmdline) load(in *input) *base.DbTopLevel {
+	db := c.read(in)
+	db.PrepareDb()
+	return db
+}
+
+// read reads the input into a new base db, without preparing it.
+func (c *cmdline) read(in *input) *base.DbTopLevel {
+	switch in.format {
+	case FORMAT_XML:
+		return readXML(in.path, c.schedule)
+	case FORMAT_DB:
+		return base.LoadDb(in.path)
+	}
+	db := base.NewDb()
+	switch in.format {
+	case FORMAT_W365:
+		w365tt.LoadJSON(db, in.path)
+	case FORMAT_CSV:
+		readcsv.LoadCSV(db, in.path)
+	case FORMAT_FET:
+		readfet.LoadFet(db, in.path)
+	}
+	return db
+}
+
+// readXML reads the given schedule from a W365 XML file.
+func readXML(xmlpath string, schedule string) *base.DbTopLevel {
+	cdata := readxml.ConvertToDb(xmlpath)
+	slist := cdata.ScheduleNames()
+	if !slices.Contains(slist, schedule) {
+		base.Error.Fatalf("Unknown Schedule: %s\n  -- Available: %s\n",
+			schedule, strings.Join(slist, ", "))
+	}
+	base.Message.Printf("Using Schedule: %s\n", schedule)
+	cdata.ReadSchedule(schedule)
+	return cdata.Db()
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"W365toFET/ttbase"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const w365file = "../testdata/Versuch_D_Margin_hour_constraint_w365.json"
+
+func TestCommands(t *testing.T) {
+	fmt.Println("\n############## TestCommands")
+	tmp := t.TempDir()
+
+	// fet, with output folder and name
+	outdir := filepath.Join(tmp, "out")
+	if rc := Run("fet", []string{"-o", outdir, "-name", "test", "-q",
+		w365file}); rc != 0 {
+		t.Fatalf("fet: exit code %d", rc)
+	}
+	for _, f := range []string{"test.fet", "test.map",
+		"Versuch_D_Margin_hour_constraint_w365.log"} {
+		if _, err := os.Stat(filepath.Join(outdir, f)); err != nil {
+			t.Errorf("fet: %v", err)
+		}
+	}
+	logdata, _ := os.ReadFile(filepath.Join(outdir,
+		"Versuch_D_Margin_hour_constraint_w365.log"))
+	if strings.Contains(string(logdata), "*INFO*") {
+		t.Errorf("fet -q: messages in log")
+	}
+
+	// check and stats, with the log in a file
+	logpath := filepath.Join(tmp, "check.log")
+	if rc := Run("check", []string{"-log", logpath, w365file}); rc != 0 {
+		t.Errorf("check: exit code %d", rc)
+	}
+	if rc := Run("stats", []string{"-log", logpath, w365file}); rc != 0 {
+		t.Errorf("stats: exit code %d", rc)
+	}
+
+	// diff: the same data, then with changes
+	if rc := Run("diff", []string{"-log", logpath,
+		w365file, w365file}); rc != 0 {
+		t.Errorf("diff: differences in the same data")
+	}
+	data, err := os.ReadFile(w365file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w365 := map[string]any{}
+	if err := json.Unmarshal(data, &w365); err != nil {
+		t.Fatal(err)
+	}
+	teacher := w365["teachers"].([]any)[0].(map[string]any)
+	teacher["name"] = "Changed"
+	lesson := w365["lessons"].([]any)[0].(map[string]any)
+	lesson["day"] = 4.0
+	lesson["hour"] = 5.0
+	data, _ = json.Marshal(w365)
+	changed := filepath.Join(tmp, "changed_w365.json")
+	if err := os.WriteFile(changed, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	ttinfo1 := loadTtInfo(t, w365file)
+	ttinfo2 := loadTtInfo(t, changed)
+	diffs := diffDbs(ttinfo1, ttinfo2)
+	for _, d := range diffs {
+		fmt.Printf("  -- %s\n", d)
+	}
+	if len(diffs) != 2 ||
+		!strings.Contains(diffs[0], "Name: ") ||
+		!strings.Contains(diffs[1], "Day: ") {
+		t.Errorf("diff: %d differences", len(diffs))
+	}
+}
+
+func loadTtInfo(t *testing.T, path string) *ttbase.TtInfo {
+	c := newCmdline(findCommand("diff"))
+	c.args = []string{"-q", "-log", filepath.Join(t.TempDir(), "x.log")}
+	c.flags.Parse(c.args)
+	c.openLog(c.logPath(""), nil)
+	return ttbase.MakeTtInfo(c.load(c.input(path)))
+}
+
+func TestImportResult(t *testing.T) {
+	fmt.Println("\n############## TestImportResult")
+	tmp := t.TempDir()
+	// A matching set of db, map and FET result, which is not written by
+	// other tests (the files in testdata/readxml are regenerated).
+	for _, f := range []string{"Demo1_db.json", "Demo1.map",
+		"Demo1_activities.xml"} {
+		data, err := os.ReadFile(filepath.Join("../testdata/cli", f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmp, f), data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rc := Run("import-result", []string{"-fet", tmp,
+		filepath.Join(tmp, "Demo1_db.json")}); rc != 0 {
+		t.Fatalf("import-result: exit code %d", rc)
+	}
+	result := filepath.Join(tmp, "Demo1_result_w365.json")
+	ttinfo := loadTtInfo(t, result)
+	placed := 0
+	for _, l := range ttinfo.Db.Lessons {
+		if l.Day >= 0 {
+			placed++
+		}
+	}
+	fmt.Printf("  -- %d of %d lessons placed\n", placed,
+		len(ttinfo.Db.Lessons))
+	if placed == 0 {
+		t.Errorf("import-result: no lessons placed")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	fmt.Println("\n############## TestMerge")
+	tmp := t.TempDir()
+	if rc := Run("merge", []string{"-o", tmp, "-name", "all", "-fet", "-q",
+		w365file, "../testdata/csv"}); rc != 0 {
+		t.Fatalf("merge: exit code %d", rc)
+	}
+	for _, f := range []string{"all_w365.json", "all.fet", "all.map",
+		"all.log"} {
+		if _, err := os.Stat(filepath.Join(tmp, f)); err != nil {
+			t.Errorf("merge: %v", err)
+		}
+	}
+	logdata, _ := os.ReadFile(filepath.Join(tmp, "all.log"))
+	fmt.Printf("  -- %d conflicts\n",
+		strings.Count(string(logdata), "*WARNING*"))
+}
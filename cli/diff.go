@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The "diff" subcommand compares two timetables, e.g. two versions of the
+// W365 data, or the data before and after "import-result". Elements with
+// tags (teachers, rooms, groups, ...) are matched by their tags, others
+// (courses, lessons) by their Ids – so these should come from the same
+// source. References to elements with tags are also shown as tags. The
+// differences are written to stdout, the exit code is 1 if there are any.
+
+func runDiff(c *cmdline) int {
+	args := c.parse(2)
+	in1 := c.input(args[0])
+	in2 := c.input(args[1])
+	c.openLog(c.logPath(""), nil)
+	db1 := c.load(in1)
+	db2 := c.load(in2)
+	diffs := diffDbs(ttbase.MakeTtInfo(db1), ttbase.MakeTtInfo(db2))
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if len(diffs) != 0 {
+		return 1
+	}
+	return 0
+}
+
+// diffDbs compares the two dbs on the basis of their JSON forms, going
+// through the lists of elements ("Teachers", "Lessons", ...). The
+// timetable data is used for the descriptions of the lessons.
+func diffDbs(ttinfo1, ttinfo2 *ttbase.TtInfo) []string {
+	j1 := tagRefs(ttinfo1, jsonObject(ttinfo1.Db)).(map[string]any)
+	j2 := tagRefs(ttinfo2, jsonObject(ttinfo2.Db)).(map[string]any)
+	diffs := []string{}
+	// The fields of DbTopLevel, in order
+	dbtype := reflect.TypeOf(base.DbTopLevel{})
+	for i := range dbtype.NumField() {
+		key := dbtype.Field(i).Name
+		v1, v2 := j1[key], j2[key]
+		l1, ok1 := v1.([]any)
+		l2, ok2 := v2.([]any)
+		if !ok1 && !ok2 && v1 != nil && v2 != nil {
+			// Info, PrintOptions
+			if f := diffFields(v1, v2); len(f) != 0 {
+				diffs = append(diffs, fmt.Sprintf("~ %s: %s",
+					key, strings.Join(f, ", ")))
+			}
+			continue
+		}
+		if key == "Constraints" {
+			// Constraints have no Ids
+			n1, n2 := len(l1), len(l2)
+			if !reflect.DeepEqual(l1, l2) {
+				diffs = append(diffs, fmt.Sprintf(
+					"~ Constraints: %d -> %d (changed)", n1, n2))
+			}
+			continue
+		}
+		diffs = append(diffs, diffLists(key, l1, l2, ttinfo1, ttinfo2)...)
+	}
+	return diffs
+}
+
+func jsonObject(v any) map[string]any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		base.Bug.Fatalf("JSON: %v\n", err)
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		base.Bug.Fatalf("JSON: %v\n", err)
+	}
+	return m
+}
+
+// tagRefs replaces the references (also the Ids) of elements with tags in
+// the JSON form of a db by the tags, so that elements which are generated
+// when reading the input (with new Ids) can be compared.
+func tagRefs(ttinfo *ttbase.TtInfo, v any) any {
+	tags := map[string]string{}
+	for ref, tag := range ttinfo.Ref2Tag {
+		tags[string(ref)] = tag
+	}
+	db := ttinfo.Db
+	for _, d := range db.Days {
+		tags[string(d.Id)] = d.Tag
+	}
+	for _, h := range db.Hours {
+		tags[string(h.Id)] = h.Tag
+	}
+	for _, r := range db.RoomGroups {
+		tags[string(r.Id)] = r.Tag
+	}
+	for _, r := range db.RoomChoiceGroups {
+		tags[string(r.Id)] = r.Tag
+	}
+	var replace func(v any) any
+	replace = func(v any) any {
+		switch vv := v.(type) {
+		case string:
+			if tag, ok := tags[vv]; ok && tag != "" {
+				return tag
+			}
+		case []any:
+			for i, x := range vv {
+				vv[i] = replace(x)
+			}
+		case map[string]any:
+			for k, x := range vv {
+				vv[k] = replace(x)
+			}
+		}
+		return v
+	}
+	return replace(v)
+}
+
+// diffLists compares two lists of elements, matched by their Ids.
+func diffLists(
+	key string,
+	l1 []any,
+	l2 []any,
+	ttinfo1 *ttbase.TtInfo,
+	ttinfo2 *ttbase.TtInfo,
+) []string {
+	diffs := []string{}
+	byId := func(l []any) (map[string]map[string]any, []string) {
+		m := map[string]map[string]any{}
+		ids := []string{}
+		for _, e := range l {
+			if em, ok := e.(map[string]any); ok {
+				id, _ := em["Id"].(string)
+				m[id] = em
+				ids = append(ids, id)
+			}
+		}
+		return m, ids
+	}
+	m1, ids1 := byId(l1)
+	m2, ids2 := byId(l2)
+	for _, id := range ids1 {
+		e1 := m1[id]
+		e2, ok := m2[id]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("- %s %s",
+				key, elementName(ttinfo1, e1)))
+			continue
+		}
+		if f := diffFields(e1, e2); len(f) != 0 {
+			diffs = append(diffs, fmt.Sprintf("~ %s %s: %s",
+				key, elementName(ttinfo2, e2), strings.Join(f, ", ")))
+		}
+	}
+	for _, id := range ids2 {
+		if _, ok := m1[id]; !ok {
+			diffs = append(diffs, fmt.Sprintf("+ %s %s",
+				key, elementName(ttinfo2, m2[id])))
+		}
+	}
+	return diffs
+}
+
+// elementName returns a description of an element for the output: its tag
+// (Id), for lessons also their course.
+func elementName(ttinfo *ttbase.TtInfo, e map[string]any) string {
+	id, _ := e["Id"].(string)
+	if course, ok := e["Course"].(string); ok {
+		if cinfo, ok := ttinfo.CourseInfo[Ref(course)]; ok {
+			return fmt.Sprintf("%s (%s)", ttinfo.View(cinfo), id)
+		}
+	}
+	return id
+}
+
+// diffFields compares two JSON objects, returning a description of each
+// changed field.
+func diffFields(v1, v2 any) []string {
+	m1, _ := v1.(map[string]any)
+	m2, _ := v2.(map[string]any)
+	keys := []string{}
+	for k := range m1 {
+		keys = append(keys, k)
+	}
+	for k := range m2 {
+		if _, ok := m1[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	diffs := []string{}
+	for _, k := range keys {
+		if !reflect.DeepEqual(m1[k], m2[k]) {
+			diffs = append(diffs, fmt.Sprintf("%s: %s -> %s",
+				k, jsonString(m1[k]), jsonString(m2[k])))
+		}
+	}
+	return diffs
+}
+
+func jsonString(v any) string {
+	if v == nil {
+		return "-"
+	}
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/readxml"
+	"W365toFET/ttbase"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The "fet" subcommand (also W365toFET and W365XMLtoFET): the FET file
+// "<name>.fet" and the map of the FET activities to the lesson Ids,
+// "<name>.map", in the output folder.
+
+func addWatchFlags(c *cmdline) (*bool, *time.Duration) {
+	return c.flags.Bool("watch", false,
+			"Keep running, regenerate the output when the input changes"),
+		c.flags.Duration("interval", base.WATCH_INTERVAL,
+			"Polling interval for -watch")
+}
+
+func runFet(c *cmdline) int {
+	listonly := c.flags.Bool("l", false,
+		"List the available schedules (W365 XML input)")
+	savedb := c.flags.Bool("db", false,
+		"Also save the base db as JSON (\"<input name>_db.json\")")
+	doprint := c.flags.Bool("p", false,
+		"Also make the printed timetables (as \"print\")")
+	tf := addTypstFlags(c)
+	watch, interval := addWatchFlags(c)
+	in := c.input(c.parse(1)[0])
+	logpath := c.logPath(c.inputLog(in))
+
+	if *listonly {
+		if in.format != FORMAT_XML {
+			fmt.Fprintln(os.Stderr, "*ERROR* -l needs W365 XML input")
+			return 2
+		}
+		c.openLog(logpath, nil)
+		for _, sname := range readxml.ConvertToDb(in.path).ScheduleNames() {
+			fmt.Println(sname)
+		}
+		return 0
+	}
+	if !*doprint {
+		tf = nil
+	}
+	run := func() {
+		makeFet(c, in, *savedb, tf)
+	}
+
+	if *watch {
+		paths := []string{in.path}
+		if tf != nil && *tf.templates != "" {
+			paths = append(paths, *tf.templates)
+		}
+		base.Watch(logpath, paths, *interval, run)
+	}
+	c.openLog(logpath, nil)
+	run()
+	return 0
+}
+
+// makeFet reads the input and writes the FET file and the Id-map file.
+// If tf is not nil, also the Typst output is made.
+func makeFet(c *cmdline, in *input, savedb bool, tf *typstFlags) {
+	db := c.read(in)
+	if savedb {
+		// Write the base db as JSON
+		fjson := filepath.Join(c.outDir(in), in.stem+"_db.json")
+		if !db.SaveDb(fjson) {
+			base.Error.Fatalf("Couldn't write JSON output to: %s\n", fjson)
+		}
+		base.Message.Printf("Db written to: %s\n", fjson)
+	}
+	db.PrepareDb()
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+
+	// ********** Build the fet file **********
+
+	xmlitem, lessonIdMap := fet.MakeFetFile(ttinfo)
+	stempath := c.outStem(in)
+
+	// Write FET file
+	fetfile := stempath + ".fet"
+	if err := os.WriteFile(fetfile, []byte(xmlitem), 0666); err != nil {
+		base.Error.Fatalf("Couldn't write fet output to: %s\n", fetfile)
+	}
+	base.Message.Printf("FET file written to: %s\n", fetfile)
+
+	// Write Id-map file.
+	mapfile := stempath + ".map"
+	if err := os.WriteFile(mapfile, []byte(lessonIdMap), 0666); err != nil {
+		base.Error.Fatalf("Couldn't write Id-map to: %s\n", mapfile)
+	}
+	base.Message.Printf("Id-map written to: %s\n", mapfile)
+
+	if tf != nil {
+		tf.makeTypst(ttinfo, filepath.Join(c.outDir(in), "typst_files"),
+			filepath.Base(stempath))
+	}
+
+	base.Message.Println("OK")
+}
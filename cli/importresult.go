@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/ttbase"
+	"W365toFET/w365tt"
+	"path/filepath"
+)
+
+// The "import-result" subcommand takes the placements (times and rooms)
+// from a FET result and writes the data with these placements as W365
+// JSON ("<name>_result_w365.json" in the output folder). Invalid
+// placements are dropped (with a warning).
+
+func runImportResult(c *cmdline) int {
+	fetresult := c.flags.String("fet", "",
+		"The FET result (folder or activities file), required")
+	mapfile := c.flags.String("map", "",
+		"The Id-map file (default: \"<name>.map\" in the output folder)")
+	in := c.input(c.parse(1)[0])
+	if *fetresult == "" {
+		c.flags.Usage()
+		return 2
+	}
+	c.openLog(c.logPath(c.inputLog(in)), nil)
+
+	stempath := c.outStem(in)
+	if *mapfile == "" {
+		*mapfile = stempath + ".map"
+	}
+	db := c.load(in)
+	actfile, err := fet.FindActivitiesFile(*fetresult, filepath.Base(stempath))
+	if err != nil {
+		base.Error.Fatalf("FET result: %v\n", err)
+	}
+	if err := fet.ApplyPlacements(db, *mapfile, actfile); err != nil {
+		base.Error.Fatalf("FET result: %v\n", err)
+	}
+	// Check the placements, invalid ones are dropped
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+	if n := fet.DropInvalidPlacements(ttinfo); n != 0 {
+		base.Warning.Printf("Invalid placements dropped: %d\n", n)
+	}
+
+	jsonpath := stempath + "_result_w365.json"
+	if !w365tt.SaveJSON(db, jsonpath) {
+		base.Error.Fatalf("Couldn't write JSON output to: %s\n", jsonpath)
+	}
+	base.Message.Println("OK")
+	return 0
+}
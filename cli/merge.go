@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/w365tt"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The "merge" subcommand merges two or more timetables (e.g. those of the
+// upper and lower school, which share teachers and rooms) into one, see
+// base.Merge. The result is written as W365 JSON ("<name>_w365.json",
+// default name "merged", in the output folder, default that of the first
+// input). The conflicts are reported as warnings. With -fet also the FET
+// file for the merged data is made.
+
+func runMerge(c *cmdline) int {
+	dofet := c.flags.Bool("fet", false,
+		"Also make the FET file (as \"fet\")")
+	c.flags.Parse(c.args)
+	args := c.flags.Args()
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "*ERROR* At least two inputs are needed\n\n")
+		c.flags.Usage()
+		return 2
+	}
+	inputs := []*input{}
+	for _, arg := range args {
+		inputs = append(inputs, c.input(arg))
+	}
+	if c.outname == "" {
+		c.outname = "merged"
+	}
+	stempath := c.outStem(inputs[0])
+	c.openLog(c.logPath(stempath+".log"), nil)
+
+	parts := []*base.DbTopLevel{}
+	for _, in := range inputs {
+		parts = append(parts, c.read(in))
+	}
+	db, conflicts, err := base.Merge(parts...)
+	if err != nil {
+		base.Error.Fatalln(err)
+	}
+	for _, cf := range conflicts {
+		base.Warning.Println(cf)
+	}
+	jsonpath := stempath + "_w365.json"
+	if !w365tt.SaveJSON(db, jsonpath) {
+		base.Error.Fatalf("Couldn't write JSON output to: %s\n", jsonpath)
+	}
+	if *dofet {
+		// The FET file is made from the JSON file, so that the Id-map
+		// fits it.
+		makeFet(c, &input{
+			path:   jsonpath,
+			format: FORMAT_W365,
+			stem:   filepath.Base(stempath) + "_w365",
+		}, false, nil)
+	} else {
+		base.Message.Println("OK")
+	}
+	return 0
+}
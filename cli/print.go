@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/ttbase"
+	"W365toFET/ttprint"
+	"os"
+	"path/filepath"
+)
+
+// The "print" subcommand (also W365toTypst): the Typst data and PDF
+// files, and optionally HTML, SVG, XLSX and iCalendar files. The output
+// goes to the folder "typst_files" in the output folder.
+
+// The flags for running Typst, also used by "fet -p"
+type typstFlags struct {
+	typst     *string
+	nopdf     *bool
+	workers   *int
+	force     *bool
+	templates *string
+}
+
+func addTypstFlags(c *cmdline) *typstFlags {
+	return &typstFlags{
+		typst: c.flags.String("typst", "typst", "Typst executable"),
+		nopdf: c.flags.Bool("np", false, "Don't run Typst"),
+		workers: c.flags.Int("j", 0,
+			"Number of parallel Typst runs (default: number of CPUs)"),
+		force: c.flags.Bool("force", false, "Rebuild all PDF files"),
+		templates: c.flags.String("templates", "",
+			"Folder with custom Typst scripts (scripts/, _fonts/)"),
+	}
+}
+
+// makeTypst generates the Typst data and – unless -np was given – the
+// PDF files. The data folder is created if necessary.
+func (tf *typstFlags) makeTypst(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string,
+) {
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		base.Error.Fatal(err)
+	}
+	typst_files := ttprint.GenTypstData(ttinfo, datadir, stemfile)
+	if !*tf.nopdf {
+		ttprint.MakePdfs(ttinfo.Db.Log, typst_files, datadir, *tf.templates,
+			*tf.typst, *tf.workers, *tf.force)
+	}
+}
+
+func runPrint(c *cmdline) int {
+	nocheck := c.flags.Bool("x", false, "Don't check for invalid placements")
+	fetresult := c.flags.String("fet", "",
+		"Take the placements from a FET result (folder or activities file)")
+	tf := addTypstFlags(c)
+	html := c.flags.Bool("html", false, "Also make HTML pages")
+	svg := c.flags.Bool("svg", false, "Also make SVG files (without Typst)")
+	xlsx := c.flags.Bool("xlsx", false, "Also make XLSX overview tables")
+	icsterm := c.flags.String("ics", "",
+		"Also make iCalendar files for the term (start:end, YYYY-MM-DD)")
+	holidays := c.flags.String("holidays", "",
+		"Holidays for -ics (comma-separated date ranges)")
+	watch, interval := addWatchFlags(c)
+	in := c.input(c.parse(1)[0])
+
+	stempath := c.outStem(in)
+	stemfile := filepath.Base(stempath)
+	datadir := filepath.Join(c.outDir(in), "typst_files")
+
+	run := func() {
+		db := c.load(in)
+
+		if *fetresult != "" {
+			// Replace the placements by those from FET. The ".map" file is
+			// written by "fet" next to the FET file.
+			actfile, err := fet.FindActivitiesFile(*fetresult, stemfile)
+			if err != nil {
+				base.Error.Fatalf("FET result: %v\n", err)
+			}
+			err = fet.ApplyPlacements(db, stempath+".map", actfile)
+			if err != nil {
+				base.Error.Fatalf("FET result: %v\n", err)
+			}
+		}
+
+		ttinfo := ttbase.MakeTtInfo(db)
+
+		// The placements from FET are always checked
+		if !*nocheck || *fetresult != "" {
+			// Among other things (which are not relevant for the printing),
+			// this checks placements
+			ttinfo.PrepareCoreData()
+		}
+
+		tf.makeTypst(ttinfo, datadir, stemfile)
+
+		if *svg {
+			ttprint.GenSvg(ttinfo, datadir, stemfile)
+		}
+
+		if *html {
+			ttprint.GenHtml(ttinfo, filepath.Join(datadir, "_html", stemfile))
+		}
+
+		if *xlsx {
+			ttprint.GenXlsx(ttinfo,
+				filepath.Join(datadir, "_xlsx", stemfile+"_overview.xlsx"))
+		}
+
+		if *icsterm != "" {
+			term, err := ttprint.ParseTerm(*icsterm, *holidays)
+			if err != nil {
+				base.Error.Fatalf("Invalid iCalendar dates: %v\n", err)
+			}
+			ttprint.GenICalendars(ttinfo, datadir, stemfile, term)
+		}
+
+		base.Message.Println("OK")
+	}
+
+	if *watch {
+		// Also the FET result and the Typst scripts are watched
+		paths := []string{in.path}
+		if *fetresult != "" {
+			paths = append(paths, *fetresult, stempath+".map")
+		}
+		if *tf.templates != "" {
+			paths = append(paths, *tf.templates)
+		}
+		base.Watch(c.logPath(c.inputLog(in)), paths, *interval, run)
+	}
+	c.openLog(c.logPath(c.inputLog(in)), nil)
+	run()
+	return 0
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"W365toFET/base"
+	"W365toFET/server"
+	"net/http"
+)
+
+// The "serve" subcommand (also W365serve) runs the local HTTP service, see
+// package server. The log (by default stderr) only gets the messages of
+// the service itself, each request has its own log.
+
+func runServe(c *cmdline) int {
+	addr := c.flags.String("addr", "localhost:8365", "Address to listen on")
+	typstexec := c.flags.String("typst", "typst", "Typst executable")
+	templates := c.flags.String("templates", "",
+		"Folder with custom Typst scripts (scripts/, _fonts/)")
+	workers := c.flags.Int("j", 0,
+		"Number of parallel Typst runs per request (default: number of CPUs)")
+	c.parse(0)
+	c.openLog(c.logPath(""), nil)
+
+	handler := server.NewHandler(server.Config{
+		Typst:     *typstexec,
+		Templates: *templates,
+		Workers:   *workers,
+	})
+	base.Message.Printf("Listening on %s\n", *addr)
+	base.Error.Fatal(http.ListenAndServe(*addr, handler))
+	return 1
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"W365toFET/ttbase"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+)
+
+// The "stats" subcommand writes some numbers about a timetable to stdout:
+// the number of elements of each kind, the lessons and their placements,
+// and the lesson hours of each teacher and class.
+
+func runStats(c *cmdline) int {
+	in := c.input(c.parse(1)[0])
+	c.openLog(c.logPath(""), nil)
+	db := c.load(in)
+	ttinfo := ttbase.MakeTtInfo(db)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	line := func(format string, a ...any) {
+		fmt.Fprintf(w, format+"\n", a...)
+	}
+	if db.Info.Institution != "" {
+		line("Institution:\t%s", db.Info.Institution)
+	}
+	line("Days:\t%d", len(db.Days))
+	line("Hours per day:\t%d", len(db.Hours))
+	line("Teachers:\t%d", len(db.Teachers))
+	line("Subjects:\t%d", len(db.Subjects))
+	line("Rooms:\t%d\t(room groups: %d, room choices: %d)",
+		len(db.Rooms), len(db.RoomGroups), len(db.RoomChoiceGroups))
+	line("Classes:\t%d\t(groups: %d)", len(db.Classes), len(db.Groups))
+	line("Courses:\t%d\t(block courses: %d, with parts: %d)",
+		len(db.Courses), len(db.SuperCourses), len(db.SubCourses))
+	nplaced, nfixed, nhours := 0, 0, 0
+	for _, l := range db.Lessons {
+		nhours += l.Duration
+		if l.Day >= 0 {
+			nplaced++
+			if l.Fixed {
+				nfixed++
+			}
+		}
+	}
+	line("Lessons:\t%d\t(hours: %d, placed: %d, fixed: %d)",
+		len(db.Lessons), nhours, nplaced, nfixed)
+	line("Constraints:\t%d", len(db.Constraints))
+	w.Flush()
+
+	// Lesson hours of the teachers and classes
+	thours := map[Ref]int{}
+	chours := map[Ref]int{}
+	for _, cinfo := range ttinfo.CourseInfo {
+		n := 0
+		for _, ai := range cinfo.Lessons {
+			n += ttinfo.Activities[ai].Duration
+		}
+		for _, t := range cinfo.Teachers {
+			thours[t] += n
+		}
+		classes := []Ref{}
+		for _, g := range cinfo.Groups {
+			if cl, err := db.GroupClass(g); err == nil &&
+				!slices.Contains(classes, cl.Id) {
+				classes = append(classes, cl.Id)
+			}
+		}
+		for _, cl := range classes {
+			chours[cl] += n
+		}
+	}
+	fmt.Println("\nTeacher hours:")
+	for _, t := range db.Teachers {
+		line("  %s\t%d", t.Tag, thours[t.Id])
+	}
+	w.Flush()
+	fmt.Println("\nClass hours:")
+	for _, cl := range db.Classes {
+		if cl.Tag != "" {
+			line("  %s\t%d", cl.Tag, chours[cl.Id])
+		}
+	}
+	w.Flush()
+	return 0
+}
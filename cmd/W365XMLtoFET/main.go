@@ -0,0 +1,13 @@
+package main
+
+import (
+	"W365toFET/cli"
+	"os"
+)
+
+// W365XMLtoFET is the same as "w365 fet -format=xml -db", which also saves
+// the base db as JSON.
+func main() {
+	os.Exit(cli.Run("fet",
+		append([]string{"-format=xml", "-db"}, os.Args[1:]...)))
+}
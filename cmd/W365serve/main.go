@@ -0,0 +1,11 @@
+package main
+
+import (
+	"W365toFET/cli"
+	"os"
+)
+
+// W365serve is the same as "w365 serve".
+func main() {
+	os.Exit(cli.Run("serve", os.Args[1:]))
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"W365toFET/cli"
+	"os"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
@@ -1,7 +1,6 @@
 package fet
 
 import (
-	"W365toFET/base"
 	"W365toFET/ttbase"
 	"encoding/xml"
 	"slices"
@@ -209,7 +208,7 @@ func addPlacementConstraints(fetinfo *fetInfo) {
 			}
 
 			if len(rlist) != 1 {
-				base.Error.Printf(
+				fetinfo.ttinfo.Db.Log.Error.Printf(
 					"Course room is not virtual, but Lesson has"+
 						" more than one Room:\n  %s", l.Id)
 				continue
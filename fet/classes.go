@@ -1,7 +1,6 @@
 package fet
 
 import (
-	"W365toFET/base"
 	"encoding/xml"
 	"strconv"
 	"strings"
@@ -67,7 +66,7 @@ func getClasses(fetinfo *fetInfo) {
 		}
 		divs, ok := ttinfo.ClassDivisions[cl.Id]
 		if !ok {
-			base.Bug.Fatalf(
+			fetinfo.ttinfo.Db.Log.Bug.Fatalf(
 				"Class %s has no entry in ttinfo.ClassDivisions\n",
 				cname)
 		}
@@ -124,7 +123,7 @@ func getClasses(fetinfo *fetInfo) {
 		for _, na := range cl.NotAvailable {
 			if na.Day != day {
 				if na.Day < day {
-					base.Error.Fatalf(
+					fetinfo.ttinfo.Db.Log.Error.Fatalf(
 						"Class %s has unordered NotAvailable times.\n",
 						cname)
 				}
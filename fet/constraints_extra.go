@@ -123,8 +123,8 @@ func getExtraConstraints(fetinfo *fetInfo) {
 		cn := c.(*base.DoubleLessonNotOverBreaks)
 
 		if len(doubleBlocked) != 0 {
-			base.Error.Fatalln("Constraint DoubleLessonNotOverBreaks" +
-				" specified more than once")
+			fetinfo.ttinfo.Db.Log.Error.Fatalln(
+				"Constraint DoubleLessonNotOverBreaks specified more than once")
 		}
 
 		timeslots := []preferredStart{}
@@ -180,7 +180,7 @@ func getExtraConstraints(fetinfo *fetInfo) {
 		for _, k := range cn.Courses {
 			cinfo, ok := ttinfo.CourseInfo[k]
 			if !ok {
-				base.Bug.Fatalf("Invalid course: %s\n", k)
+				fetinfo.ttinfo.Db.Log.Bug.Fatalf("Invalid course: %s\n", k)
 			}
 			for _, aid := range cinfo.Lessons {
 				tclist.ConstraintActivityPreferredTimeSlots = append(
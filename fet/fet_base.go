@@ -5,6 +5,7 @@ import (
 	"W365toFET/base"
 	"W365toFET/ttbase"
 	"encoding/xml"
+	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -24,14 +25,14 @@ const fet_version = "6.28.2"
 
 // Function makeXML produces a chunk of pretty-printed XML output from
 // the input data.
-func makeXML(data interface{}, indent_level int) string {
+func makeXML(data interface{}, indent_level int) (string, error) {
 	const indent = "  "
 	prefix := strings.Repeat(indent, indent_level)
 	xmlData, err := xml.MarshalIndent(data, prefix, indent)
 	if err != nil {
-		base.Error.Fatalf("%v\n", err)
+		return "", err
 	}
-	return string(xmlData)
+	return string(xmlData), nil
 }
 
 type fet struct {
@@ -65,6 +66,23 @@ func weight2fet(w int) string {
 	return strconv.FormatFloat(wfet, 'f', 3, 64)
 }
 
+// Fet2Weight is the inverse of weight2fet, returning the smallest weight
+// which produces at least the given FET weight (percentage). An invalid
+// weight produces an error, with the maximum weight.
+func Fet2Weight(wfet string) (int, error) {
+	wf, err := strconv.ParseFloat(wfet, 64)
+	if err != nil {
+		return base.MAXWEIGHT, fmt.Errorf("invalid FET weight: %s", wfet)
+	}
+	for w := 0; w < base.MAXWEIGHT; w++ {
+		x, _ := strconv.ParseFloat(weight2fet(w), 64)
+		if x >= wf {
+			return w, nil
+		}
+	}
+	return base.MAXWEIGHT, nil
+}
+
 type idMap struct {
 	activityId int
 	baseId     string
@@ -200,7 +218,11 @@ func MakeFetFile(ttinfo *ttbase.TtInfo) (string, string) {
 	}
 	lidmap := strings.Join(idmlines, "\n")
 
-	return xml.Header + makeXML(fetinfo.fetdata, 0), lidmap
+	xmlitem, err := makeXML(fetinfo.fetdata, 0)
+	if err != nil {
+		dbdata.Log.Bug.Fatalf("%v\n", err)
+	}
+	return xml.Header + xmlitem, lidmap
 }
 
 /*
@@ -3,11 +3,24 @@ package fet
 import (
 	"W365toFET/base"
 	"W365toFET/ttbase"
+	"bufio"
+	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
+// Reading the result of a FET run. FET writes the placements to a file
+// "<name>_activities.xml" in its result folder (normally something like
+// "timetables/<name>/"). The FET activity numbers are mapped to the lesson
+// Ids by the ".map" file written together with the FET file.
+
 type fetPlacement struct {
 	// Note that this is intended for Days and Hours which are 0-based indexes.
 	// It will not work with strings ("normal" FET references)
@@ -34,39 +47,68 @@ func ReadPlacements(
 	ttinfo *ttbase.TtInfo,
 	xmlpath string,
 ) []ActivityPlacement {
+	placements, err := readPlacements(ttinfo.Db, xmlpath)
+	if err != nil {
+		ttinfo.Db.Log.Error.Fatal(err)
+	}
+	return placements
+}
+
+func readPlacements(
+	db *base.DbTopLevel,
+	xmlpath string,
+) ([]ActivityPlacement, error) {
 	// Open the  XML activities file
 	xmlFile, err := os.Open(xmlpath)
 	if err != nil {
-		base.Error.Fatal(err)
+		return nil, err
 	}
 	// Remember to close the file at the end of the function
 	defer xmlFile.Close()
 	// read the opened XML file as a byte array.
-	base.Message.Printf("Reading: %s\n", xmlpath)
+	db.Log.Message.Printf("Reading: %s\n", xmlpath)
 	byteValue, _ := io.ReadAll(xmlFile)
+	return parsePlacements(db, byteValue, xmlpath)
+}
+
+// parsePlacements reads the placements from the contents of a FET
+// activities file. The name is used in error messages.
+func parsePlacements(
+	db *base.DbTopLevel,
+	data []byte,
+	xmlpath string,
+) ([]ActivityPlacement, error) {
 	v := fetActivities{}
-	err = xml.Unmarshal(byteValue, &v)
+	err := xml.Unmarshal(data, &v)
 	if err != nil {
-		base.Error.Fatalf("XML error in %s:\n %v\n", xmlpath, err)
+		return nil, fmt.Errorf("XML error in %s:\n %w", xmlpath, err)
 	}
 
 	// Need mapping for the Rooms
 	rmap := map[string]Ref{}
-	for _, r := range ttinfo.Db.Rooms {
+	for _, r := range db.Rooms {
 		rmap[r.Tag] = r.Id
 	}
+	room := func(tag string) (Ref, error) {
+		if r, ok := rmap[tag]; ok {
+			return r, nil
+		}
+		return "", fmt.Errorf("unknown room in %s: %s", xmlpath, tag)
+	}
 
 	placements := []ActivityPlacement{}
 	for _, p := range v.Placements {
+		rtags := p.Real_Room
+		if len(rtags) == 0 && p.Room != "" {
+			rtags = []string{p.Room}
+		}
 		rlist := []Ref{}
-		if len(p.Real_Room) == 0 {
-			if p.Room != "" {
-				rlist = append(rlist, rmap[p.Room])
-			}
-		} else {
-			for _, r := range p.Real_Room {
-				rlist = append(rlist, rmap[r])
+		for _, rtag := range rtags {
+			r, err := room(rtag)
+			if err != nil {
+				return nil, err
 			}
+			rlist = append(rlist, r)
 		}
 		placements = append(placements, ActivityPlacement{
 			Id:    p.Id,
@@ -75,5 +117,182 @@ func ReadPlacements(
 			Rooms: rlist,
 		})
 	}
-	return placements
+	return placements, nil
+}
+
+// ReadActivityMap reads a ".map" file, which has a line "<FET activity
+// number>:<lesson Id>" for each activity.
+func ReadActivityMap(mapfile string) (map[int]Ref, error) {
+	infile, err := os.Open(mapfile)
+	if err != nil {
+		return nil, err
+	}
+	// Remember to close the file at the end of the function
+	defer infile.Close()
+	return parseActivityMap(infile, mapfile)
+}
+
+func parseActivityMap(r io.Reader, mapfile string) (map[int]Ref, error) {
+	amap := map[int]Ref{}
+	// Read the file line by line using scanner
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		iref := strings.SplitN(scanner.Text(), ":", 2)
+		if len(iref) != 2 {
+			return nil, fmt.Errorf("invalid line in %s:\n  \"%s\"",
+				mapfile, scanner.Text())
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(iref[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid line in %s:\n  \"%s\"",
+				mapfile, scanner.Text())
+		}
+		amap[i] = Ref(strings.TrimSpace(iref[1]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return amap, nil
+}
+
+// FindActivitiesFile returns the path of the FET activities file. If path
+// is a folder, it is searched (recursively) for a file
+// "<stemfile>_activities.xml", or – if there is no such file – for a
+// single file "*_activities.xml".
+func FindActivitiesFile(path string, stemfile string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !fi.IsDir() {
+		return path, nil
+	}
+	// Files matching the stem, other activities files
+	found := [2][]string{}
+	err = filepath.WalkDir(path, func(
+		p string, d fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if d.Name() == stemfile+"_activities.xml" {
+			found[0] = append(found[0], p)
+		} else if strings.HasSuffix(d.Name(), "_activities.xml") {
+			found[1] = append(found[1], p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, flist := range found {
+		if len(flist) == 1 {
+			return flist[0], nil
+		}
+		if len(flist) > 1 {
+			return "", fmt.Errorf(
+				"more than one FET activities file in %s:\n  %s",
+				path, strings.Join(flist, "\n  "))
+		}
+	}
+	return "", fmt.Errorf("no FET activities file in %s", path)
+}
+
+// ApplyPlacements sets the times and rooms of the lessons from the result
+// of a FET run. Lessons which are not in the result are marked as not
+// placed. The placements are not checked here, that is done when the
+// activities are placed (ttbase.PrepareCoreData).
+func ApplyPlacements(
+	db *base.DbTopLevel,
+	mapfile string,
+	xmlpath string,
+) error {
+	activityMap, err := ReadActivityMap(mapfile)
+	if err != nil {
+		return err
+	}
+	placements, err := readPlacements(db, xmlpath)
+	if err != nil {
+		return err
+	}
+	return applyPlacements(db, activityMap, placements, mapfile)
+}
+
+// ApplyPlacementData is like ApplyPlacements, but takes the contents of the
+// ".map" file and the activities file (e.g. from an HTTP request).
+func ApplyPlacementData(
+	db *base.DbTopLevel,
+	mapdata []byte,
+	xmldata []byte,
+) error {
+	activityMap, err := parseActivityMap(bytes.NewReader(mapdata), "map")
+	if err != nil {
+		return err
+	}
+	placements, err := parsePlacements(db, xmldata, "activities")
+	if err != nil {
+		return err
+	}
+	return applyPlacements(db, activityMap, placements, "map")
+}
+
+func applyPlacements(
+	db *base.DbTopLevel,
+	activityMap map[int]Ref,
+	placements []ActivityPlacement,
+	mapfile string,
+) error {
+	errs := []error{}
+	pmap := map[Ref]ActivityPlacement{}
+	for _, p := range placements {
+		lref, ok := activityMap[p.Id]
+		if !ok {
+			errs = append(errs, fmt.Errorf(
+				"FET activity %d not in %s", p.Id, mapfile))
+			continue
+		}
+		if p.Day < 0 || p.Day >= len(db.Days) ||
+			p.Hour < 0 || p.Hour >= len(db.Hours) {
+			errs = append(errs, fmt.Errorf(
+				"FET activity %d: invalid time %d.%d", p.Id, p.Day, p.Hour))
+			continue
+		}
+		pmap[lref] = p
+	}
+	for _, l := range db.Lessons {
+		p, ok := pmap[l.Id]
+		if !ok {
+			if l.Day >= 0 {
+				db.Log.Warning.Printf("Lesson not placed by FET: %s\n", l.Id)
+			}
+			l.Day = -1
+			l.Hour = 0
+			l.Rooms = nil
+			continue
+		}
+		l.Day = p.Day
+		l.Hour = p.Hour
+		l.Rooms = p.Rooms
+	}
+	return errors.Join(errs...)
+}
+
+// DropInvalidPlacements marks the lessons whose placements were rejected
+// by ttbase.PrepareCoreData (with a warning) as not placed, so that the
+// invalid placements are not passed on, e.g. when saving the data. It
+// returns the number of lessons affected.
+func DropInvalidPlacements(ttinfo *ttbase.TtInfo) int {
+	n := 0
+	for _, a := range ttinfo.Activities[1:] {
+		if a.Placement < 0 && a.Lesson.Day >= 0 {
+			a.Lesson.Day = -1
+			a.Lesson.Hour = 0
+			a.Lesson.Rooms = nil
+			n++
+		}
+	}
+	return n
 }
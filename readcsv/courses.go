@@ -0,0 +1,201 @@
+package readcsv
+
+import (
+	"W365toFET/base"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type courseRow struct {
+	row      int
+	subject  Ref
+	groups   []Ref
+	teachers []Ref
+	room     Ref
+	lessons  []int
+}
+
+// Optional columns: Room, Block.
+// The lessons are given as their lengths, e.g. "2+1+1". A course with a
+// Block tag and lessons is the SuperCourse of the block, the other courses
+// with this tag (and no lessons) are its SubCourses.
+func (cd *csvData) readCourses(t *table) {
+	blocks := map[string][]*courseRow{}
+	blocktags := []string{}
+	for i := range t.rows {
+		crow := &courseRow{
+			row:      i,
+			subject:  cd.getSubject(t, i),
+			groups:   cd.getGroups(t, i),
+			teachers: cd.getTeachers(t, i),
+			room:     cd.getRoom(t, i),
+			lessons:  cd.getLessons(t, i),
+		}
+		block := t.cell(i, "Block")
+		if block != "" {
+			if _, ok := blocks[block]; !ok {
+				blocktags = append(blocktags, block)
+			}
+			blocks[block] = append(blocks[block], crow)
+			continue
+		}
+		if len(crow.lessons) == 0 {
+			cd.errorf(t, i, "Lessons", "Course has no lessons")
+			continue
+		}
+		c := cd.db.NewCourse("")
+		c.Subject = crow.subject
+		c.Groups = crow.groups
+		c.Teachers = crow.teachers
+		c.Room = crow.room
+		cd.addLessons(c.Id, crow.lessons)
+	}
+
+	for _, block := range blocktags {
+		var spc *base.SuperCourse
+		for _, crow := range blocks[block] {
+			if len(crow.lessons) == 0 {
+				continue
+			}
+			if spc != nil {
+				cd.errorf(t, crow.row, "Lessons",
+					"Block %s has more than one course with lessons", block)
+				continue
+			}
+			spc = cd.db.NewSuperCourse("")
+			spc.Subject = crow.subject
+			cd.addLessons(spc.Id, crow.lessons)
+		}
+		if spc == nil {
+			cd.errorf(t, blocks[block][0].row, "Block",
+				"Block %s has no course with lessons", block)
+			continue
+		}
+		for _, crow := range blocks[block] {
+			if len(crow.lessons) != 0 {
+				continue
+			}
+			sbc := cd.db.NewSubCourse("")
+			sbc.SuperCourses = []Ref{spc.Id}
+			sbc.Subject = crow.subject
+			sbc.Groups = crow.groups
+			sbc.Teachers = crow.teachers
+			sbc.Room = crow.room
+		}
+	}
+}
+
+func (cd *csvData) addLessons(cref Ref, lengths []int) {
+	for _, n := range lengths {
+		l := cd.db.NewLesson("")
+		l.Course = cref
+		l.Duration = n
+		l.Day = -1
+		l.Hour = -1
+		l.Rooms = []Ref{}
+	}
+}
+
+func (cd *csvData) getSubject(t *table, i int) Ref {
+	tag := cd.required(t, i, "Subject")
+	sref, ok := cd.subjects[tag]
+	if !ok && tag != "" {
+		cd.errorf(t, i, "Subject", "Unknown Subject: %s", tag)
+	}
+	return sref
+}
+
+// Groups are given as "class" or "class.group".
+func (cd *csvData) getGroups(t *table, i int) []Ref {
+	glist := []Ref{}
+	for _, g := range listCell(t, i, "Groups") {
+		gref, ok := cd.groups[g]
+		if ok {
+			glist = append(glist, gref)
+		} else {
+			cd.errorf(t, i, "Groups", "Unknown Class or Group: %s", g)
+		}
+	}
+	return glist
+}
+
+func (cd *csvData) getTeachers(t *table, i int) []Ref {
+	tlist := []Ref{}
+	for _, tt := range listCell(t, i, "Teachers") {
+		tref, ok := cd.teachers[tt]
+		if ok {
+			tlist = append(tlist, tref)
+		} else {
+			cd.errorf(t, i, "Teachers", "Unknown Teacher: %s", tt)
+		}
+	}
+	return tlist
+}
+
+// A Room or RoomGroup, or a choice of Rooms ("r1/r2/r3").
+func (cd *csvData) getRoom(t *table, i int) Ref {
+	rcell := t.cell(i, "Room")
+	if rcell == "" {
+		return ""
+	}
+	if !strings.Contains(rcell, CHOICE_SEP) {
+		rref, ok := cd.rooms[rcell]
+		if !ok {
+			cd.errorf(t, i, "Room", "Unknown Room: %s", rcell)
+		}
+		return rref
+	}
+	rlist := []Ref{}
+	for _, r := range strings.Split(rcell, CHOICE_SEP) {
+		rref, ok := cd.realRooms[strings.TrimSpace(r)]
+		if ok {
+			rlist = append(rlist, rref)
+		} else {
+			cd.errorf(t, i, "Room", "Unknown Room in choice: %s", r)
+		}
+	}
+	// Reuse an existing RoomChoiceGroup for the same rooms.
+	rcref, ok := cd.roomChoices[rcell]
+	if !ok {
+		e := cd.db.NewRoomChoiceGroup("")
+		e.Tag = cd.roomChoiceTag()
+		e.Name = rcell
+		e.Rooms = rlist
+		rcref = e.Id
+		cd.roomChoices[rcell] = rcref
+	}
+	return rcref
+}
+
+// A new tag for a RoomChoiceGroup, "[n]". As Rooms, RoomGroups and
+// RoomChoiceGroups share their tags, it may not be the tag of a room.
+func (cd *csvData) roomChoiceTag() string {
+	for n := len(cd.roomChoices) + 1; ; n++ {
+		tag := "[" + strconv.Itoa(n) + "]"
+		if _, ok := cd.rooms[tag]; ok {
+			continue
+		}
+		if !slices.ContainsFunc(cd.db.RoomChoiceGroups,
+			func(e *base.RoomChoiceGroup) bool { return e.Tag == tag }) {
+			return tag
+		}
+	}
+}
+
+func (cd *csvData) getLessons(t *table, i int) []int {
+	llist := []int{}
+	lcell := t.cell(i, "Lessons")
+	if lcell == "" {
+		return llist
+	}
+	for _, l := range strings.Split(lcell, "+") {
+		n, err := strconv.Atoi(strings.TrimSpace(l))
+		if err != nil || n < 1 || n > len(cd.hours) {
+			cd.errorf(t, i, "Lessons", "Invalid lesson length: %s", l)
+			continue
+		}
+		llist = append(llist, n)
+	}
+	return llist
+}
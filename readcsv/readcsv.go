@@ -0,0 +1,441 @@
+// Package readcsv reads timetable data from a folder of CSV files into a
+// base.DbTopLevel, as an alternative to the W365 input. See README for the
+// expected files and columns.
+package readcsv
+
+import (
+	"W365toFET/base"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Ref = base.Ref
+
+const GROUP_SEP = "."    // between class and group tags, e.g. "10.A"
+const DIVISION_SEP = "|" // between the divisions of a class
+const CHOICE_SEP = "/"   // between the rooms of a room choice
+
+type table struct {
+	file  string
+	cols  map[string]int
+	rows  [][]string
+	lines []int // line numbers in the file, for error reports
+}
+
+type csvData struct {
+	db          *base.DbTopLevel
+	nerrors     int
+	days        map[string]int
+	hours       map[string]int
+	teachers    map[string]Ref
+	subjects    map[string]Ref
+	rooms       map[string]Ref // Rooms and RoomGroups
+	realRooms   map[string]Ref
+	roomChoices map[string]Ref // room tags -> RoomChoiceGroup
+	groups      map[string]Ref // "class" or "class.group" -> Group
+}
+
+// LoadCSV reads the CSV files in the given folder into the (empty) base db.
+// All errors are reported before the program is stopped.
+func LoadCSV(newdb *base.DbTopLevel, dirpath string) {
+	cd := &csvData{
+		db:          newdb,
+		days:        map[string]int{},
+		hours:       map[string]int{},
+		teachers:    map[string]Ref{},
+		subjects:    map[string]Ref{},
+		rooms:       map[string]Ref{},
+		realRooms:   map[string]Ref{},
+		roomChoices: map[string]Ref{},
+		groups:      map[string]Ref{},
+	}
+	newdb.Log.Message.Printf("Reading CSV files in: %s\n", dirpath)
+	newdb.Info.Reference = filepath.Base(dirpath)
+	newdb.Info.MiddayBreak = []int{}
+	cd.readDays(cd.readTable(dirpath, "days.csv", "Tag", "Name"))
+	cd.readHours(cd.readTable(dirpath, "hours.csv", "Tag", "Name"))
+	cd.readTeachers(cd.readTable(dirpath, "teachers.csv", "Tag", "Name"))
+	cd.readSubjects(cd.readTable(dirpath, "subjects.csv", "Tag", "Name"))
+	cd.readRooms(cd.readTable(dirpath, "rooms.csv", "Tag", "Name"))
+	cd.readClasses(cd.readTable(dirpath, "classes.csv", "Tag"))
+	cd.readCourses(cd.readTable(dirpath, "courses.csv",
+		"Subject", "Groups", "Teachers", "Lessons"))
+	if cd.nerrors != 0 {
+		newdb.Log.Error.Fatalf("%d errors in CSV input\n", cd.nerrors)
+	}
+}
+
+// Read a CSV file, the first row being the column names. The field
+// separator can be "," or ";". Lines starting with "#" are ignored.
+func (cd *csvData) readTable(dirpath, fname string, required ...string) *table {
+	t := &table{file: fname, cols: map[string]int{}}
+	fpath := filepath.Join(dirpath, fname)
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		cd.nerrors++
+		cd.db.Log.Error.Printf("%v\n", err)
+		return t
+	}
+	head, _, _ := strings.Cut(string(data), "\n")
+	r := csv.NewReader(strings.NewReader(string(data)))
+	if strings.Count(head, ";") > strings.Count(head, ",") {
+		r.Comma = ';'
+	}
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	first := true
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			cd.nerrors++
+			cd.db.Log.Error.Printf("%s: %v\n", fname, err)
+			return t
+		}
+		if first {
+			for i, col := range record {
+				t.cols[strings.TrimSpace(col)] = i
+			}
+			first = false
+			continue
+		}
+		if slices.IndexFunc(record, func(s string) bool {
+			return strings.TrimSpace(s) != ""
+		}) < 0 {
+			continue // empty row
+		}
+		line, _ := r.FieldPos(0)
+		t.rows = append(t.rows, record)
+		t.lines = append(t.lines, line)
+	}
+	for _, col := range required {
+		if _, ok := t.cols[col]; !ok {
+			cd.nerrors++
+			cd.db.Log.Error.Printf("%s: Column missing: %s\n", fname, col)
+		}
+	}
+	return t
+}
+
+func (cd *csvData) errorf(t *table, i int, col string, f string, a ...any) {
+	cd.nerrors++
+	cd.db.Log.Error.Printf("%s, line %d, column %s: %s\n",
+		t.file, t.lines[i], col, fmt.Sprintf(f, a...))
+}
+
+// Get the value in the given row and column, "" if there is none.
+func (t *table) cell(i int, col string) string {
+	j, ok := t.cols[col]
+	if !ok || j >= len(t.rows[i]) {
+		return ""
+	}
+	return strings.TrimSpace(t.rows[i][j])
+}
+
+// Get a value which must be present.
+func (cd *csvData) required(t *table, i int, col string) string {
+	val := t.cell(i, col)
+	if val == "" {
+		cd.errorf(t, i, col, "Value missing")
+	}
+	return val
+}
+
+// Get an integer value, returning the default if the cell is empty.
+func (cd *csvData) intCell(t *table, i int, col string, dflt int) int {
+	val := t.cell(i, col)
+	if val == "" {
+		return dflt
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		cd.errorf(t, i, col, "Not a number: %s", val)
+		return dflt
+	}
+	return n
+}
+
+func (cd *csvData) boolCell(t *table, i int, col string) bool {
+	switch strings.ToLower(t.cell(i, col)) {
+	case "", "0", "-", "no", "nein", "false":
+		return false
+	case "1", "x", "yes", "ja", "true":
+		return true
+	}
+	cd.errorf(t, i, col, "Invalid value (true or false): %s",
+		t.cell(i, col))
+	return false
+}
+
+// Split a list of tags, separated by spaces or commas.
+func listCell(t *table, i int, col string) []string {
+	return strings.FieldsFunc(t.cell(i, col), func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+}
+
+// Read a list of blocked time slots: "Day.Hour" for single hours, "Day"
+// for whole days.
+func (cd *csvData) timeSlots(t *table, i int, col string) []base.TimeSlot {
+	tslist := []base.TimeSlot{}
+	for _, item := range listCell(t, i, col) {
+		dtag, htag, ok := strings.Cut(item, ".")
+		d, dok := cd.days[dtag]
+		if !dok {
+			cd.errorf(t, i, col, "Unknown Day: %s", dtag)
+			continue
+		}
+		if !ok {
+			for h := range len(cd.hours) {
+				tslist = append(tslist, base.TimeSlot{Day: d, Hour: h})
+			}
+			continue
+		}
+		h, hok := cd.hours[htag]
+		if !hok {
+			cd.errorf(t, i, col, "Unknown Hour: %s", htag)
+			continue
+		}
+		tslist = append(tslist, base.TimeSlot{Day: d, Hour: h})
+	}
+	slices.SortFunc(tslist, func(a, b base.TimeSlot) int {
+		if a.Day == b.Day {
+			return a.Hour - b.Hour
+		}
+		return a.Day - b.Day
+	})
+	return slices.Compact(tslist)
+}
+
+// Check that a tag is not empty and hasn't been used already.
+func (cd *csvData) newTag(t *table, i int, col string, used func(string) bool,
+) (string, bool) {
+	tag := cd.required(t, i, col)
+	if tag == "" {
+		return "", false
+	}
+	if used(tag) {
+		cd.errorf(t, i, col, "Tag defined twice: %s", tag)
+		return "", false
+	}
+	return tag, true
+}
+
+func (cd *csvData) readDays(t *table) {
+	for i := range t.rows {
+		tag, ok := cd.newTag(t, i, "Tag", func(s string) bool {
+			_, ok := cd.days[s]
+			return ok
+		})
+		if !ok {
+			continue
+		}
+		e := cd.db.NewDay("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		cd.days[tag] = len(cd.days)
+	}
+}
+
+// Optional columns: Start, End (times, "hh:mm"), Afternoon (first
+// afternoon hour) and Lunch (midday break).
+func (cd *csvData) readHours(t *table) {
+	for i := range t.rows {
+		tag, ok := cd.newTag(t, i, "Tag", func(s string) bool {
+			_, ok := cd.hours[s]
+			return ok
+		})
+		if !ok {
+			continue
+		}
+		h := len(cd.hours)
+		e := cd.db.NewHour("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		e.Start = t.cell(i, "Start")
+		e.End = t.cell(i, "End")
+		if cd.boolCell(t, i, "Afternoon") {
+			cd.db.Info.FirstAfternoonHour = h
+		}
+		if cd.boolCell(t, i, "Lunch") {
+			cd.db.Info.MiddayBreak = append(cd.db.Info.MiddayBreak, h)
+		}
+		cd.hours[tag] = h
+	}
+}
+
+// Optional columns: Firstname, Absences, MinLessonsPerDay,
+// MaxLessonsPerDay, MaxDays, MaxGapsPerDay, MaxGapsPerWeek, MaxAfternoons,
+// LunchBreak.
+func (cd *csvData) readTeachers(t *table) {
+	for i := range t.rows {
+		tag, ok := cd.newTag(t, i, "Tag", func(s string) bool {
+			_, ok := cd.teachers[s]
+			return ok
+		})
+		if !ok {
+			continue
+		}
+		e := cd.db.NewTeacher("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		e.Firstname = t.cell(i, "Firstname")
+		e.NotAvailable = cd.timeSlots(t, i, "Absences")
+		e.MinLessonsPerDay = cd.intCell(t, i, "MinLessonsPerDay", -1)
+		e.MaxLessonsPerDay = cd.intCell(t, i, "MaxLessonsPerDay", -1)
+		e.MaxDays = cd.intCell(t, i, "MaxDays", -1)
+		e.MaxGapsPerDay = cd.intCell(t, i, "MaxGapsPerDay", -1)
+		e.MaxGapsPerWeek = cd.intCell(t, i, "MaxGapsPerWeek", -1)
+		e.MaxAfternoons = cd.intCell(t, i, "MaxAfternoons", -1)
+		e.LunchBreak = cd.boolCell(t, i, "LunchBreak")
+		cd.teachers[tag] = e.Id
+	}
+}
+
+func (cd *csvData) readSubjects(t *table) {
+	for i := range t.rows {
+		tag, ok := cd.newTag(t, i, "Tag", func(s string) bool {
+			_, ok := cd.subjects[s]
+			return ok
+		})
+		if !ok {
+			continue
+		}
+		e := cd.db.NewSubject("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		cd.subjects[tag] = e.Id
+	}
+}
+
+// Optional columns: Absences, Rooms. A row with Rooms is a RoomGroup.
+func (cd *csvData) readRooms(t *table) {
+	used := func(s string) bool {
+		_, ok := cd.rooms[s]
+		return ok
+	}
+	// First the real rooms, then the room groups.
+	for i := range t.rows {
+		if t.cell(i, "Rooms") != "" {
+			continue
+		}
+		tag, ok := cd.newTag(t, i, "Tag", used)
+		if !ok {
+			continue
+		}
+		e := cd.db.NewRoom("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		e.NotAvailable = cd.timeSlots(t, i, "Absences")
+		cd.rooms[tag] = e.Id
+		cd.realRooms[tag] = e.Id
+	}
+	for i := range t.rows {
+		if t.cell(i, "Rooms") == "" {
+			continue
+		}
+		tag, ok := cd.newTag(t, i, "Tag", used)
+		if !ok {
+			continue
+		}
+		rlist := []Ref{}
+		for _, r := range listCell(t, i, "Rooms") {
+			rref, ok := cd.realRooms[r]
+			if ok {
+				rlist = append(rlist, rref)
+			} else {
+				cd.errorf(t, i, "Rooms", "Unknown Room: %s", r)
+			}
+		}
+		if t.cell(i, "Absences") != "" {
+			cd.errorf(t, i, "Absences", "Not allowed for a RoomGroup")
+		}
+		e := cd.db.NewRoomGroup("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		e.Rooms = rlist
+		cd.rooms[tag] = e.Id
+	}
+}
+
+// Optional columns: Name, Year, Letter, Divisions, Absences,
+// MinLessonsPerDay, MaxLessonsPerDay, MaxGapsPerDay, MaxGapsPerWeek,
+// MaxAfternoons, LunchBreak, ForceFirstHour.
+// The divisions are separated by "|", the groups within a division by
+// spaces or commas, e.g. "A B | F R".
+func (cd *csvData) readClasses(t *table) {
+	for i := range t.rows {
+		tag, ok := cd.newTag(t, i, "Tag", func(s string) bool {
+			_, ok := cd.groups[s]
+			return ok
+		})
+		if !ok {
+			continue
+		}
+		if strings.Contains(tag, GROUP_SEP) {
+			cd.errorf(t, i, "Tag", "Class tag may not contain '%s': %s",
+				GROUP_SEP, tag)
+			continue
+		}
+		divs := []base.Division{}
+		dcell := t.cell(i, "Divisions")
+		if dcell != "" {
+			for j, div := range strings.Split(dcell, DIVISION_SEP) {
+				glist := []Ref{}
+				for _, gtag := range strings.FieldsFunc(div,
+					func(r rune) bool { return r == ' ' || r == ',' },
+				) {
+					gname := tag + GROUP_SEP + gtag
+					if _, nok := cd.groups[gname]; nok {
+						cd.errorf(t, i, "Divisions",
+							"Group defined twice: %s", gtag)
+						continue
+					}
+					g := cd.db.NewGroup("")
+					g.Tag = gtag
+					cd.groups[gname] = g.Id
+					glist = append(glist, g.Id)
+				}
+				if len(glist) < 2 {
+					cd.errorf(t, i, "Divisions",
+						"Division %d needs at least two groups", j+1)
+				}
+				divs = append(divs, base.Division{
+					Name:   "#div" + strconv.Itoa(j+1),
+					Groups: glist,
+				})
+			}
+		}
+
+		// Add a Group for the whole class.
+		classGroup := cd.db.NewGroup("")
+		classGroup.Tag = ""
+		cd.groups[tag] = classGroup.Id
+
+		e := cd.db.NewClass("")
+		e.Tag = tag
+		e.Name = t.cell(i, "Name")
+		e.Year = cd.intCell(t, i, "Year", 0)
+		e.Letter = t.cell(i, "Letter")
+		e.NotAvailable = cd.timeSlots(t, i, "Absences")
+		e.Divisions = divs
+		e.MinLessonsPerDay = cd.intCell(t, i, "MinLessonsPerDay", -1)
+		e.MaxLessonsPerDay = cd.intCell(t, i, "MaxLessonsPerDay", -1)
+		e.MaxGapsPerDay = cd.intCell(t, i, "MaxGapsPerDay", -1)
+		e.MaxGapsPerWeek = cd.intCell(t, i, "MaxGapsPerWeek", -1)
+		e.MaxAfternoons = cd.intCell(t, i, "MaxAfternoons", -1)
+		e.LunchBreak = cd.boolCell(t, i, "LunchBreak")
+		e.ForceFirstHour = cd.boolCell(t, i, "ForceFirstHour")
+		e.ClassGroup = classGroup.Id
+	}
+}
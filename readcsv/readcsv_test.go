@@ -0,0 +1,146 @@
+package readcsv
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/ttbase"
+	"W365toFET/w365tt"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var inputdirs = []string{
+	"../testdata/csv",
+}
+
+func TestLoadCSV(t *testing.T) {
+	base.OpenLog("")
+	tmpdir := t.TempDir()
+	for _, dir := range inputdirs {
+		fmt.Println("\n ++++++++++++++++++++++")
+		db := base.NewDb()
+		LoadCSV(db, dir)
+		fmt.Printf("*** Teachers: %d, Classes: %d, Courses: %d,"+
+			" SuperCourses: %d, Lessons: %d\n",
+			len(db.Teachers), len(db.Classes), len(db.Courses),
+			len(db.SuperCourses), len(db.Lessons))
+
+		db.PrepareDb()
+		ttinfo := ttbase.MakeTtInfo(db)
+		ttinfo.PrepareCoreData()
+		xmlitem, _ := fet.MakeFetFile(ttinfo)
+		if len(xmlitem) == 0 {
+			t.Fatalf("No FET output: %s", dir)
+		}
+
+		fjson := filepath.Join(tmpdir, filepath.Base(dir)+"_w365.json")
+		if !w365tt.SaveJSON(db, fjson) {
+			t.Errorf("Export to W365 JSON failed: %s", dir)
+		}
+	}
+}
+
+// copyCSV copies the test CSV files to a new folder, changing the text
+// old to new in the file fname.
+func copyCSV(t *testing.T, fname, old, new string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := filepath.Glob(filepath.Join(inputdirs[0], "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(b)
+		if filepath.Base(f) == fname {
+			if !strings.Contains(text, old) {
+				t.Fatalf("%s: no %q", fname, old)
+			}
+			text = strings.Replace(text, old, new, 1)
+		}
+		err = os.WriteFile(filepath.Join(dir, filepath.Base(f)),
+			[]byte(text), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCSVErrors(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestCSVErrors")
+	for _, test := range []struct {
+		file, old, new string
+		want           string // file, line, column and message
+	}{
+		{"courses.csv", "De,5,BC,", "De,5,XY,",
+			"courses.csv, line 3, column Teachers: Unknown Teacher: XY"},
+		{"courses.csv", "Sp,5,EF,TH,", "Sp,5,EF,TH2,",
+			"courses.csv, line 6, column Room: Unknown Room: TH2"},
+		{"teachers.csv", "BC,Becker,Bernd,Fr,4,", "BC,Becker,Bernd,Fr,vier,",
+			"teachers.csv, line 3, column MaxDays: Not a number: vier"},
+		{"courses.csv", "Ma,5,AB,r5,2+1+1,", "Ma,5,AB,r5,2+x,",
+			"courses.csv, line 2, column Lessons: Invalid lesson length: x"},
+		{"subjects.csv", "De,Deutsch", "Ma,Deutsch",
+			"subjects.csv, line 3, column Tag: Tag defined twice: Ma"},
+		{"rooms.csv", "r6,Raum 6", "r5,Raum 6",
+			"rooms.csv, line 3, column Tag: Tag defined twice: r5"},
+	} {
+		dir := copyCSV(t, test.file, test.old, test.new)
+		db := base.NewDb()
+		buf := &bytes.Buffer{}
+		db.Log = base.NewLogSet(buf, true)
+		err := base.CatchAbort(func() { LoadCSV(db, dir) })
+		if err == nil {
+			t.Errorf("%s: no error", test.want)
+			continue
+		}
+		if !strings.Contains(buf.String(), test.want+"\n") {
+			t.Errorf("Expected %q, log:\n%s", test.want, buf)
+		}
+	}
+}
+
+func TestRoomChoiceTags(t *testing.T) {
+	// The generated tags of the room choices ("[n]") may not be those of
+	// real rooms.
+	base.OpenLog("")
+	fmt.Println("\n############## TestRoomChoiceTags")
+	dir := copyCSV(t, "rooms.csv", "r6,Raum 6", "[1],Raum 6\n[2],Raum 7")
+	fcourses := filepath.Join(dir, "courses.csv")
+	b, err := os.ReadFile(fcourses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.ReplaceAll(string(b), "r6", "[1]")
+	if err := os.WriteFile(fcourses, []byte(text), 0666); err != nil {
+		t.Fatal(err)
+	}
+	db := base.NewDb()
+	LoadCSV(db, dir)
+	tags := map[string]bool{}
+	for _, r := range db.Rooms {
+		tags[r.Tag] = true
+	}
+	for _, r := range db.RoomGroups {
+		tags[r.Tag] = true
+	}
+	if len(db.RoomChoiceGroups) == 0 {
+		t.Fatal("No room choices")
+	}
+	for _, r := range db.RoomChoiceGroups {
+		if tags[r.Tag] {
+			t.Errorf("Room choice %s: tag used twice: %s", r.Name, r.Tag)
+		}
+		tags[r.Tag] = true
+		fmt.Printf("  -- %s: %s\n", r.Tag, r.Name)
+	}
+}
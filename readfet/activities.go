@@ -0,0 +1,178 @@
+package readfet
+
+import (
+	"W365toFET/base"
+	"slices"
+)
+
+// The activities of an activity group (or a single activity with group
+// Id 0) become a Course, each activity a Lesson. Rooms and placements are
+// handled with the constraints.
+func (fetdata *fetData) readActivities() {
+	db := fetdata.db
+	alist := fetdata.fetin.Activities_List.Activity
+	slices.SortStableFunc(alist, func(a, b fetActivity) int {
+		return a.Id - b.Id
+	})
+	courses := map[int]*base.Course{} // activity group -> Course
+	for _, a := range alist {
+		if !a.Active {
+			fetdata.db.Log.Message.Printf(
+				"Ignoring inactive activity %d\n", a.Id)
+			continue
+		}
+		c, ok := courses[a.Activity_Group_Id]
+		if !ok {
+			c = db.NewCourse("")
+			sref, ok := fetdata.subjects[a.Subject]
+			if !ok {
+				fetdata.db.Log.Error.Fatalf("Activity %d: Unknown Subject %s\n",
+					a.Id, a.Subject)
+			}
+			c.Subject = sref
+			c.Teachers = []Ref{}
+			for _, t := range a.Teacher {
+				tn, ok := fetdata.teachers[t]
+				if !ok {
+					fetdata.db.Log.Error.Fatalf(
+						"Activity %d: Unknown Teacher %s\n",
+						a.Id, t)
+				}
+				c.Teachers = append(c.Teachers, tn.Id)
+			}
+			c.Groups = []Ref{}
+			for _, g := range a.Students {
+				gref, ok := fetdata.groups[g]
+				if ok {
+					c.Groups = append(c.Groups, gref)
+					continue
+				}
+				if _, ok := fetdata.subgroups[g]; ok {
+					fetdata.db.Log.Error.Printf(
+						"Activity %d: Subgroups are not"+
+							" supported, ignoring %s\n", a.Id, g)
+					continue
+				}
+				fetdata.db.Log.Error.Fatalf(
+					"Activity %d: Unknown Students %s\n",
+					a.Id, g)
+			}
+			if a.Activity_Group_Id != 0 {
+				courses[a.Activity_Group_Id] = c
+			}
+		}
+		l := db.NewLesson("")
+		l.Course = c.Id
+		l.Duration = a.Duration
+		l.Day = -1
+		l.Hour = -1
+		l.Rooms = []Ref{}
+		fetdata.activities[a.Id] = l
+		fetdata.activityList = append(fetdata.activityList, a.Id)
+	}
+}
+
+// Get the Course of an activity.
+func (fetdata *fetData) activityCourse(aid int) (*base.Course, bool) {
+	l, ok := fetdata.activities[aid]
+	if !ok {
+		fetdata.db.Log.Warning.Printf(
+			"Constraint on unknown activity: %d\n", aid)
+		return nil, false
+	}
+	return fetdata.db.Elements[l.Course].(*base.Course), true
+}
+
+// Get the Courses of a list of activities, each Course only once.
+func (fetdata *fetData) activityCourses(aids []int) []Ref {
+	crefs := []Ref{}
+	for _, aid := range aids {
+		c, ok := fetdata.activityCourse(aid)
+		if ok && !slices.Contains(crefs, c.Id) {
+			crefs = append(crefs, c.Id)
+		}
+	}
+	return crefs
+}
+
+func (fetdata *fetData) placeActivity(c *fetConstraint) {
+	for _, aid := range c.Activity_Id {
+		l, ok := fetdata.activities[aid]
+		if !ok {
+			fetdata.db.Log.Warning.Printf(
+				"Placement of unknown activity: %d\n", aid)
+			continue
+		}
+		ts, ok := fetdata.timeSlot(c.Preferred_Day, c.Preferred_Hour)
+		if !ok {
+			continue
+		}
+		l.Day = ts.Day
+		l.Hour = ts.Hour
+		l.Fixed = c.Permanently_Locked || c.Weight_Percentage == "100"
+	}
+}
+
+// A list of preferred rooms is the Course's room (if more than one room,
+// a RoomChoiceGroup).
+func (fetdata *fetData) setPreferredRooms(c *fetConstraint) {
+	var rref Ref
+	if len(c.Preferred_Room) == 1 {
+		var ok bool
+		rref, ok = fetdata.rooms[c.Preferred_Room[0]]
+		if !ok {
+			fetdata.db.Log.Error.Fatalf(
+				"Unknown Room: %s\n", c.Preferred_Room[0])
+		}
+	} else if len(c.Preferred_Room) > 1 {
+		rref = fetdata.makeRoomChoiceGroup(c.Preferred_Room)
+	} else {
+		return
+	}
+	for _, aid := range c.Activity_Id {
+		course, ok := fetdata.activityCourse(aid)
+		if !ok {
+			continue
+		}
+		if course.Room == "" {
+			course.Room = rref
+		} else if course.Room != rref {
+			fetdata.db.Log.Warning.Printf(
+				"Activity %d: Different preferred rooms in course\n", aid)
+		}
+	}
+}
+
+// A single preferred room is taken as the room of a placed Lesson. It
+// is also used for the Course if there were no preferred rooms.
+func (fetdata *fetData) setPreferredRoom(c *fetConstraint) {
+	rref, ok := fetdata.rooms[c.Room]
+	if !ok {
+		fetdata.db.Log.Error.Fatalf("Unknown Room: %s\n", c.Room)
+	}
+	rlist := []Ref{}
+	if len(c.Real_Room) != 0 {
+		for _, r := range c.Real_Room {
+			rr, ok := fetdata.realRooms[r]
+			if !ok {
+				fetdata.db.Log.Error.Fatalf("Unknown Room: %s\n", r)
+			}
+			rlist = append(rlist, rr)
+		}
+	} else if _, ok := fetdata.realRooms[c.Room]; ok {
+		rlist = append(rlist, rref)
+	}
+	for _, aid := range c.Activity_Id {
+		l, ok := fetdata.activities[aid]
+		if !ok {
+			continue
+		}
+		course := fetdata.db.Elements[l.Course].(*base.Course)
+		if course.Room == "" {
+			course.Room = rref
+		}
+		if l.Day >= 0 {
+			l.Rooms = rlist
+		}
+	}
+}
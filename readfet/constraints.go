@@ -0,0 +1,505 @@
+package readfet
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The constraints handled here are basically those generated by package
+// fet. Note that the adjustments to the gaps made by package fet for the
+// lunch breaks are not reversed.
+func (fetdata *fetData) readConstraints() {
+	clist := slices.Concat(
+		fetdata.fetin.Time_Constraints_List.Constraints,
+		fetdata.fetin.Space_Constraints_List.Constraints,
+	)
+	unsupported := map[string]int{}
+	minDays := []*fetConstraint{}
+	roomPlacements := []*fetConstraint{}
+	for i := range clist {
+		c := &clist[i]
+		if c.Active == "false" {
+			continue
+		}
+		ctype := c.XMLName.Local
+		ok := true
+		switch ctype {
+		case "ConstraintBasicCompulsoryTime",
+			"ConstraintBasicCompulsorySpace":
+
+		// *** Teachers
+		case "ConstraintTeacherNotAvailableTimes":
+			if t, ok := fetdata.teacher(c); ok {
+				t.NotAvailable = fetdata.timeSlots(c.Not_Available_Time)
+			}
+		case "ConstraintTeacherMaxDaysPerWeek":
+			if t, ok := fetdata.teacher(c); ok {
+				t.MaxDays = c.Max_Days_Per_Week
+			}
+		case "ConstraintTeacherMinHoursDaily":
+			if t, ok := fetdata.teacher(c); ok {
+				t.MinLessonsPerDay = c.Minimum_Hours_Daily
+			}
+		case "ConstraintTeacherMaxHoursDaily":
+			if t, ok := fetdata.teacher(c); ok {
+				t.MaxLessonsPerDay = c.Maximum_Hours_Daily
+			}
+		case "ConstraintTeacherMaxGapsPerDay":
+			if t, ok := fetdata.teacher(c); ok {
+				t.MaxGapsPerDay = c.Max_Gaps
+			}
+		case "ConstraintTeacherMaxGapsPerWeek":
+			if t, ok := fetdata.teacher(c); ok {
+				t.MaxGapsPerWeek = c.Max_Gaps
+			}
+		case "ConstraintTeacherIntervalMaxDaysPerWeek":
+			ok = fetdata.isAfternoons(c)
+			if t, tok := fetdata.teacher(c); ok && tok {
+				t.MaxAfternoons = c.Max_Days_Per_Week
+			}
+		case "ConstraintTeacherMaxHoursDailyInInterval":
+			ok = fetdata.isLunchBreak(c)
+			if t, tok := fetdata.teacher(c); ok && tok {
+				t.LunchBreak = true
+			}
+
+		// *** Classes
+		case "ConstraintStudentsSetNotAvailableTimes":
+			if cl, ok := fetdata.class(c); ok {
+				cl.NotAvailable = fetdata.timeSlots(c.Not_Available_Time)
+			}
+		case "ConstraintStudentsSetMinHoursDaily":
+			if cl, ok := fetdata.class(c); ok {
+				cl.MinLessonsPerDay = c.Minimum_Hours_Daily
+			}
+		case "ConstraintStudentsSetMaxHoursDaily":
+			if cl, ok := fetdata.class(c); ok {
+				cl.MaxLessonsPerDay = c.Maximum_Hours_Daily
+			}
+		case "ConstraintStudentsSetMaxGapsPerDay":
+			if cl, ok := fetdata.class(c); ok {
+				cl.MaxGapsPerDay = c.Max_Gaps
+			}
+		case "ConstraintStudentsSetMaxGapsPerWeek":
+			if cl, ok := fetdata.class(c); ok {
+				cl.MaxGapsPerWeek = c.Max_Gaps
+			}
+		case "ConstraintStudentsSetIntervalMaxDaysPerWeek":
+			ok = fetdata.isAfternoons(c)
+			if cl, cok := fetdata.class(c); ok && cok {
+				cl.MaxAfternoons = c.Max_Days_Per_Week
+			}
+		case "ConstraintStudentsSetMaxHoursDailyInInterval":
+			ok = fetdata.isLunchBreak(c)
+			if cl, cok := fetdata.class(c); ok && cok {
+				cl.LunchBreak = true
+			}
+		case "ConstraintStudentsSetEarlyMaxBeginningsAtSecondHour":
+			ok = c.Max_Beginnings_At_Second_Hour == 0
+			if cl, cok := fetdata.class(c); ok && cok {
+				cl.ForceFirstHour = true
+			}
+
+		// *** Rooms
+		case "ConstraintRoomNotAvailableTimes":
+			rref, rok := fetdata.realRooms[c.Room]
+			if rok {
+				r := fetdata.db.Elements[rref].(*base.Room)
+				r.NotAvailable = fetdata.timeSlots(c.Not_Available_Time)
+			} else {
+				fetdata.db.Log.Error.Printf(
+					"%s: Unknown Room %s\n", ctype, c.Room)
+			}
+
+		// *** Activities
+		case "ConstraintActivityPreferredStartingTime":
+			fetdata.placeActivity(c)
+		case "ConstraintActivityPreferredRooms":
+			fetdata.setPreferredRooms(c)
+		case "ConstraintActivityPreferredRoom":
+			// Handle these after all placements and preferred rooms.
+			roomPlacements = append(roomPlacements, c)
+		case "ConstraintMinDaysBetweenActivities":
+			minDays = append(minDays, c)
+		case "ConstraintActivitiesSameStartingTime":
+			fetdata.parallelCourses(c)
+		case "ConstraintActivityEndsStudentsDay":
+			fetdata.lessonsEndDay(c)
+		case "ConstraintActivityPreferredTimeSlots":
+			ok = fetdata.beforeAfterHour(c)
+		case "ConstraintActivitiesPreferredStartingTimes":
+			ok = fetdata.doubleLessonNotOverBreaks(c)
+
+		default:
+			ok = false
+		}
+		if !ok {
+			unsupported[ctype]++
+		}
+	}
+	for _, c := range roomPlacements {
+		fetdata.setPreferredRoom(c)
+	}
+	fetdata.daysBetween(minDays)
+
+	ulist := []string{}
+	for ctype, n := range unsupported {
+		ulist = append(ulist, ctype+": "+strconv.Itoa(n))
+	}
+	if len(ulist) != 0 {
+		slices.Sort(ulist)
+		fetdata.db.Log.Warning.Printf("Constraints not supported:\n  -- %s\n",
+			strings.Join(ulist, "\n  -- "))
+	}
+}
+
+// weight converts a FET weight (percentage), reporting invalid values.
+func (fetdata *fetData) weight(wfet string) int {
+	w, err := fet.Fet2Weight(wfet)
+	if err != nil {
+		fetdata.db.Log.Error.Printf("%v\n", err)
+	}
+	return w
+}
+
+func (fetdata *fetData) teacher(c *fetConstraint) (*base.Teacher, bool) {
+	t, ok := fetdata.teachers[c.Teacher]
+	if !ok {
+		fetdata.db.Log.Error.Printf("%s: Unknown Teacher %s\n",
+			c.XMLName.Local, c.Teacher)
+	}
+	return t, ok
+}
+
+// Only constraints on whole classes can be handled.
+func (fetdata *fetData) class(c *fetConstraint) (*base.Class, bool) {
+	cl, ok := fetdata.classes[c.Students]
+	if !ok {
+		fetdata.db.Log.Error.Printf("%s: Not a Class: %s\n",
+			c.XMLName.Local, c.Students)
+	}
+	return cl, ok
+}
+
+func (fetdata *fetData) timeSlots(tlist []fetTime) []base.TimeSlot {
+	tslist := []base.TimeSlot{}
+	for _, t := range tlist {
+		ts, ok := fetdata.timeSlot(t.Day, t.Hour)
+		if ok {
+			tslist = append(tslist, ts)
+		}
+	}
+	slices.SortFunc(tslist, func(a, b base.TimeSlot) int {
+		if a.Day == b.Day {
+			return a.Hour - b.Hour
+		}
+		return a.Day - b.Day
+	})
+	return tslist
+}
+
+// An interval from a start hour to the end of the day is taken as the
+// afternoons. The start hour must be the same for all these constraints.
+func (fetdata *fetData) isAfternoons(c *fetConstraint) bool {
+	if c.Interval_End_Hour != "" {
+		return false
+	}
+	h, ok := fetdata.hourIndex[c.Interval_Start_Hour]
+	if !ok {
+		return false
+	}
+	info := &fetdata.db.Info
+	if info.FirstAfternoonHour < 0 {
+		info.FirstAfternoonHour = h
+	}
+	return info.FirstAfternoonHour == h
+}
+
+// A maximum of all but one hours in an interval is taken as a lunch break.
+// The interval must be the same for all these constraints.
+func (fetdata *fetData) isLunchBreak(c *fetConstraint) bool {
+	h0, ok := fetdata.hourIndex[c.Interval_Start_Hour]
+	if !ok {
+		return false
+	}
+	h1, ok := fetdata.hourIndex[c.Interval_End_Hour]
+	if !ok {
+		if c.Interval_End_Hour != "" {
+			return false
+		}
+		h1 = len(fetdata.hourIndex)
+	}
+	if c.Maximum_Hours_Daily != h1-h0-1 {
+		return false
+	}
+	mb := []int{}
+	for h := h0; h < h1; h++ {
+		mb = append(mb, h)
+	}
+	info := &fetdata.db.Info
+	if len(info.MiddayBreak) == 0 {
+		info.MiddayBreak = mb
+	}
+	return slices.Equal(info.MiddayBreak, mb)
+}
+
+func (fetdata *fetData) parallelCourses(c *fetConstraint) {
+	crefs := fetdata.activityCourses(c.Activity_Id)
+	if len(crefs) < 2 {
+		return
+	}
+	w := fetdata.weight(c.Weight_Percentage)
+	// There is a FET constraint for each lesson of the courses.
+	for _, cn := range fetdata.db.Constraints {
+		pc, ok := cn.(*base.ParallelCourses)
+		if ok && pc.Weight == w && slices.Equal(pc.Courses, crefs) {
+			return
+		}
+	}
+	cn := fetdata.db.NewParallelCourses()
+	cn.Weight = w
+	cn.Courses = crefs
+}
+
+func (fetdata *fetData) lessonsEndDay(c *fetConstraint) {
+	w := fetdata.weight(c.Weight_Percentage)
+	for _, cref := range fetdata.activityCourses(c.Activity_Id) {
+		// There is a FET constraint for each lesson of the course.
+		found := false
+		for _, cn := range fetdata.db.Constraints {
+			led, ok := cn.(*base.LessonsEndDay)
+			if ok && led.Course == cref {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cn := fetdata.db.NewLessonsEndDay()
+			cn.Weight = w
+			cn.Course = cref
+		}
+	}
+}
+
+// Only time slots which allow the same hours before or after a certain
+// hour on every day are supported.
+func (fetdata *fetData) beforeAfterHour(c *fetConstraint) bool {
+	ndays := len(fetdata.dayIndex)
+	nhours := len(fetdata.hourIndex)
+	hours := make([][]int, ndays)
+	for _, pt := range c.Preferred_Time_Slot {
+		ts, ok := fetdata.timeSlot(pt.Preferred_Day, pt.Preferred_Hour)
+		if !ok {
+			return false
+		}
+		hours[ts.Day] = append(hours[ts.Day], ts.Hour)
+	}
+	hlist := hours[0]
+	slices.Sort(hlist)
+	for _, hl := range hours[1:] {
+		slices.Sort(hl)
+		if !slices.Equal(hl, hlist) {
+			return false
+		}
+	}
+	if len(hlist) == 0 {
+		return false
+	}
+	// Contiguous?
+	for i, h := range hlist {
+		if h != hlist[0]+i {
+			return false
+		}
+	}
+	var after bool
+	var hour int
+	if hlist[0] == 0 {
+		hour = len(hlist)
+	} else if hlist[len(hlist)-1] == nhours-1 {
+		after = true
+		hour = hlist[0] - 1
+	} else {
+		return false
+	}
+
+	w := fetdata.weight(c.Weight_Percentage)
+	var bah *base.BeforeAfterHour
+	for _, cn := range fetdata.db.Constraints {
+		cn0, ok := cn.(*base.BeforeAfterHour)
+		if ok && cn0.Weight == w && cn0.After == after && cn0.Hour == hour {
+			bah = cn0
+			break
+		}
+	}
+	if bah == nil {
+		bah = fetdata.db.NewBeforeAfterHour()
+		bah.Weight = w
+		bah.After = after
+		bah.Hour = hour
+		bah.Courses = []Ref{}
+	}
+	for _, cref := range fetdata.activityCourses(c.Activity_Id) {
+		if !slices.Contains(bah.Courses, cref) {
+			bah.Courses = append(bah.Courses, cref)
+		}
+	}
+	return true
+}
+
+// Only the form generated by package fet is supported: permitted starting
+// times for all double lessons, the same on every day.
+func (fetdata *fetData) doubleLessonNotOverBreaks(c *fetConstraint) bool {
+	if c.Duration != "2" || c.Teacher != "" || c.Students != "" ||
+		c.Subject != "" || c.Activity_Tag != "" {
+		return false
+	}
+	for _, cn := range fetdata.db.Constraints {
+		if _, ok := cn.(*base.DoubleLessonNotOverBreaks); ok {
+			return false
+		}
+	}
+	nhours := len(fetdata.hourIndex)
+	starts := map[int][]int{}
+	for _, ps := range c.Preferred_Starting_Time {
+		ts, ok := fetdata.timeSlot(
+			ps.Preferred_Starting_Day, ps.Preferred_Starting_Hour)
+		if !ok {
+			return false
+		}
+		starts[ts.Day] = append(starts[ts.Day], ts.Hour)
+	}
+	hlist := starts[0]
+	for d := 1; d < len(fetdata.dayIndex); d++ {
+		if !slices.Equal(starts[d], hlist) {
+			return false
+		}
+	}
+	// A break after hour h blocks a double lesson starting at h.
+	blist := []int{}
+	for h := 0; h < nhours-1; h++ {
+		if !slices.Contains(hlist, h) {
+			blist = append(blist, h+1)
+		}
+	}
+	cn := fetdata.db.NewDoubleLessonNotOverBreaks()
+	cn.Weight = fetdata.weight(c.Weight_Percentage)
+	cn.Hours = blist
+	return true
+}
+
+type daysBetweenKey struct {
+	weight  int
+	ndays   int
+	consec  bool
+	course1 Ref
+	course2 Ref
+}
+
+// Convert the min-days-between-activities constraints. Those within a
+// single Course with one day between them are used for the general
+// AutomaticDifferentDays constraint (taking the most common form) and
+// for DaysBetween constraints on courses differing from this. Those
+// between two Courses become DaysBetweenJoin constraints.
+func (fetdata *fetData) daysBetween(clist []*fetConstraint) {
+	db := fetdata.db
+	courseKeys := map[Ref][]daysBetweenKey{}
+	joins := []daysBetweenKey{}
+	for _, c := range clist {
+		crefs := fetdata.activityCourses(c.Activity_Id)
+		k := daysBetweenKey{
+			weight: fetdata.weight(c.Weight_Percentage),
+			ndays:  c.MinDays,
+			consec: c.Consecutive_If_Same_Day,
+		}
+		switch len(crefs) {
+		case 1:
+			if !slices.Contains(courseKeys[crefs[0]], k) {
+				courseKeys[crefs[0]] = append(courseKeys[crefs[0]], k)
+			}
+		case 2:
+			k.course1 = crefs[0]
+			k.course2 = crefs[1]
+			if !slices.Contains(joins, k) {
+				joins = append(joins, k)
+			}
+		default:
+			fetdata.db.Log.Warning.Printf("ConstraintMinDaysBetweenActivities"+
+				" on more than two courses not supported: %v\n",
+				c.Activity_Id)
+		}
+	}
+
+	// Find the most common form for the different-days constraint.
+	counts := map[daysBetweenKey]int{}
+	dd := daysBetweenKey{}
+	for _, c := range db.Courses {
+		for _, k := range courseKeys[c.Id] {
+			if k.ndays == 1 {
+				counts[k]++
+				if counts[k] > counts[dd] {
+					dd = k
+				}
+			}
+		}
+	}
+	add := db.NewAutomaticDifferentDays()
+	add.Weight = dd.weight
+	add.ConsecutiveIfSameDay = dd.consec
+
+	// Count the fixed and unfixed lessons in each course. As in ttbase,
+	// only courses with unfixed lessons to separate need constraints.
+	nfixed := map[Ref]int{}
+	nunfixed := map[Ref]int{}
+	for _, l := range db.Lessons {
+		if l.Fixed {
+			nfixed[l.Course]++
+		} else {
+			nunfixed[l.Course]++
+		}
+	}
+	dbmap := map[daysBetweenKey]*base.DaysBetween{}
+	addDaysBetween := func(k daysBetweenKey, cref Ref) {
+		cn, ok := dbmap[k]
+		if !ok {
+			cn = db.NewDaysBetween()
+			cn.Weight = k.weight
+			cn.DaysBetween = k.ndays
+			cn.ConsecutiveIfSameDay = k.consec
+			cn.Courses = []Ref{}
+			dbmap[k] = cn
+		}
+		cn.Courses = append(cn.Courses, cref)
+	}
+	for _, c := range db.Courses {
+		if nunfixed[c.Id] == 0 || (nfixed[c.Id] == 0 && nunfixed[c.Id] == 1) {
+			continue
+		}
+		hasdd := false
+		for _, k := range courseKeys[c.Id] {
+			if k == dd {
+				hasdd = true
+				continue
+			}
+			addDaysBetween(k, c.Id)
+			if k.ndays == 1 {
+				hasdd = true
+			}
+		}
+		if !hasdd && dd.weight != 0 {
+			// Override the default constraint.
+			addDaysBetween(daysBetweenKey{ndays: 1}, c.Id)
+		}
+	}
+
+	for _, k := range joins {
+		cn := db.NewDaysBetweenJoin()
+		cn.Weight = k.weight
+		cn.Course1 = k.course1
+		cn.Course2 = k.course2
+		cn.DaysBetween = k.ndays
+		cn.ConsecutiveIfSameDay = k.consec
+	}
+}
@@ -0,0 +1,154 @@
+package readfet
+
+import (
+	"encoding/xml"
+)
+
+// Only the parts of a FET file which can be represented in the base db
+// are read. The constraints are collected as generic elements, the fields
+// used depend on the constraint type (the element name).
+
+type fetFile struct {
+	XMLName                xml.Name `xml:"fet"`
+	Institution_Name       string
+	Comments               string
+	Days_List              fetDaysList
+	Hours_List             fetHoursList
+	Teachers_List          fetTeachersList
+	Subjects_List          fetSubjectsList
+	Rooms_List             fetRoomsList
+	Students_List          fetStudentsList
+	Activities_List        fetActivitiesList
+	Time_Constraints_List  fetConstraintsList
+	Space_Constraints_List fetConstraintsList
+}
+
+type fetItem struct { // Day, Hour, Teacher, Subject
+	Name      string
+	Long_Name string
+}
+
+type fetDaysList struct {
+	Day []fetItem
+}
+
+type fetHoursList struct {
+	Hour []fetItem
+}
+
+type fetTeachersList struct {
+	Teacher []fetItem
+}
+
+type fetSubjectsList struct {
+	Subject []fetItem
+}
+
+type fetRoom struct {
+	Name              string
+	Long_Name         string
+	Virtual           bool
+	Set_of_Real_Rooms []fetRealRoomSet
+}
+
+type fetRealRoomSet struct {
+	Real_Room []string
+}
+
+type fetRoomsList struct {
+	Room []fetRoom
+}
+
+type fetCategory struct {
+	Division []string
+}
+
+type fetSubgroup struct {
+	Name string
+}
+
+type fetGroup struct {
+	Name     string
+	Subgroup []fetSubgroup
+}
+
+type fetYear struct {
+	Name      string
+	Long_Name string
+	Separator string
+	Category  []fetCategory
+	Group     []fetGroup
+}
+
+type fetStudentsList struct {
+	Year []fetYear
+}
+
+type fetActivity struct {
+	Id                int
+	Teacher           []string
+	Subject           string
+	Students          []string
+	Active            bool
+	Duration          int
+	Activity_Group_Id int
+}
+
+type fetActivitiesList struct {
+	Activity []fetActivity
+}
+
+type fetTime struct {
+	Day  string
+	Hour string
+}
+
+type fetPreferredTime struct {
+	Preferred_Day  string
+	Preferred_Hour string
+}
+
+type fetPreferredStart struct {
+	Preferred_Starting_Day  string
+	Preferred_Starting_Hour string
+}
+
+type fetConstraint struct {
+	XMLName           xml.Name
+	Weight_Percentage string
+	Active            string // "false" => ignore the constraint
+
+	// Resources
+	Teacher      string
+	Students     string
+	Room         string
+	Subject      string
+	Activity_Tag string
+	Duration     string
+
+	// Resource constraints
+	Not_Available_Time            []fetTime
+	Max_Days_Per_Week             int
+	Minimum_Hours_Daily           int
+	Maximum_Hours_Daily           int
+	Max_Gaps                      int
+	Interval_Start_Hour           string
+	Interval_End_Hour             string
+	Max_Beginnings_At_Second_Hour int
+
+	// Activity constraints
+	Activity_Id             []int
+	Preferred_Day           string
+	Preferred_Hour          string
+	Permanently_Locked      bool
+	Consecutive_If_Same_Day bool
+	MinDays                 int
+	Preferred_Time_Slot     []fetPreferredTime
+	Preferred_Starting_Time []fetPreferredStart
+	Preferred_Room          []string
+	Real_Room               []string
+}
+
+type fetConstraintsList struct {
+	Constraints []fetConstraint `xml:",any"`
+}
@@ -0,0 +1,408 @@
+// Package readfet reads a FET file into a base.DbTopLevel.
+//
+// FET has no Ids, so new ones are generated for all elements. Where the
+// FET file was generated by package fet, the packed names (e.g.
+// "Tag*Name@Start-End" for hours) are unpacked. The activities of an
+// activity group become a Course with one Lesson per activity.
+package readfet
+
+import (
+	"W365toFET/base"
+	"encoding/xml"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Ref = base.Ref
+
+type fetData struct {
+	db           *base.DbTopLevel
+	fetin        *fetFile
+	dayIndex     map[string]int
+	hourIndex    map[string]int
+	teachers     map[string]*base.Teacher
+	subjects     map[string]Ref
+	rooms        map[string]Ref // FET room -> Room, RoomGroup, etc.
+	realRooms    map[string]Ref
+	roomTags     map[string]bool
+	roomChoices  map[string]Ref // room tags -> RoomChoiceGroup
+	classes      map[string]*base.Class
+	groups       map[string]Ref    // FET Year or Group -> Group
+	subgroups    map[string]string // FET Subgroup -> FET Year
+	activities   map[int]*base.Lesson
+	activityList []int // activities in order
+}
+
+func ReadFet(fetpath string) *fetFile {
+	return readFet(base.DefaultLog, fetpath)
+}
+
+func readFet(log *base.LogSet, fetpath string) *fetFile {
+	// Open the  XML file
+	xmlFile, err := os.Open(fetpath)
+	if err != nil {
+		log.Error.Fatal(err)
+	}
+	// Remember to close the file at the end of the function
+	defer xmlFile.Close()
+	// read the opened XML file as a byte array.
+	log.Message.Printf("Reading: %s\n", fetpath)
+	byteValue, _ := io.ReadAll(xmlFile)
+	v := fetFile{}
+	err = xml.Unmarshal(byteValue, &v)
+	if err != nil {
+		log.Error.Fatalf("XML error in %s:\n %v\n", fetpath, err)
+	}
+	return &v
+}
+
+// LoadFet reads a FET file into the given (empty) base db.
+func LoadFet(newdb *base.DbTopLevel, fetpath string) {
+	fetdata := &fetData{
+		db:          newdb,
+		fetin:       readFet(newdb.Log, fetpath),
+		dayIndex:    map[string]int{},
+		hourIndex:   map[string]int{},
+		teachers:    map[string]*base.Teacher{},
+		subjects:    map[string]Ref{},
+		rooms:       map[string]Ref{},
+		realRooms:   map[string]Ref{},
+		roomTags:    map[string]bool{},
+		roomChoices: map[string]Ref{},
+		classes:     map[string]*base.Class{},
+		groups:      map[string]Ref{},
+		subgroups:   map[string]string{},
+		activities:  map[int]*base.Lesson{},
+	}
+	newdb.Info.Institution = fetdata.fetin.Institution_Name
+	newdb.Info.Reference = fetdata.fetin.Comments
+	newdb.Info.FirstAfternoonHour = -1
+	newdb.Info.MiddayBreak = []int{}
+	fetdata.readDays()
+	fetdata.readHours()
+	fetdata.readTeachers()
+	fetdata.readSubjects()
+	fetdata.readRooms()
+	fetdata.readClasses()
+	fetdata.readActivities()
+	fetdata.readConstraints()
+	if newdb.Info.FirstAfternoonHour < 0 {
+		newdb.Info.FirstAfternoonHour = 0
+	}
+}
+
+// Split a packed FET name ("Tag*Name") into tag and name. If there is no
+// "*", the FET name is the tag and the long name is the name.
+func splitName(item fetItem) (string, string) {
+	tag, name, ok := strings.Cut(item.Long_Name, "*")
+	if ok {
+		return tag, name
+	}
+	if item.Long_Name == "" {
+		return item.Name, item.Name
+	}
+	return item.Name, item.Long_Name
+}
+
+func (fetdata *fetData) readDays() {
+	for i, n := range fetdata.fetin.Days_List.Day {
+		tag, name := splitName(n)
+		e := fetdata.db.NewDay("")
+		e.Tag = tag
+		e.Name = name
+		fetdata.dayIndex[n.Name] = i
+	}
+}
+
+func (fetdata *fetData) readHours() {
+	for i, n := range fetdata.fetin.Hours_List.Hour {
+		tag, name := splitName(n)
+		// A packed name may also have the times: "Name@Start-End".
+		name, times, _ := strings.Cut(name, "@")
+		start, end, _ := strings.Cut(times, "-")
+		e := fetdata.db.NewHour("")
+		e.Tag = tag
+		e.Name = name
+		e.Start = start
+		e.End = end
+		fetdata.hourIndex[n.Name] = i
+	}
+}
+
+func (fetdata *fetData) readTeachers() {
+	for _, n := range fetdata.fetin.Teachers_List.Teacher {
+		// The long name is taken to be "Firstname Name".
+		lname := strings.TrimSpace(n.Long_Name)
+		e := fetdata.db.NewTeacher("")
+		e.Tag = n.Name
+		i := strings.LastIndex(lname, " ")
+		if i < 0 {
+			e.Name = lname
+		} else {
+			e.Firstname = lname[:i]
+			e.Name = lname[i+1:]
+		}
+		if e.Name == "" {
+			e.Name = n.Name
+		}
+		e.NotAvailable = []base.TimeSlot{}
+		e.MinLessonsPerDay = -1
+		e.MaxLessonsPerDay = -1
+		e.MaxDays = -1
+		e.MaxGapsPerDay = -1
+		e.MaxGapsPerWeek = -1
+		e.MaxAfternoons = -1
+		fetdata.teachers[n.Name] = e
+	}
+}
+
+func (fetdata *fetData) readSubjects() {
+	for _, n := range fetdata.fetin.Subjects_List.Subject {
+		e := fetdata.db.NewSubject("")
+		e.Tag = n.Name
+		e.Name = n.Long_Name
+		if e.Name == "" {
+			e.Name = n.Name
+		}
+		fetdata.subjects[n.Name] = e.Id
+	}
+}
+
+// The real rooms are read first, then the virtual ones. A virtual room
+// with a single set of real rooms becomes a RoomChoiceGroup, one with
+// sets of single rooms becomes a RoomGroup. Other virtual rooms can't be
+// represented, only their compulsory rooms are kept.
+func (fetdata *fetData) readRooms() {
+	db := fetdata.db
+	for _, n := range fetdata.fetin.Rooms_List.Room {
+		if n.Virtual {
+			continue
+		}
+		e := db.NewRoom("")
+		e.Tag = n.Name
+		e.Name = n.Long_Name
+		if e.Name == "" {
+			e.Name = n.Name
+		}
+		e.NotAvailable = []base.TimeSlot{}
+		fetdata.rooms[n.Name] = e.Id
+		fetdata.realRooms[n.Name] = e.Id
+		fetdata.roomTags[n.Name] = true
+	}
+	for _, n := range fetdata.fetin.Rooms_List.Room {
+		if !n.Virtual {
+			continue
+		}
+		if len(n.Set_of_Real_Rooms) == 1 {
+			fetdata.rooms[n.Name] = fetdata.makeRoomChoiceGroup(
+				n.Set_of_Real_Rooms[0].Real_Room)
+			continue
+		}
+		rlist := []Ref{}
+		for _, rs := range n.Set_of_Real_Rooms {
+			if len(rs.Real_Room) != 1 {
+				fetdata.db.Log.Warning.Printf("Virtual room %s: Room choice"+
+					" not supported, ignoring %s\n",
+					n.Name, strings.Join(rs.Real_Room, ","))
+				continue
+			}
+			rref, ok := fetdata.realRooms[rs.Real_Room[0]]
+			if !ok {
+				fetdata.db.Log.Error.Fatalf(
+					"Virtual room %s: Unknown Room %s\n",
+					n.Name, rs.Real_Room[0])
+			}
+			rlist = append(rlist, rref)
+		}
+		e := db.NewRoomGroup("")
+		e.Tag = fetdata.newRoomTag(n.Name)
+		e.Name = n.Long_Name
+		e.Rooms = rlist
+		fetdata.rooms[n.Name] = e.Id
+	}
+}
+
+// Get an unused room tag, based on the given one.
+func (fetdata *fetData) newRoomTag(tag string) string {
+	t := tag
+	i := 0
+	for fetdata.roomTags[t] {
+		i++
+		t = tag + "[" + strconv.Itoa(i) + "]"
+	}
+	fetdata.roomTags[t] = true
+	return t
+}
+
+// Get a RoomChoiceGroup for the given real rooms, reusing an existing one
+// for the same rooms.
+func (fetdata *fetData) makeRoomChoiceGroup(rooms []string) Ref {
+	rlist := []Ref{}
+	for _, r := range rooms {
+		rref, ok := fetdata.realRooms[r]
+		if !ok {
+			fetdata.db.Log.Error.Fatalf("Unknown Room in room choice: %s\n", r)
+		}
+		rlist = append(rlist, rref)
+	}
+	name := strings.Join(rooms, ",")
+	rcref, ok := fetdata.roomChoices[name]
+	if !ok {
+		// Make a new Tag
+		var tag string
+		for i := 1; ; i++ {
+			tag = "[" + strconv.Itoa(i) + "]"
+			if !fetdata.roomTags[tag] {
+				break
+			}
+		}
+		fetdata.roomTags[tag] = true
+		e := fetdata.db.NewRoomChoiceGroup("")
+		e.Tag = tag
+		e.Name = name
+		e.Rooms = rlist
+		rcref = e.Id
+		fetdata.roomChoices[name] = rcref
+	}
+	return rcref
+}
+
+// The FET years become Classes. Their divisions are taken from the FET
+// categories, if these are present. Any remaining groups are arranged
+// in divisions according to their subgroups: groups with no common
+// subgroups can share a division.
+func (fetdata *fetData) readClasses() {
+	db := fetdata.db
+	for _, y := range fetdata.fetin.Students_List.Year {
+		sep := y.Separator
+		if sep == "" {
+			sep = " "
+		}
+		// Group tags without class prefix
+		gtags := map[string]string{}
+		subgroups := map[string][]string{}
+		for _, g := range y.Group {
+			gtag, ok := strings.CutPrefix(g.Name, y.Name+sep)
+			if !ok {
+				gtag = g.Name
+			}
+			gtags[gtag] = g.Name
+			sglist := []string{}
+			for _, sg := range g.Subgroup {
+				sglist = append(sglist, sg.Name)
+				fetdata.subgroups[sg.Name] = y.Name
+			}
+			subgroups[g.Name] = sglist
+		}
+
+		// Make the groups, collecting them into divisions.
+		grefs := map[string]Ref{}
+		divs := [][]string{}
+		used := map[string]bool{}
+		for _, cat := range y.Category {
+			div := []string{}
+			for _, gtag := range cat.Division {
+				gname, ok := gtags[gtag]
+				if !ok {
+					fetdata.db.Log.Warning.Printf(
+						"Year %s: Group %s not defined\n",
+						y.Name, gtag)
+					continue
+				}
+				div = append(div, gname)
+				used[gname] = true
+			}
+			divs = append(divs, div)
+		}
+		for _, g := range y.Group {
+			if used[g.Name] {
+				continue
+			}
+			nc := len(y.Category)
+			i := slices.IndexFunc(divs[nc:], func(div []string) bool {
+				if len(subgroups[g.Name]) == 0 {
+					return false
+				}
+				for _, g1 := range div {
+					for _, sg := range subgroups[g1] {
+						if slices.Contains(subgroups[g.Name], sg) {
+							return false
+						}
+					}
+				}
+				return true
+			})
+			if i < 0 {
+				divs = append(divs, []string{g.Name})
+			} else {
+				// Don't change the divisions from the categories.
+				divs[nc+i] = append(divs[nc+i], g.Name)
+			}
+		}
+		bdivs := []base.Division{}
+		for i, div := range divs {
+			glist := []Ref{}
+			for _, gname := range div {
+				gref, ok := grefs[gname]
+				if !ok {
+					g := db.NewGroup("")
+					g.Tag, _ = strings.CutPrefix(gname, y.Name+sep)
+					gref = g.Id
+					grefs[gname] = gref
+					fetdata.groups[gname] = gref
+				}
+				glist = append(glist, gref)
+			}
+			if len(glist) < 2 {
+				fetdata.db.Log.Warning.Printf("In Class %s,"+
+					" not enough Groups (>1) in Division %d\n",
+					y.Name, i+1)
+			}
+			bdivs = append(bdivs, base.Division{
+				Name:   "#div" + strconv.Itoa(i+1),
+				Groups: glist,
+			})
+		}
+
+		// Add a Group for the whole class.
+		classGroup := db.NewGroup("")
+		classGroup.Tag = ""
+		fetdata.groups[y.Name] = classGroup.Id
+
+		e := db.NewClass("")
+		e.Tag = y.Name
+		e.Name = y.Long_Name
+		// Try to get year and letter from the tag.
+		i := strings.IndexFunc(y.Name, func(r rune) bool {
+			return r < '0' || r > '9'
+		})
+		if i < 0 {
+			i = len(y.Name)
+		}
+		e.Year, _ = strconv.Atoi(y.Name[:i])
+		e.Letter = y.Name[i:]
+		e.NotAvailable = []base.TimeSlot{}
+		e.Divisions = bdivs
+		e.MinLessonsPerDay = -1
+		e.MaxLessonsPerDay = -1
+		e.MaxGapsPerDay = -1
+		e.MaxGapsPerWeek = -1
+		e.MaxAfternoons = -1
+		e.ClassGroup = classGroup.Id
+		fetdata.classes[y.Name] = e
+	}
+}
+
+// Get the TimeSlot for FET day and hour names.
+func (fetdata *fetData) timeSlot(day, hour string) (base.TimeSlot, bool) {
+	d, ok1 := fetdata.dayIndex[day]
+	h, ok2 := fetdata.hourIndex[hour]
+	if !ok1 || !ok2 {
+		fetdata.db.Log.Error.Printf("Invalid time: %s.%s\n", day, hour)
+		return base.TimeSlot{}, false
+	}
+	return base.TimeSlot{Day: d, Hour: h}, true
+}
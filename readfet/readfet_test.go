@@ -0,0 +1,228 @@
+package readfet
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/ttbase"
+	"W365toFET/w365tt"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var inputfiles = []string{
+	"../testdata/Versuch_D_Margin_hour_constraint.fet",
+	"../testdata/readxml/Demo1.fet",
+	"../testdata/readxml/x01.fet",
+	"../testdata/readfet/test.fet",
+}
+
+func TestReadFet(t *testing.T) {
+	base.OpenLog("")
+	tmpdir := t.TempDir()
+	for _, ffet := range inputfiles {
+		fmt.Println("\n ++++++++++++++++++++++")
+		db := base.NewDb()
+		LoadFet(db, ffet)
+		fmt.Printf("*** Courses: %d, Lessons: %d, Constraints: %d\n",
+			len(db.Courses), len(db.Lessons), len(db.Constraints))
+
+		db.PrepareDb()
+		ttinfo := ttbase.MakeTtInfo(db)
+		ttinfo.PrepareCoreData()
+
+		// The FET file produced from the imported data should have the
+		// same activities.
+		fetin := ReadFet(ffet)
+		xmlitem, _ := fet.MakeFetFile(ttinfo)
+		if len(xmlitem) == 0 {
+			t.Fatalf("No FET output: %s", ffet)
+		}
+		if len(fetin.Activities_List.Activity) != len(db.Lessons) {
+			t.Errorf("%s: %d activities, %d lessons", ffet,
+				len(fetin.Activities_List.Activity), len(db.Lessons))
+		}
+
+		// Conversion to W365 JSON
+		fjson := filepath.Join(tmpdir, filepath.Base(ffet)+"_w365.json")
+		if !w365tt.SaveJSON(db, fjson) {
+			t.Errorf("Export to W365 JSON failed: %s", ffet)
+		}
+	}
+}
+
+// The last test file has the structures which need converting: virtual
+// rooms, a year with categories and the constraints on activities.
+var testfet = inputfiles[len(inputfiles)-1]
+
+// elementTags returns the Tags of the referenced elements.
+func elementTags(db *base.DbTopLevel, refs []base.Ref) []string {
+	tags := []string{}
+	for _, ref := range refs {
+		switch e := db.Elements[ref].(type) {
+		case *base.Room:
+			tags = append(tags, e.Tag)
+		case *base.RoomGroup:
+			tags = append(tags, e.Tag)
+		case *base.RoomChoiceGroup:
+			tags = append(tags, e.Tag)
+		case *base.Group:
+			tags = append(tags, e.Tag)
+		default:
+			tags = append(tags, string(ref))
+		}
+	}
+	return tags
+}
+
+func TestRooms(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestRooms")
+	db := base.NewDb()
+	LoadFet(db, testfet)
+
+	// A virtual room with one set of rooms is a choice, the one with sets
+	// of single rooms is a RoomGroup. The list of preferred rooms for
+	// activity 4 is a further choice.
+	if len(db.Rooms) != 3 {
+		t.Errorf("Rooms: %d, expected 3", len(db.Rooms))
+	}
+	rcgs := map[string]string{}
+	for _, e := range db.RoomChoiceGroups {
+		rcgs[e.Name] = strings.Join(elementTags(db, e.Rooms), ",")
+	}
+	if len(rcgs) != 2 || rcgs["r1,r2"] != "r1,r2" || rcgs["r2,r3"] != "r2,r3" {
+		t.Errorf("RoomChoiceGroups: %v", rcgs)
+	}
+	if len(db.RoomGroups) != 1 {
+		t.Fatalf("RoomGroups: %d, expected 1", len(db.RoomGroups))
+	}
+	rg := db.RoomGroups[0]
+	if rg.Tag != "V2" || !slices.Equal(
+		elementTags(db, rg.Rooms), []string{"r1", "r3"}) {
+		t.Errorf("RoomGroup %s: %v", rg.Tag, elementTags(db, rg.Rooms))
+	}
+
+	// The rooms of the courses, and of the placed lesson
+	rooms := map[string]string{}
+	for _, c := range db.Courses {
+		s := db.Elements[c.Subject].(*base.Subject).Tag
+		tch := db.Elements[c.Teachers[0]].(*base.Teacher).Tag
+		r := db.Elements[c.Room]
+		switch e := r.(type) {
+		case *base.RoomChoiceGroup:
+			rooms[s+"/"+tch] = e.Name
+		case *base.RoomGroup:
+			rooms[s+"/"+tch] = e.Tag
+		}
+	}
+	if rooms["Ma/AA"] != "r1,r2" || rooms["De/BB"] != "V2" ||
+		rooms["De/AA"] != "r2,r3" {
+		t.Errorf("Course rooms: %v", rooms)
+	}
+	for _, l := range db.Lessons {
+		if l.Day >= 0 && !slices.Equal(
+			elementTags(db, l.Rooms), []string{"r1", "r3"}) {
+			t.Errorf("Lesson rooms: %v", elementTags(db, l.Rooms))
+		}
+	}
+}
+
+func TestClasses(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestClasses")
+	db := base.NewDb()
+	LoadFet(db, testfet)
+
+	if len(db.Classes) != 1 {
+		t.Fatalf("Classes: %d, expected 1", len(db.Classes))
+	}
+	c := db.Classes[0]
+	if c.Tag != "5" || c.Name != "Klasse 5" || c.Year != 5 ||
+		c.MinLessonsPerDay != 2 {
+		t.Errorf("Class: %+v", c)
+	}
+	// The divisions are the categories, the group tags without the class
+	divs := [][]string{}
+	for _, div := range c.Divisions {
+		divs = append(divs, elementTags(db, div.Groups))
+	}
+	if len(divs) != 2 || !slices.Equal(divs[0], []string{"A", "B"}) ||
+		!slices.Equal(divs[1], []string{"X", "Y"}) {
+		t.Errorf("Divisions: %v", divs)
+	}
+	if g, ok := db.Elements[c.ClassGroup].(*base.Group); !ok || g.Tag != "" {
+		t.Errorf("ClassGroup: %v", db.Elements[c.ClassGroup])
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestConstraints")
+	db := base.NewDb()
+	LoadFet(db, testfet)
+
+	courses := map[string]base.Ref{}
+	for _, c := range db.Courses {
+		s := db.Elements[c.Subject].(*base.Subject).Tag
+		tch := db.Elements[c.Teachers[0]].(*base.Teacher).Tag
+		courses[s+"/"+tch] = c.Id
+	}
+	for _, tt := range db.Teachers {
+		if tt.Tag == "AA" && (tt.MaxGapsPerDay != 1 ||
+			tt.Firstname != "Anna" || tt.Name != "Alt") {
+			t.Errorf("Teacher: %+v", tt)
+		}
+	}
+	w100, _ := fet.Fet2Weight("100")
+	w90, _ := fet.Fet2Weight("90")
+	counts := map[string]int{}
+	for _, cn := range db.Constraints {
+		counts[cn.CType()]++
+		switch c := cn.(type) {
+		case *base.ParallelCourses:
+			// Activities 3 and 4
+			if c.Weight != w100 || !slices.Equal(c.Courses,
+				[]base.Ref{courses["De/BB"], courses["De/AA"]}) {
+				t.Errorf("ParallelCourses: %+v", c)
+			}
+		case *base.LessonsEndDay:
+			// One constraint for both activities of the course
+			if c.Weight != w100 || c.Course != courses["Ma/AA"] {
+				t.Errorf("LessonsEndDay: %+v", c)
+			}
+		case *base.AutomaticDifferentDays:
+			// From the constraint within the course
+			if c.Weight != w100 || !c.ConsecutiveIfSameDay {
+				t.Errorf("AutomaticDifferentDays: %+v", c)
+			}
+		case *base.DaysBetweenJoin:
+			// From the constraint on activities 3 and 1
+			if c.Weight != w90 || c.DaysBetween != 1 ||
+				c.ConsecutiveIfSameDay ||
+				c.Course1 != courses["De/BB"] ||
+				c.Course2 != courses["Ma/AA"] {
+				t.Errorf("DaysBetweenJoin: %+v", c)
+			}
+		default:
+			t.Errorf("Unexpected constraint: %+v", c)
+		}
+	}
+	for _, ctype := range []string{"ParallelCourses", "LessonsEndDay",
+		"AutomaticDifferentDays", "DaysBetweenJoin"} {
+		if counts[ctype] != 1 {
+			t.Errorf("Constraints: %v", counts)
+			break
+		}
+	}
+
+	// The placement of activity 3
+	for _, l := range db.Lessons {
+		if l.Course == courses["De/BB"] &&
+			(l.Day != 1 || l.Hour != 1 || !l.Fixed) {
+			t.Errorf("Placement: %+v", l)
+		}
+	}
+}
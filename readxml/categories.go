@@ -1,7 +1,6 @@
 package readxml
 
 import (
-	"W365toFET/base"
 	"W365toFET/w365tt"
 	"strings"
 )
@@ -19,7 +18,7 @@ func (cdata *conversionData) withLunchBreak(
 	for _, catref := range splitRefList(refs) {
 		cat, ok := cdata.categories[catref]
 		if !ok {
-			base.Error.Fatalf("Teacher or Class (%s):\n"+
+			cdata.db.Log.Error.Fatalf("Teacher or Class (%s):\n"+
 				"  -- Invalid Category: %s", nodeId, catref)
 		}
 		//fmt.Printf("  :: %+v\n", cat)
@@ -41,7 +40,7 @@ func (cdata *conversionData) isStandIns(
 	for _, catref := range splitRefList(refs) {
 		cat, ok := cdata.categories[catref]
 		if !ok {
-			base.Error.Fatalf("Class (%s):\n"+
+			cdata.db.Log.Error.Fatalf("Class (%s):\n"+
 				"  -- Invalid Category: %s", nodeId, catref)
 		}
 		//fmt.Printf("  :: %+v\n", cat)
@@ -63,7 +62,7 @@ func (cdata *conversionData) getBlockTag(
 		for _, catref := range splitRefList(refs) {
 			cat, ok := cdata.categories[catref]
 			if !ok {
-				base.Error.Fatalf("Class (%s):\n"+
+				cdata.db.Log.Error.Fatalf("Class (%s):\n"+
 					"  -- Invalid Category: %s", nodeId, catref)
 			}
 			//fmt.Printf("  :: %+v\n", cat)
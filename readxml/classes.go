@@ -52,6 +52,7 @@ func (cdata *conversionData) readClasses() {
 		e.Year = n.Level
 		e.Letter = n.Letter
 		e.Tag = strconv.Itoa(n.Level) + n.Letter
+		e.Color = cdata.xmlColour(n.Color)
 
 		notAvailable := cdata.getAbsences(n.Absences,
 			fmt.Sprintf("In Class %s (Absences)", n.Id))
@@ -102,7 +103,8 @@ func (cdata *conversionData) readClasses() {
 		for i, wdivref := range splitRefList(n.Divisions) {
 			wdiv, ok := cdata.divisions[wdivref]
 			if !ok {
-				base.Error.Fatalf("In Class %s:\n  -- Invalid Division: %s\n",
+				cdata.db.Log.Error.Fatalf(
+					"In Class %s:\n  -- Invalid Division: %s\n",
 					n.Id, wdivref)
 			}
 			dname := wdiv.Name
@@ -114,19 +116,19 @@ func (cdata *conversionData) readClasses() {
 				c, ok := pregroups[gref]
 				if ok {
 					if c != nil {
-						base.Error.Fatalf("Group Defined in"+
+						cdata.db.Log.Error.Fatalf("Group Defined in"+
 							" multiple Divisions:\n  -- %s\n", gref)
 					}
 					// Flag Group and add to division's group list
 					pregroups[gref] = e
 					glist = append(glist, gref)
 				} else {
-					base.Error.Fatalf("Unknown Group in Class %s,"+
+					cdata.db.Log.Error.Fatalf("Unknown Group in Class %s,"+
 						" Division %s:\n  %s\n", e.Tag, wdiv.Name, gref)
 				}
 			}
 			if len(glist) < 2 {
-				base.Error.Fatalf("In Class %s,"+
+				cdata.db.Log.Error.Fatalf("In Class %s,"+
 					" not enough valid Groups (>1) in Division %s\n",
 					e.Tag, wdiv.Name)
 			}
@@ -146,7 +148,7 @@ func (cdata *conversionData) readClasses() {
 					pregroups[gref] = e
 				}
 			} else {
-				base.Error.Fatalf("Unknown Group in Class %s,"+
+				cdata.db.Log.Error.Fatalf("Unknown Group in Class %s,"+
 					" no Division:\n  %s\n", e.Tag, gref)
 			}
 		}
@@ -170,8 +172,8 @@ func (cdata *conversionData) readClasses() {
 	// Copy Groups.
 	for _, n := range cdata.xmlin.Groups {
 		if pregroups[n.Id] == nil {
-			base.Error.Printf("Group not attached to Class, removing:\n"+
-				"  -- %s\n", n.Id)
+			cdata.db.Log.Error.Printf(
+				"Group not attached to Class, removing:\n  -- %s\n", n.Id)
 			continue
 		}
 		g := db.NewGroup(n.Id)
@@ -202,7 +204,8 @@ func (cdata *conversionData) getCourseGroups(c *Course) []Ref {
 				continue
 			}
 		}
-		base.Error.Fatalf("In Course %s:\n  -- Invalid Course Group: %s\n",
+		cdata.db.Log.Error.Fatalf(
+			"In Course %s:\n  -- Invalid Course Group: %s\n",
 			c.Id, ref)
 	}
 	return glist
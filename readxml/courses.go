@@ -1,7 +1,6 @@
 package readxml
 
 import (
-	"W365toFET/base"
 	"log"
 	"strconv"
 	"strings"
@@ -40,7 +39,7 @@ func (cdata *conversionData) readCourses() map[Ref][]int {
 				if l != "" {
 					ll, err := strconv.Atoi(l)
 					if err != nil {
-						base.Error.Fatalf(" In Course %s:\n"+
+						cdata.db.Log.Error.Fatalf(" In Course %s:\n"+
 							"  -- SplitHoursPerWeek = %s\n",
 							n.Id, n.SplitHoursPerWeek)
 					}
@@ -48,7 +47,7 @@ func (cdata *conversionData) readCourses() map[Ref][]int {
 				}
 			}
 		} else if n.HoursPerWeek != 0.0 {
-			base.Warning.Printf("In Course %s:\n"+
+			cdata.db.Log.Warning.Printf("In Course %s:\n"+
 				"  -- No SplitHoursPerWeek specified\n", n.Id)
 			for i := 0; i < int(n.HoursPerWeek); i++ {
 				llen = append(llen, 1)
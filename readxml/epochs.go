@@ -0,0 +1,97 @@
+package readxml
+
+import (
+	"W365toFET/base"
+	"slices"
+)
+
+// The EpochPlans record which courses are taught in which weeks. The
+// courses of a plan in the upper school are normally SubCourses of a
+// block, whose SuperCourse has the lessons which are timetabled. This
+// SuperCourse is linked to the EpochPlan. Plain Courses (e.g. a class's
+// main lesson in the lower school) are also possible.
+// Draft plans are ignored.
+func (cdata *conversionData) readEpochPlans() {
+	db := cdata.db
+	lessonMap := map[Ref]*Lesson{}
+	for i := 0; i < len(cdata.xmlin.Lessons); i++ {
+		n := &cdata.xmlin.Lessons[i]
+		lessonMap[n.Id] = n
+	}
+	slices.SortFunc(cdata.xmlin.EpochPlans, func(a, b EpochPlan) int {
+		if a.ListPosition < b.ListPosition {
+			return -1
+		}
+		return 1
+	})
+	for i := 0; i < len(cdata.xmlin.EpochPlans); i++ {
+		n := &cdata.xmlin.EpochPlans[i]
+		if n.Draft {
+			cdata.db.Log.Message.Printf(
+				"Ignoring draft EpochPlan: %s\n", n.Name)
+			continue
+		}
+		e := db.NewEpochPlan(n.Id)
+		e.Name = n.Name
+		e.Tag = n.Shortcut
+
+		// Collect the weeks for each course.
+		courses := []Ref{}
+		weeks := map[Ref][]int{}
+		unknown := map[Ref]bool{}
+		for _, lref := range splitRefList(n.Lessons) {
+			l, ok := lessonMap[lref]
+			if !ok {
+				cdata.db.Log.Error.Fatalf("EpochPlan %s: Unknown Lesson %s\n",
+					n.Id, lref)
+			}
+			cref := l.Course
+			switch db.Elements[cref].(type) {
+			case *base.Course, *base.SubCourse:
+			default:
+				if !unknown[cref] {
+					cdata.db.Log.Warning.Printf("EpochPlan %s:\n"+
+						"  -- Course not a Course or SubCourse: %s\n",
+						n.Name, cref)
+					unknown[cref] = true
+				}
+				continue
+			}
+			wlist, ok := weeks[cref]
+			if !ok {
+				courses = append(courses, cref)
+			}
+			if !slices.Contains(wlist, l.Day) {
+				weeks[cref] = append(wlist, l.Day)
+			}
+		}
+		for _, cref := range courses {
+			wlist := weeks[cref]
+			slices.Sort(wlist)
+			e.Epochs = append(e.Epochs, base.Epoch{
+				Course: cref,
+				Weeks:  wlist,
+			})
+		}
+		slices.SortStableFunc(e.Epochs, func(a, b base.Epoch) int {
+			return a.Weeks[0] - b.Weeks[0]
+		})
+
+		// Link the blocks to the EpochPlan.
+		for _, cref := range courses {
+			sbc, ok := db.Elements[cref].(*base.SubCourse)
+			if !ok {
+				continue
+			}
+			for _, spcref := range sbc.SuperCourses {
+				spc := db.Elements[spcref].(*base.SuperCourse)
+				if spc.EpochPlan == "" {
+					spc.EpochPlan = e.Id
+				} else if spc.EpochPlan != e.Id {
+					cdata.db.Log.Warning.Printf("SuperCourse %s:\n"+
+						"  -- In more than one EpochPlan\n", spcref)
+				}
+			}
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"W365toFET/base"
 	"log"
 	"slices"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 //TODO: The Course field can be a Course or a SuperCourse.
@@ -13,9 +16,10 @@ import (
 // Non-scheduled lessons must be discovered (from the Courses and SuperCourses)
 // and then added.
 // Bear in mind that lessons have no length field, so multi-hour lessons are
-// made up of single lessons. It might be worth replacing these by real
-// multi-hour lessons? Of course, they must be reverted to W365 form to pass
-// them back , but this should be possible ...
+// made up of single lessons. These are replaced by real multi-hour lessons,
+// using the SplitHoursPerWeek lengths of the course. The Ids of the single
+// lessons are kept (Lesson.Parts), so that SplitLesson can convert a
+// placement back to W365 form.
 
 func (cdata *conversionData) makeLessons(scheduled []Ref) {
 	// Collect scheduled lessons.
@@ -42,13 +46,14 @@ func (cdata *conversionData) makeLessons(scheduled []Ref) {
 			if !ok {
 				_, ok = e.(*base.SuperCourse)
 				if !ok {
-					base.Error.Fatalf("Lesson %s has invalid Course\n", n.Id)
+					cdata.db.Log.Error.Fatalf(
+						"Lesson %s has invalid Course\n", n.Id)
 				}
 			}
 			lessons[cid] = append(lessons[cid], n)
 			continue
 		}
-		base.Error.Fatalf("Lesson %s has unknown Course\n", n.Id)
+		cdata.db.Log.Error.Fatalf("Lesson %s has unknown Course\n", n.Id)
 	}
 
 	// Generate base.Lessons for the courses, taking into account the
@@ -74,6 +79,7 @@ func (cdata *conversionData) makeLessons(scheduled []Ref) {
 			llen := llens[i]
 			day := -1
 			hour := -1
+			parts := []Ref{}
 			for j := 0; j < len(llist); j++ {
 				ll := llist[j]
 				d := ll.Day
@@ -90,6 +96,9 @@ func (cdata *conversionData) makeLessons(scheduled []Ref) {
 				// found
 				day = d
 				hour = h
+				for _, lx := range llist[j : j+llen] {
+					parts = append(parts, lx.Id)
+				}
 				// remove used Lessons
 				llist = slices.Delete(llist, j, j+llen)
 				break
@@ -103,6 +112,9 @@ func (cdata *conversionData) makeLessons(scheduled []Ref) {
 			l.Hour = hour
 			l.Fixed = day >= 0
 			l.Rooms = []Ref{}
+			if len(parts) != 0 {
+				l.Parts = parts
+			}
 		}
 		if len(llist) != 0 {
 			log.Fatalf("*ERROR* Didn't consume all lessons in course %s\n",
@@ -110,3 +122,42 @@ func (cdata *conversionData) makeLessons(scheduled []Ref) {
 		}
 	}
 }
+
+// SplitLesson converts a placed base.Lesson back to W365 single-hour
+// lessons. The Ids of the original XML lessons are reused, new Ids are
+// generated for any additional hours. An unplaced lesson has no W365 form.
+func SplitLesson(l *base.Lesson) []Lesson {
+	if l.Day < 0 {
+		return nil
+	}
+	lessons := []Lesson{}
+	for i := 0; i < l.Duration; i++ {
+		var id Ref
+		if i < len(l.Parts) {
+			id = l.Parts[i]
+		} else {
+			u, err := uuid.NewV4()
+			if err != nil {
+				base.Error.Fatalf("Failed to generate UUID: %v", err)
+			}
+			id = Ref(u.String())
+		}
+		lessons = append(lessons, Lesson{
+			Id:         id,
+			Course:     l.Course,
+			Day:        l.Day,
+			Hour:       l.Hour + i,
+			Fixed:      l.Fixed,
+			LocalRooms: RefList(strings.Join(refStrings(l.Rooms), ",")),
+		})
+	}
+	return lessons
+}
+
+func refStrings(rlist []Ref) []string {
+	slist := []string{}
+	for _, r := range rlist {
+		slist = append(slist, string(r))
+	}
+	return slist
+}
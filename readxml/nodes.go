@@ -60,7 +60,7 @@ type Teacher struct {
 	Firstname    string     `xml:",attr"`
 	Absences     RefList    `xml:",attr"`
 	Categories   RefList    `xml:",attr"`
-	//+	Color string  `xml:",attr"` // "#ffcc00"
+	Color        string     `xml:",attr"` // "#ffcc00"
 	//+	Gender int `xml:",attr"`
 	MinLessonsPerDay int `xml:",attr"`
 	MaxLessonsPerDay int `xml:",attr"`
@@ -79,7 +79,7 @@ type Subject struct {
 	ListPosition float32    `xml:",attr"`
 	Name         string     `xml:",attr"`
 	Shortcut     string     `xml:",attr"`
-	//+ Color string  `xml:",attr"` // "#ffcc00"
+	Color        string     `xml:",attr"` // "#ffcc00"
 }
 
 func (n *Subject) IdStr() w365tt.Ref {
@@ -123,7 +123,7 @@ type Class struct {
 	MaxLessonsPerDay int     `xml:",attr"`
 	MaxAfternoons    int     `xml:"NumberOfAfterNoonDays,attr"`
 	//+ ClassTeachers string `xml:"ClassTeacher,attr"`
-	//+ Color string  `xml:",attr"` // "#ffcc00"
+	Color string `xml:",attr"` // "#ffcc00"
 	//TODO: Implement in W365?
 	//+ MaxGapsPerWeek    int `xml:"MaxWindowsPerWeek,attr"`
 }
@@ -204,6 +204,23 @@ func (n *Lesson) IdStr() w365tt.Ref {
 	return n.Id
 }
 
+// An EpochPlan has its own Lessons, which are not in a Schedule. In these
+// the Day field is the school week (starting at 0), the Hour field is
+// not used.
+type EpochPlan struct {
+	Id           w365tt.Ref `xml:",attr"`
+	ListPosition float32    `xml:",attr"`
+	Name         string     `xml:",attr"`
+	Shortcut     string     `xml:",attr"`
+	Draft        bool       `xml:",attr"`
+	Groups       RefList    `xml:",attr"` // Classes
+	Lessons      RefList    `xml:",attr"`
+}
+
+func (n *EpochPlan) IdStr() w365tt.Ref {
+	return n.Id
+}
+
 type Category struct {
 	Id        w365tt.Ref `xml:",attr"`
 	Name      string     `xml:",attr"`
@@ -241,17 +258,18 @@ type Scenario struct { // Contains the main data to be processed.
 	Description string     `xml:"Decription,attr"` // sic
 
 	// Child nodes:
-	Days       []Day      `xml:"Day"`
-	Hours      []Hour     `xml:"TimedObject"`
-	Absences   []Absence  `xml:"Absence"`
-	Teachers   []Teacher  `xml:"Teacher"`
-	Subjects   []Subject  `xml:"Subject"`
-	Rooms      []Room     `xml:"Room"`
-	Classes    []Class    `xml:"Grade"`
-	Groups     []Group    `xml:"Group"`
-	Divisions  []Division `xml:"GradePartiton"`
-	Courses    []Course   `xml:"Course"`
-	Lessons    []Lesson   `xml:"Lesson"`
-	Schedules  []Schedule `xml:"Schedule"`
-	Categories []Category `xml:"Category"`
+	Days       []Day       `xml:"Day"`
+	Hours      []Hour      `xml:"TimedObject"`
+	Absences   []Absence   `xml:"Absence"`
+	Teachers   []Teacher   `xml:"Teacher"`
+	Subjects   []Subject   `xml:"Subject"`
+	Rooms      []Room      `xml:"Room"`
+	Classes    []Class     `xml:"Grade"`
+	Groups     []Group     `xml:"Group"`
+	Divisions  []Division  `xml:"GradePartiton"`
+	Courses    []Course    `xml:"Course"`
+	Lessons    []Lesson    `xml:"Lesson"`
+	Schedules  []Schedule  `xml:"Schedule"`
+	Categories []Category  `xml:"Category"`
+	EpochPlans []EpochPlan `xml:"EpochPlan"`
 }
@@ -102,6 +102,7 @@ func ConvertToDb(f365xml string) *conversionData {
 	cdata.readClasses() // also handles Groups
 	cdata.courseLessons = cdata.readCourses()
 	// courseLessons maps course ref -> list of lesson lengths
+	cdata.readEpochPlans()
 	return cdata
 }
 
@@ -155,7 +156,8 @@ func (cdata *conversionData) getAbsences(
 	for _, aref := range splitRefList(reflist) {
 		ts, ok := cdata.absences[aref]
 		if !ok {
-			base.Error.Fatalf("%s:\n  -- Invalid Absence: %s\n", msg, aref)
+			cdata.db.Log.Error.Fatalf(
+				"%s:\n  -- Invalid Absence: %s\n", msg, aref)
 		}
 		result = append(result, ts)
 	}
@@ -168,7 +170,7 @@ func (cdata *conversionData) getAbsences(
 				return -1
 			}
 			if a.Hour == b.Hour {
-				base.Error.Fatalf("%s:\n  -- Equal Absences\n", msg)
+				cdata.db.Log.Error.Fatalf("%s:\n  -- Equal Absences\n", msg)
 			}
 			return 1
 		}
@@ -244,6 +246,18 @@ func get_time(t string) string {
 	return fmt.Sprintf("%02d:%02d", h, m)
 }
 
+func (cdata *conversionData) xmlColour(c string) string {
+	// Check colour and return as "#rrggbb", empty if invalid
+	if c == "" {
+		return ""
+	}
+	if !base.ValidColour(c) {
+		cdata.db.Log.Warning.Printf("Invalid colour: %s\n", c)
+		return ""
+	}
+	return strings.ToLower(c)
+}
+
 func splitRefList(reflist RefList) []Ref {
 	result := []Ref{}
 	if reflist != "" {
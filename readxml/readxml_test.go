@@ -4,6 +4,7 @@ import (
 	"W365toFET/base"
 	"W365toFET/fet"
 	"W365toFET/ttbase"
+	"W365toFET/w365tt"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -90,3 +91,154 @@ func toFET(db *base.DbTopLevel, fetpath string) {
 	base.Message.Printf("Id-map written to: %s\n", mapfile)
 	base.Message.Println("OK")
 }
+
+func TestSplitLessons(t *testing.T) {
+	// Splitting the multi-hour lessons should reproduce the scheduled
+	// XML lessons.
+	base.OpenLog("")
+	nmulti := 0
+	for _, fxml := range inputfiles {
+		cdata := ConvertToDb(fxml)
+		slist := cdata.ScheduleNames()
+		if len(slist) == 0 {
+			continue
+		}
+		sname := SCHEDULE_NAME
+		if !slices.Contains(slist, sname) {
+			sname = slist[0]
+		}
+		cdata.ReadSchedule(sname)
+		xlessons := map[Ref]*Lesson{}
+		for i := 0; i < len(cdata.xmlin.Lessons); i++ {
+			n := &cdata.xmlin.Lessons[i]
+			xlessons[n.Id] = n
+		}
+		n := 0
+		for _, l := range cdata.db.Lessons {
+			split := SplitLesson(l)
+			if l.Day < 0 {
+				if split != nil {
+					t.Errorf("Lesson %s: unplaced, split gives %+v",
+						l.Id, split)
+				}
+				continue
+			}
+			// A placed lesson is made of XML lessons, one for each hour.
+			if len(split) != l.Duration || len(l.Parts) != l.Duration {
+				t.Errorf("Lesson %s: duration %d, %d parts, split into %d",
+					l.Id, l.Duration, len(l.Parts), len(split))
+				continue
+			}
+			if l.Duration > 1 {
+				nmulti++
+			}
+			for i, sl := range split {
+				xl, ok := xlessons[sl.Id]
+				if !ok || sl.Id != l.Parts[i] || sl.Course != l.Course ||
+					sl.Day != l.Day || sl.Hour != l.Hour+i {
+					t.Errorf("Lesson %s, hour %d: split gives %+v",
+						l.Id, i, sl)
+					continue
+				}
+				if xl.Course != sl.Course || xl.Day != sl.Day ||
+					xl.Hour != sl.Hour {
+					t.Errorf("Lesson %s: split gives %+v, XML %+v",
+						sl.Id, sl, xl)
+				}
+				n++
+			}
+		}
+		fmt.Printf("\n ***** %s: %d lessons split back *****\n", fxml, n)
+	}
+	if nmulti == 0 {
+		t.Errorf("No multi-hour lessons split")
+	}
+	fmt.Printf(" ***** %d multi-hour lessons *****\n", nmulti)
+}
+
+func TestEpochPlans(t *testing.T) {
+	// The EpochPlans (not the drafts) must be read with their courses and
+	// survive the export to W365 JSON and reloading.
+	base.OpenLog("")
+	fmt.Println("\n############## TestEpochPlans")
+	nplans := 0
+	for _, fxml := range inputfiles {
+		cdata := ConvertToDb(fxml)
+		db := cdata.db
+		n := 0
+		for _, e := range cdata.xmlin.EpochPlans {
+			if !e.Draft {
+				n++
+			}
+		}
+		if len(db.EpochPlans) != n {
+			t.Errorf("%s: %d EpochPlans, expected %d",
+				fxml, len(db.EpochPlans), n)
+		}
+		nplans += n
+		for _, e := range db.EpochPlans {
+			if len(e.Epochs) == 0 {
+				t.Errorf("EpochPlan %s: no epochs", e.Tag)
+			}
+			for _, epoch := range e.Epochs {
+				switch c := db.Elements[epoch.Course].(type) {
+				case *base.Course:
+				case *base.SubCourse:
+					// The blocks are linked to the plan
+					for _, spcref := range c.SuperCourses {
+						spc := db.Elements[spcref].(*base.SuperCourse)
+						if spc.EpochPlan != e.Id {
+							t.Errorf("EpochPlan %s: SuperCourse %s"+
+								" not linked", e.Tag, spcref)
+						}
+					}
+				default:
+					t.Errorf("EpochPlan %s: invalid course %s",
+						e.Tag, epoch.Course)
+				}
+				if len(epoch.Weeks) == 0 ||
+					!slices.IsSorted(epoch.Weeks) {
+					t.Errorf("EpochPlan %s, course %s: weeks %v",
+						e.Tag, epoch.Course, epoch.Weeks)
+				}
+			}
+			fmt.Printf("  -- EpochPlan %s: %d epochs\n", e.Tag, len(e.Epochs))
+		}
+
+		// Round trip through W365 JSON. The SubCourse Ids get a prefix
+		// when loaded.
+		fjson := filepath.Join(t.TempDir(), "epochs_w365.json")
+		if !w365tt.SaveJSON(db, fjson) {
+			t.Fatalf("%s: export failed", fxml)
+		}
+		db2 := base.NewDb()
+		w365tt.LoadJSON(db2, fjson)
+		if len(db2.EpochPlans) != len(db.EpochPlans) {
+			t.Errorf("%s: %d EpochPlans after reloading, expected %d",
+				fxml, len(db2.EpochPlans), len(db.EpochPlans))
+			continue
+		}
+		courseId := func(ref base.Ref) base.Ref {
+			return base.Ref(strings.TrimPrefix(string(ref), "$$"))
+		}
+		for i, e := range db.EpochPlans {
+			e2 := db2.EpochPlans[i]
+			if e2.Id != e.Id || e2.Tag != e.Tag || e2.Name != e.Name ||
+				len(e2.Epochs) != len(e.Epochs) {
+				t.Errorf("EpochPlan %s: reloaded as %+v", e.Tag, e2)
+				continue
+			}
+			for j, epoch := range e.Epochs {
+				epoch2 := e2.Epochs[j]
+				if courseId(epoch2.Course) != courseId(epoch.Course) ||
+					!slices.Equal(epoch2.Weeks, epoch.Weeks) {
+					t.Errorf("EpochPlan %s: epoch %+v reloaded as %+v",
+						e.Tag, epoch, epoch2)
+				}
+			}
+		}
+	}
+	if nplans == 0 {
+		t.Errorf("No EpochPlans read")
+	}
+}
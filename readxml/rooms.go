@@ -56,7 +56,7 @@ func (cdata *conversionData) checkRealRooms(
 				continue
 			}
 		}
-		base.Error.Fatalf("%s:\n  -- Invalid Room: %s\n", msg, ref)
+		cdata.db.Log.Error.Fatalf("%s:\n  -- Invalid Room: %s\n", msg, ref)
 	}
 	return result
 }
@@ -82,7 +82,7 @@ func (cdata *conversionData) getCourseRoom(c *Course) Ref {
 			_, ok = s.(*base.RoomGroup)
 			if ok {
 				if len(refs) != 1 {
-					base.Error.Fatalf("In Course %s:\n"+
+					cdata.db.Log.Error.Fatalf("In Course %s:\n"+
 						"  -- a RoomGroup must be the only item in the"+
 						" PreferredRooms list: %s\n",
 						c.Id, ref)
@@ -90,7 +90,7 @@ func (cdata *conversionData) getCourseRoom(c *Course) Ref {
 				return ref
 			}
 		}
-		base.Error.Fatalf("In Course %s:\n  -- Invalid Room: %s\n",
+		cdata.db.Log.Error.Fatalf("In Course %s:\n  -- Invalid Room: %s\n",
 			c.Id, ref)
 	}
 	if len(rlist) == 0 {
@@ -116,7 +116,7 @@ func (cdata *conversionData) makeRoomChoiceGroup(rooms []Ref) Ref {
 				continue
 			}
 		}
-		base.Bug.Fatalf("%s is not a (real) Room\n", rref)
+		cdata.db.Log.Bug.Fatalf("%s is not a (real) Room\n", rref)
 	}
 	name := strings.Join(taglist, ",")
 	// Reuse existing Element when the rooms match.
@@ -18,6 +18,7 @@ func (cdata *conversionData) readSubjects() {
 		e := cdata.db.NewSubject(n.Id)
 		e.Name = n.Name
 		e.Tag = n.Shortcut
+		e.Color = cdata.xmlColour(n.Color)
 		cdata.subjectTags[e.Tag] = e.Id
 	}
 }
@@ -32,7 +33,7 @@ func (cdata *conversionData) getCourseSubject(c *Course) Ref {
 	// Repeated use of the same subject list will reuse the created subject.
 	//
 	if c.Subjects == "" {
-		base.Error.Fatalf("In Course %s:\n  -- No Subject\n", c.Id)
+		cdata.db.Log.Error.Fatalf("In Course %s:\n  -- No Subject\n", c.Id)
 	}
 	slist := []Ref{}
 	for _, ref := range splitRefList(c.Subjects) {
@@ -44,7 +45,7 @@ func (cdata *conversionData) getCourseSubject(c *Course) Ref {
 				continue
 			}
 		}
-		base.Error.Fatalf("In Course %s:\n  -- Invalid Subject: %s\n",
+		cdata.db.Log.Error.Fatalf("In Course %s:\n  -- Invalid Subject: %s\n",
 			c.Id, ref)
 	}
 	if len(slist) == 1 {
@@ -61,7 +62,7 @@ func (cdata *conversionData) getCourseSubject(c *Course) Ref {
 				continue
 			}
 		}
-		base.Error.Fatalf("In Course %s:\n  -- Invalid Subject: %s\n",
+		cdata.db.Log.Error.Fatalf("In Course %s:\n  -- Invalid Subject: %s\n",
 			c.Id, sref)
 	}
 	sktag := strings.Join(sklist, ",")
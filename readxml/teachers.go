@@ -21,6 +21,7 @@ func (cdata *conversionData) readTeachers() {
 		e := db.NewTeacher(n.Id)
 		e.Name = n.Name
 		e.Tag = n.Shortcut
+		e.Color = cdata.xmlColour(n.Color)
 
 		notAvailable := cdata.getAbsences(n.Absences,
 			fmt.Sprintf("In Teacher %s (Absences)", n.Id))
@@ -77,7 +78,7 @@ func (cdata *conversionData) getCourseTeachers(c *Course) []Ref {
 				continue
 			}
 		}
-		base.Error.Fatalf("In Course %s:\n  -- Invalid Teacher: %s\n",
+		cdata.db.Log.Error.Fatalf("In Course %s:\n  -- Invalid Teacher: %s\n",
 			c.Id, ref)
 	}
 	return tlist
@@ -0,0 +1,324 @@
+// Package server provides the conversions and the printing as a local HTTP
+// service, so that they can be used without starting the programs and
+// exchanging files.
+//
+// All endpoints take POST requests with W365 JSON data:
+//
+//	/fet     – returns the FET file and the lesson map
+//	/typst   – returns the JSON data for the Typst scripts
+//	/pdf     – returns the PDF files (as a zip archive)
+//	/import  – takes a FET result and returns the W365 data with the
+//	           placements from FET
+//
+// Each request is handled with its own log (base.LogSet), which is
+// returned with the result. Errors which would end the command-line
+// programs only end the request (status 422, "Unprocessable Entity").
+package server
+
+import (
+	"W365toFET/base"
+	"W365toFET/fet"
+	"W365toFET/ttbase"
+	"W365toFET/ttprint"
+	"W365toFET/w365tt"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The maximum size of a request body
+const MAX_REQUEST_SIZE = 64 << 20
+
+// The name used for the generated files if none is given (parameter
+// "name")
+const DEFAULT_NAME = "timetable"
+
+type Config struct {
+	Typst     string // Typst executable
+	Templates string // folder with custom Typst scripts, can be empty
+	Workers   int    // number of parallel Typst runs (0: number of CPUs)
+}
+
+// NewHandler returns the handler for the endpoints of the service.
+func NewHandler(config Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /fet", handler(config, handleFet))
+	mux.Handle("POST /typst", handler(config, handleTypst))
+	mux.Handle("POST /pdf", handler(config, handlePdf))
+	mux.Handle("POST /import", handler(config, handleImport))
+	return mux
+}
+
+// A request being handled, with its own log.
+type request struct {
+	config Config
+	w      http.ResponseWriter
+	r      *http.Request
+	logbuf *bytes.Buffer
+	log    *base.LogSet
+}
+
+// A requestError is a problem with the request itself, not with the data.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e requestError) Error() string {
+	return e.msg
+}
+
+type handlerFunc func(rq *request) error
+
+func handler(config Config, f handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)
+		logbuf := &bytes.Buffer{}
+		rq := &request{config, w, r, logbuf, base.NewLogSet(logbuf, true)}
+		defer func() {
+			// Don't let a bug in one request stop the server
+			if p := recover(); p != nil {
+				rq.log.Bug.Printf("%v\n", p)
+				rq.writeError(http.StatusInternalServerError,
+					fmt.Sprintf("internal error: %v", p))
+			}
+		}()
+		var err error
+		if abort := base.CatchAbort(func() { err = f(rq) }); abort != nil {
+			rq.writeError(http.StatusUnprocessableEntity, abort.Error())
+			return
+		}
+		if err != nil {
+			if rerr, ok := err.(requestError); ok {
+				rq.writeError(rerr.status, rerr.msg)
+			} else {
+				rq.writeError(http.StatusUnprocessableEntity, err.Error())
+			}
+		}
+	})
+}
+
+func (rq *request) writeJSON(status int, v any) {
+	rq.w.Header().Set("Content-Type", "application/json")
+	rq.w.WriteHeader(status)
+	if err := json.NewEncoder(rq.w).Encode(v); err != nil {
+		rq.log.Error.Printf("Response not written: %v\n", err)
+	}
+}
+
+func (rq *request) writeError(status int, msg string) {
+	rq.writeJSON(status, map[string]string{
+		"error": strings.TrimSpace(msg),
+		"log":   rq.logbuf.String(),
+	})
+}
+
+// name returns the name for the generated files, from the parameter
+// "name".
+func (rq *request) name() (string, error) {
+	name := rq.r.FormValue("name")
+	if name == "" {
+		return DEFAULT_NAME, nil
+	}
+	if !regexp.MustCompile(`^[\w.-]+$`).MatchString(name) ||
+		strings.HasPrefix(name, ".") {
+		return "", requestError{http.StatusBadRequest,
+			"invalid name: " + name}
+	}
+	return name, nil
+}
+
+// loadDb reads the W365 data into a new db, using the request's log. With
+// a multipart form, the data is in the part "w365", otherwise it is the
+// request body.
+func (rq *request) loadDb() (*base.DbTopLevel, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(rq.r.Header.Get("Content-Type"), "multipart/") {
+		data, err = rq.formFile("w365")
+	} else {
+		data, err = io.ReadAll(rq.r.Body)
+	}
+	if err != nil {
+		return nil, requestError{http.StatusBadRequest, err.Error()}
+	}
+	db := base.NewDb()
+	db.Log = rq.log
+	w365tt.LoadJSONData(db, data)
+	db.PrepareDb()
+	return db, nil
+}
+
+func (rq *request) formFile(field string) ([]byte, error) {
+	f, _, err := rq.r.FormFile(field)
+	if err != nil {
+		return nil, fmt.Errorf("form field %s: %w", field, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ttInfo builds the timetable data, checking the placements.
+func ttInfo(db *base.DbTopLevel) *ttbase.TtInfo {
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+	return ttinfo
+}
+
+func handleFet(rq *request) error {
+	db, err := rq.loadDb()
+	if err != nil {
+		return err
+	}
+	xmlitem, lessonIdMap := fet.MakeFetFile(ttInfo(db))
+	rq.writeJSON(http.StatusOK, map[string]string{
+		"fet": xmlitem,
+		"map": lessonIdMap,
+		"log": rq.logbuf.String(),
+	})
+	return nil
+}
+
+// typstData generates the Typst input files for the request in the given
+// (temporary) folder. It returns the entries for the PDF files.
+func (rq *request) typstData(
+	datadir string,
+) ([]ttprint.TypstTable, error) {
+	// The body must be read before the parameters are parsed.
+	db, err := rq.loadDb()
+	if err != nil {
+		return nil, err
+	}
+	name, err := rq.name()
+	if err != nil {
+		return nil, err
+	}
+	return ttprint.GenTypstData(ttInfo(db), datadir, name), nil
+}
+
+func handleTypst(rq *request) error {
+	datadir, err := os.MkdirTemp("", "w365serve")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(datadir)
+	tfiles, err := rq.typstData(datadir)
+	if err != nil {
+		return err
+	}
+	files := map[string]json.RawMessage{}
+	for _, tt := range tfiles {
+		t := tt.Data
+		if _, ok := files[t]; ok {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(datadir, "_data", t+".json"))
+		if err != nil {
+			return err
+		}
+		files[t] = b
+	}
+	rq.writeJSON(http.StatusOK, map[string]any{
+		"files":  files,
+		"tables": ttprint.Names(tfiles),
+		"log":    rq.logbuf.String(),
+	})
+	return nil
+}
+
+func handlePdf(rq *request) error {
+	datadir, err := os.MkdirTemp("", "w365serve")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(datadir)
+	tfiles, err := rq.typstData(datadir)
+	if err != nil {
+		return err
+	}
+	// Only selected tables (parameter "table", can be repeated)?
+	if tables := rq.r.Form["table"]; len(tables) != 0 {
+		selected := []ttprint.TypstTable{}
+		for _, t := range tables {
+			i := slices.IndexFunc(tfiles, func(tt ttprint.TypstTable) bool {
+				return tt.Name == t
+			})
+			if i < 0 {
+				return requestError{http.StatusBadRequest,
+					"unknown table: " + t}
+			}
+			selected = append(selected, tfiles[i])
+		}
+		tfiles = selected
+	}
+	ttprint.MakePdfs(rq.log, tfiles, datadir, rq.config.Templates,
+		rq.config.Typst, rq.config.Workers, true)
+
+	// The PDF files and the log are returned in a zip archive.
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, tfile := range ttprint.Names(tfiles) {
+		b, err := os.ReadFile(filepath.Join(datadir, "_pdf", tfile+".pdf"))
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(zw, tfile+".pdf", b); err != nil {
+			return err
+		}
+	}
+	err = writeZipFile(zw, "log.txt", rq.logbuf.Bytes())
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return err
+	}
+	rq.w.Header().Set("Content-Type", "application/zip")
+	rq.w.WriteHeader(http.StatusOK)
+	rq.w.Write(buf.Bytes())
+	return nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	return err
+}
+
+// handleImport takes a multipart form with the W365 data ("w365"), the
+// lesson map ("map") and the FET activities file ("activities"). The W365
+// data is returned with the placements from FET.
+func handleImport(rq *request) error {
+	db, err := rq.loadDb()
+	if err != nil {
+		return err
+	}
+	mapdata, err := rq.formFile("map")
+	if err != nil {
+		return requestError{http.StatusBadRequest, err.Error()}
+	}
+	xmldata, err := rq.formFile("activities")
+	if err != nil {
+		return requestError{http.StatusBadRequest, err.Error()}
+	}
+	if err := fet.ApplyPlacementData(db, mapdata, xmldata); err != nil {
+		return err
+	}
+	// Check the placements, invalid ones are removed (with a warning).
+	fet.DropInvalidPlacements(ttInfo(db))
+	rq.writeJSON(http.StatusOK, map[string]any{
+		"w365": w365tt.ToW365(db),
+		"log":  rq.logbuf.String(),
+	})
+	return nil
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"W365toFET/base"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const inputfile = "../testdata/Versuch_D_Margin_hour_constraint_w365.json"
+
+func post(
+	t *testing.T,
+	ts *httptest.Server,
+	path string,
+	ctype string,
+	body []byte,
+) (int, []byte) {
+	resp, err := http.Post(ts.URL+path, ctype, bytes.NewReader(body))
+	if err != nil {
+		// Can be called in a goroutine, so no t.Fatal
+		t.Error(err)
+		return 0, nil
+	}
+	defer resp.Body.Close()
+	buf := &bytes.Buffer{}
+	buf.ReadFrom(resp.Body)
+	return resp.StatusCode, buf.Bytes()
+}
+
+func TestServer(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestServer")
+	input, err := os.ReadFile(inputfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A fake Typst, which just writes the output file.
+	tmp := t.TempDir()
+	typst := filepath.Join(tmp, "typst")
+	err = os.WriteFile(typst, []byte("#!/bin/sh\n"+
+		"for a; do out=\"$a\"; done\necho PDF > \"$out\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(NewHandler(Config{Typst: typst}))
+	defer ts.Close()
+
+	// Several requests at the same time, some with invalid data. Each
+	// must get its own log.
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := input
+			if i%2 == 1 {
+				data = []byte(`{"days": 1}`)
+			}
+			status, body := post(t, ts, "/fet", "application/json", data)
+			result := map[string]string{}
+			if err := json.Unmarshal(body, &result); err != nil {
+				t.Errorf("Request %d: %v", i, err)
+				return
+			}
+			if i%2 == 1 {
+				if status != http.StatusUnprocessableEntity ||
+					result["error"] == "" {
+					t.Errorf("Request %d: status %d, %s", i, status, body)
+				}
+				return
+			}
+			if status != http.StatusOK ||
+				!strings.Contains(result["fet"], "<fet") ||
+				result["map"] == "" {
+				t.Errorf("Request %d: status %d", i, status)
+			}
+			if strings.Contains(result["log"], "unmarshal") {
+				t.Errorf("Request %d: log of another request:\n%s",
+					i, result["log"])
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Typst data
+	status, body := post(t, ts, "/typst?name=test", "application/json", input)
+	result := struct {
+		Files  map[string]json.RawMessage
+		Tables []string
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil || status != 200 {
+		t.Fatalf("/typst: status %d, %v", status, err)
+	}
+	fmt.Printf("  -- /typst: %v\n", result.Tables)
+	if _, ok := result.Files["test_teachers"]; !ok {
+		t.Errorf("/typst: no teacher tables")
+	}
+	status, _ = post(t, ts, "/typst?name=../x", "application/json", input)
+	if status != http.StatusBadRequest {
+		t.Errorf("/typst: invalid name accepted")
+	}
+
+	// PDF files
+	status, body = post(t, ts, "/pdf?name=test&table=test_classes",
+		"application/json", input)
+	if status != http.StatusOK {
+		t.Fatalf("/pdf: status %d, %s", status, body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	fmt.Printf("  -- /pdf: %v\n", names)
+	if len(names) != 2 || names[0] != "test_classes.pdf" {
+		t.Errorf("/pdf: %v", names)
+	}
+	testImport(t, ts, input)
+}
+
+// testImport takes the placements of the fixed lessons as "FET result".
+func testImport(t *testing.T, ts *httptest.Server, input []byte) {
+	status, body := post(t, ts, "/fet", "application/json", input)
+	result := map[string]string{}
+	if err := json.Unmarshal(body, &result); err != nil || status != 200 {
+		t.Fatalf("/fet: status %d, %v", status, err)
+	}
+	type lesson struct {
+		Id    string
+		Day   int
+		Hour  int
+		Fixed bool
+	}
+	w365 := struct{ Lessons []lesson }{}
+	if err := json.Unmarshal(input, &w365); err != nil {
+		t.Fatal(err)
+	}
+	lessons := map[string]lesson{}
+	for _, l := range w365.Lessons {
+		lessons[l.Id] = l
+	}
+	xml := []string{"<Activities_Timetable>"}
+	nfixed := 0
+	for _, line := range strings.Split(result["map"], "\n") {
+		aid, lid, _ := strings.Cut(line, ":")
+		if l := lessons[lid]; l.Fixed {
+			nfixed++
+			xml = append(xml, fmt.Sprintf("<Activity><Id>%s</Id>"+
+				"<Day>%d</Day><Hour>%d</Hour></Activity>", aid, l.Day, l.Hour))
+		}
+	}
+	xml = append(xml, "</Activities_Timetable>")
+
+	form := &bytes.Buffer{}
+	mw := multipart.NewWriter(form)
+	for field, data := range map[string]string{
+		"w365":       string(input),
+		"map":        result["map"],
+		"activities": strings.Join(xml, "\n"),
+	} {
+		fw, err := mw.CreateFormFile(field, field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(data))
+	}
+	mw.Close()
+	status, body = post(t, ts, "/import", mw.FormDataContentType(),
+		form.Bytes())
+	imported := struct{ W365 struct{ Lessons []lesson } }{}
+	if err := json.Unmarshal(body, &imported); err != nil || status != 200 {
+		t.Fatalf("/import: status %d, %v\n%s", status, err, body)
+	}
+	placed := 0
+	for _, l := range imported.W365.Lessons {
+		if l.Day < 0 {
+			continue
+		}
+		placed++
+		l0 := lessons[l.Id]
+		if l.Day != l0.Day || l.Hour != l0.Hour {
+			t.Errorf("/import: lesson %s at %d.%d, expected %d.%d",
+				l.Id, l.Day, l.Hour, l0.Day, l0.Hour)
+		}
+	}
+	fmt.Printf("  -- /import: %d lessons placed\n", placed)
+	if placed != nfixed {
+		t.Errorf("/import: %d lessons placed, expected %d", placed, nfixed)
+	}
+}
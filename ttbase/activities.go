@@ -14,7 +14,7 @@ type Activity struct {
 	Index     ActivityIndex
 	Duration  int
 	Resources []ResourceIndex
-	XRooms        []ResourceIndex // for room choices
+	XRooms    []ResourceIndex // for room choices
 	// ExtendedGroups is a list of atomic group indexes for those groups
 	// in the activity's class(es) which are NOT involved in the activity.
 	ExtendedGroups []ResourceIndex
@@ -104,7 +104,7 @@ func (ttinfo *TtInfo) addActivityInfo(
 				// Check for repetitions
 				if slices.Contains(resources, agix) {
 					if !slices.Contains(warned, cinfo) {
-						base.Warning.Printf(
+						ttinfo.Db.Log.Warning.Printf(
 							"Lesson with repeated atomic group"+
 								" in Course: %s\n", ttinfo.View(cinfo))
 						warned = append(warned, cinfo)
@@ -146,13 +146,13 @@ func (ttinfo *TtInfo) addActivityInfo(
 			if rchoices[rref] {
 				a.XRooms = append(a.XRooms, r2tt[rref])
 			} else {
-				base.Error.Printf("Room (%s) used for lesson of"+
+				ttinfo.Db.Log.Error.Printf("Room (%s) used for lesson of"+
 					" course %s:\n  Room not specified for course.\n",
 					ttinfo.Ref2Tag[rref], ttinfo.View(cinfo))
 			}
 		}
 		if len(a.XRooms) > nrooms {
-			base.Warning.Printf("Lesson in course %s uses more rooms"+
+			ttinfo.Db.Log.Warning.Printf("Lesson in course %s uses more rooms"+
 				" than specified for course.\n", ttinfo.View(cinfo))
 		}
 
@@ -191,13 +191,14 @@ func (ttinfo *TtInfo) addActivityInfo(
 		p := a.Placement
 		if a.Fixed {
 			if p < 0 {
-				base.Bug.Fatalf("Fixed activity with no time slot: %d\n", aix)
+				ttinfo.Db.Log.Bug.Fatalf(
+					"Fixed activity with no time slot: %d\n", aix)
 			}
 			for _, paix := range a.Parallel {
 				pa := ttinfo.Activities[paix]
 				pp := pa.Placement
 				if pa.Fixed {
-					base.Warning.Printf("Parallel fixed lessons:\n"+
+					ttinfo.Db.Log.Warning.Printf("Parallel fixed lessons:\n"+
 						"  -- %d: %s\n  -- %d: %s\n",
 						aix,
 						ttinfo.View(ttinfo.Activities[aix].CourseInfo),
@@ -205,14 +206,15 @@ func (ttinfo *TtInfo) addActivityInfo(
 						ttinfo.View(ttinfo.Activities[paix].CourseInfo),
 					)
 					if pp != p {
-						base.Error.Fatalln("Parallel fixed lessons have" +
-							" different times")
+						ttinfo.Db.Log.Error.Fatalln(
+							"Parallel fixed lessons have different times")
 					}
 				} else {
 					if pp != p {
 						if pp >= 0 {
-							base.Warning.Printf("Parallel lessons with"+
-								" different times:\n  -- %d: %s\n  -- %d: %s\n",
+							ttinfo.Db.Log.Warning.Printf(
+								"Parallel lessons with different times:\n"+
+									"  -- %d: %s\n  -- %d: %s\n",
 								aix,
 								ttinfo.View(ttinfo.Activities[aix].CourseInfo),
 								paix,
@@ -233,8 +235,9 @@ func (ttinfo *TtInfo) addActivityInfo(
 				pp := pa.Placement
 				if pp >= 0 && pp != p {
 					// Warn and set ALL to -1
-					base.Warning.Printf("Parallel lessons with different"+
-						" times (placements revoked):\n  -- %d: %s\n",
+					ttinfo.Db.Log.Warning.Printf(
+						"Parallel lessons with different"+
+							" times (placements revoked):\n  -- %d: %s\n",
 						aix,
 						ttinfo.View(ttinfo.Activities[aix].CourseInfo))
 					a.Placement = -1
@@ -266,7 +269,7 @@ func (ttinfo *TtInfo) addActivityInfo(
 				// Check for end-of-day problems when duration > 1
 				h := p % ttinfo.NHours
 				if h+a.Duration > ttinfo.NHours {
-					base.Error.Fatalf(
+					ttinfo.Db.Log.Error.Fatalf(
 						"Placement for Fixed Activity %d @ %d invalid:\n"+
 							"  -- %s\n",
 						aix, p, ttinfo.View(a.CourseInfo))
@@ -280,7 +283,7 @@ func (ttinfo *TtInfo) addActivityInfo(
 						placed[paix] = true
 					}
 				} else {
-					base.Error.Fatalf(
+					ttinfo.Db.Log.Error.Fatalf(
 						"Placement of Fixed Activity %d @ %d failed:\n"+
 							"  -- %s\n",
 						aix, p, ttinfo.View(a.CourseInfo))
@@ -316,7 +319,7 @@ func (ttinfo *TtInfo) addActivityInfo(
 			ttl := ttinfo.Activities[aix-1]
 			cinfo := ttl.CourseInfo
 			//
-			base.Warning.Printf(
+			ttinfo.Db.Log.Warning.Printf(
 				"Placement of Activity %d @ %d failed:\n"+
 					"  -- %s\n",
 				aix, p, ttinfo.View(cinfo))
@@ -336,7 +339,7 @@ func (ttinfo *TtInfo) addActivityInfo(
 				if ttinfo.TtSlots[slot] == 0 {
 					ttinfo.TtSlots[slot] = aix
 				} else {
-					base.Warning.Printf(
+					ttinfo.Db.Log.Warning.Printf(
 						"Lesson in course %s cannot use room %s\n",
 						ttinfo.View(a.CourseInfo),
 						ttinfo.Resources[rix].(*base.Room).Tag)
@@ -410,10 +413,10 @@ func (ttinfo *TtInfo) UnplaceActivity(aix ActivityIndex) {
 
 	//TODO--- for testing
 	if a.Fixed {
-		base.Bug.Fatalf("Can't unplace %d – fixed\n", aix)
+		ttinfo.Db.Log.Bug.Fatalf("Can't unplace %d – fixed\n", aix)
 	}
 	if slot < 0 {
-		base.Bug.Printf("Can't unplace %d – not placed\n", aix)
+		ttinfo.Db.Log.Bug.Printf("Can't unplace %d – not placed\n", aix)
 		panic(1)
 		return
 	}
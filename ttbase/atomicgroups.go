@@ -25,7 +25,7 @@ func (ttinfo *TtInfo) makeAtomicGroups() {
 	for _, cl := range ttinfo.Db.Classes {
 		divs, ok := ttinfo.ClassDivisions[cl.Id]
 		if !ok {
-			base.Bug.Fatalf("ttinfo.classDivisions[%s]\n", cl.Id)
+			ttinfo.Db.Log.Bug.Fatalf("ttinfo.classDivisions[%s]\n", cl.Id)
 		}
 
 		if len(divs) == 0 {
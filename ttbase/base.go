@@ -168,7 +168,7 @@ func (ttinfo *TtInfo) PrepareCoreData() {
 	i := 0
 	for _, ag := range ags {
 		if ag.Index != i {
-			base.Bug.Fatalf("Atomic group index != resource index:\n"+
+			ttinfo.Db.Log.Bug.Fatalf("Atomic group index != resource index:\n"+
 				"  -- %d: %+v\n", i, ag)
 		}
 		ttinfo.Resources[i] = ag
@@ -42,7 +42,7 @@ func (ttinfo *TtInfo) processConstraints() {
 					diffDays.consecutiveIfSameDay = cn.ConsecutiveIfSameDay
 					diffDays.daysBetween = map[Ref][]*base.DaysBetween{}
 				} else {
-					base.Bug.Fatalln(
+					ttinfo.Db.Log.Bug.Fatalln(
 						"More than one AutomaticDifferentDays constraint")
 				}
 				continue
@@ -109,8 +109,8 @@ func (ttinfo *TtInfo) processConstraints() {
 						for _, cr := range cn.Courses {
 							clist = append(clist, string(cr))
 						}
-						base.Error.Fatalf("Parallel courses have different"+
-							" lessons: %s\n",
+						ttinfo.Db.Log.Error.Fatalf(
+							"Parallel courses have different lessons: %s\n",
 							strings.Join(clist, ","))
 					}
 					for j, lix := range cinfo.Lessons {
@@ -122,8 +122,8 @@ func (ttinfo *TtInfo) processConstraints() {
 							for _, cr := range cn.Courses {
 								clist = append(clist, string(cr))
 							}
-							base.Error.Fatalf("Parallel courses have lesson"+
-								" mismatch: %s\n",
+							ttinfo.Db.Log.Error.Fatalf(
+								"Parallel courses have lesson mismatch: %s\n",
 								strings.Join(clist, ","))
 						}
 						llists[j] = append(llists[j], lix)
@@ -163,8 +163,9 @@ func (ttinfo *TtInfo) processConstraints() {
 		if len(unfixeds) == 0 || (len(fixeds) == 0 && len(unfixeds) == 1) {
 			// No constraints necessary
 			if ddcsok {
-				base.Warning.Printf("Superfluous DaysBetween constraint on"+
-					" course:\n  -- %s", ttinfo.View(cinfo))
+				ttinfo.Db.Log.Warning.Printf(
+					"Superfluous DaysBetween constraint on"+
+						" course:\n  -- %s", ttinfo.View(cinfo))
 			}
 			continue
 		}
@@ -185,8 +186,9 @@ func (ttinfo *TtInfo) processConstraints() {
 			// Add default constraint
 			for _, alist := range aidlists {
 				if len(alist) > ttinfo.NDays {
-					base.Warning.Printf("Course has too many lessons for"+
-						"DifferentDays constraint:\n  -- %s\n",
+					ttinfo.Db.Log.Warning.Printf(
+						"Course has too many lessons for"+
+							"DifferentDays constraint:\n  -- %s\n",
 						ttinfo.View(cinfo))
 					continue
 				}
@@ -205,8 +207,9 @@ func (ttinfo *TtInfo) processConstraints() {
 				if ddc.Weight != 0 {
 					for _, alist := range aidlists {
 						if (len(alist)-1)*ddc.DaysBetween >= ttinfo.NDays {
-							base.Warning.Printf("Course has too many lessons"+
-								" for DaysBetween constraint:\n  -- %s\n",
+							ttinfo.Db.Log.Warning.Printf(
+								"Course has too many lessons"+
+									" for DaysBetween constraint:\n  -- %s\n",
 								ttinfo.View(cinfo))
 							continue
 						}
@@ -26,7 +26,7 @@ func (ttinfo *TtInfo) View(cinfo *CourseInfo) string {
 	for _, g := range cinfo.Groups {
 		gx, ok := ttinfo.Ref2Tag[g]
 		if !ok {
-			base.Bug.Fatalf("No Ref2Tag for %s\n", g)
+			ttinfo.Db.Log.Bug.Fatalf("No Ref2Tag for %s\n", g)
 		}
 		glist = append(glist, gx)
 	}
@@ -74,7 +74,7 @@ func gatherCourseInfo(ttinfo *TtInfo) {
 				} else {
 					rc, ok := rx.(*base.RoomChoiceGroup)
 					if !ok {
-						base.Bug.Fatalf(
+						ttinfo.Db.Log.Bug.Fatalf(
 							"Invalid room in course %s:\n  %s\n",
 							cref, rref)
 					}
@@ -124,7 +124,7 @@ func (ttinfo *TtInfo) checkAllocatedRooms(cinfo *CourseInfo) {
 			for _, rref := range lrooms {
 				rlist = append(rlist, ttinfo.Ref2Tag[rref])
 			}
-			base.Warning.Printf("Lesson in Course %s has wrong number"+
+			ttinfo.Db.Log.Warning.Printf("Lesson in Course %s has wrong number"+
 				" of rooms allocated:\n  -- %+v (expected %d)\n",
 				cinfo.Id, rlist, len(vr.Rooms)+len(vr.RoomChoices))
 			return
@@ -138,7 +138,8 @@ func (ttinfo *TtInfo) checkAllocatedRooms(cinfo *CourseInfo) {
 			if lrmap[rref] {
 				delete(lrmap, rref)
 			} else {
-				base.Warning.Printf("Lesson in Course %s needs room %s\n",
+				ttinfo.Db.Log.Warning.Printf(
+					"Lesson in Course %s needs room %s\n",
 					cinfo.Id, ttinfo.Ref2Tag[rref])
 				return
 			}
@@ -166,7 +167,7 @@ func (ttinfo *TtInfo) checkAllocatedRooms(cinfo *CourseInfo) {
 			for rref := range lrmap {
 				rlist = append(rlist, ttinfo.Ref2Tag[rref])
 			}
-			base.Warning.Printf("Lesson in Course %s has invalid"+
+			ttinfo.Db.Log.Warning.Printf("Lesson in Course %s has invalid"+
 				" room-choice allocations: %+v\n",
 				cinfo.Id, rlist)
 			return
@@ -0,0 +1,84 @@
+package ttbase
+
+import (
+	"W365toFET/base"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Finding free rooms in a placed timetable, using the room slots in
+// TtSlots. A slot is free if there is no activity in it and the room is
+// not blocked there (NotAvailable). PrepareCoreData must have been called.
+
+// FreeRooms returns the (real) rooms which are free at the given time.
+// If "within" is not empty, only the rooms of this RoomGroup or
+// RoomChoiceGroup (or this Room) are considered.
+func (ttinfo *TtInfo) FreeRooms(day int, hour int, within Ref) ([]Ref, error) {
+	if day < 0 || day >= ttinfo.NDays || hour < 0 || hour >= ttinfo.NHours {
+		return nil, fmt.Errorf("invalid time slot: day %d, hour %d",
+			day, hour)
+	}
+	rooms, err := ttinfo.roomResources(within)
+	if err != nil {
+		return nil, err
+	}
+	return ttinfo.freeRooms(rooms, day*ttinfo.NHours+hour), nil
+}
+
+// FreeRoomTable returns the free rooms for each time slot (day * NHours +
+// hour). The rooms can be restricted as for FreeRooms.
+func (ttinfo *TtInfo) FreeRoomTable(within Ref) ([][]Ref, error) {
+	rooms, err := ttinfo.roomResources(within)
+	if err != nil {
+		return nil, err
+	}
+	table := make([][]Ref, ttinfo.SlotsPerWeek)
+	for p := 0; p < ttinfo.SlotsPerWeek; p++ {
+		table[p] = ttinfo.freeRooms(rooms, p)
+	}
+	return table, nil
+}
+
+func (ttinfo *TtInfo) freeRooms(rooms []ResourceIndex, slot int) []Ref {
+	free := []Ref{}
+	for _, rix := range rooms {
+		if ttinfo.TtSlots[rix*ttinfo.SlotsPerWeek+slot] == 0 {
+			free = append(free, ttinfo.Resources[rix].(*base.Room).Id)
+		}
+	}
+	return free
+}
+
+// roomResources returns the resource indexes of the rooms to consider, in
+// the order of the rooms in the database.
+func (ttinfo *TtInfo) roomResources(within Ref) ([]ResourceIndex, error) {
+	if len(ttinfo.TtSlots) == 0 {
+		return nil, errors.New("no time slots, PrepareCoreData not called")
+	}
+	var filter []Ref
+	if within != "" {
+		switch r := ttinfo.Db.Elements[within].(type) {
+		case *base.Room:
+			filter = []Ref{r.Id}
+		case *base.RoomGroup:
+			filter = r.Rooms
+		case *base.RoomChoiceGroup:
+			filter = r.Rooms
+		default:
+			return nil, fmt.Errorf("not a room or room group: %s", within)
+		}
+	}
+	rooms := []ResourceIndex{}
+	for rix := ttinfo.NAtomicGroups; rix < len(ttinfo.Resources); rix++ {
+		r, ok := ttinfo.Resources[rix].(*base.Room)
+		if !ok {
+			continue
+		}
+		if filter != nil && !slices.Contains(filter, r.Id) {
+			continue
+		}
+		rooms = append(rooms, rix)
+	}
+	return rooms, nil
+}
@@ -1,9 +1,5 @@
 package ttbase
 
-import (
-	"W365toFET/base"
-)
-
 // By trying all slots for all (non-fixed) activities just after the fixed
 // activities have been placed, each activity can get a list of potentially
 // available slots.
@@ -25,8 +21,8 @@ func (ttinfo *TtInfo) makePossibleSlots() {
 			}
 		}
 		if len(plist) == 0 {
-			base.Error.Fatalf("Activity %d has no available time slots\n"+
-				"  -- Course: %s\n",
+			ttinfo.Db.Log.Error.Fatalf(
+				"Activity %d has no available time slots\n  -- Course: %s\n",
 				aix, ttinfo.View(a.CourseInfo))
 		}
 		a.PossibleSlots = plist
@@ -0,0 +1,128 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Tile colours. The background of a tile can come from the lesson (its
+// "Background" field, the default) or from the subject, a teacher or the
+// class. This is chosen in the print options by "ColorScheme":
+//
+//	"lesson"  – the lesson's background (default)
+//	"subject" – the subject's colour
+//	"teacher" – the colour of the (first) teacher
+//	"class"   – the colour of the (first) class
+//	"none"    – no background colours
+//
+// If the chosen element has no colour, the lesson's background is used.
+// The text colour (black or white) is chosen to contrast with the
+// background.
+
+const (
+	COLOURS_LESSON  = "lesson"
+	COLOURS_SUBJECT = "subject"
+	COLOURS_TEACHER = "teacher"
+	COLOURS_CLASS   = "class"
+	COLOURS_NONE    = "none"
+)
+
+type colourScheme struct {
+	db     *base.DbTopLevel
+	scheme string
+}
+
+func newColourScheme(db *base.DbTopLevel) colourScheme {
+	// The scheme has been checked in ParsePrintOptions
+	return colourScheme{db, printOptions(db).ColorScheme}
+}
+
+// background returns the background colour for a tile of the given lesson.
+// The teachers and classes are tried in the given order, so the teacher
+// or class of the page should be first.
+func (cs colourScheme) background(
+	l *base.Lesson,
+	subject base.Ref,
+	teachers []base.Ref,
+	classes []base.Ref,
+) string {
+	colour := ""
+	switch cs.scheme {
+	case COLOURS_NONE:
+		return ""
+	case COLOURS_SUBJECT:
+		if s, ok := cs.db.Elements[subject].(*base.Subject); ok {
+			colour = s.Color
+		}
+	case COLOURS_TEACHER:
+		for _, tref := range teachers {
+			if t, ok := cs.db.Elements[tref].(*base.Teacher); ok &&
+				t.Color != "" {
+				colour = t.Color
+				break
+			}
+		}
+	case COLOURS_CLASS:
+		for _, cref := range classes {
+			if c, ok := cs.db.Elements[cref].(*base.Class); ok &&
+				c.Color != "" {
+				colour = c.Color
+				break
+			}
+		}
+	}
+	if colour == "" {
+		return l.Background
+	}
+	return colour
+}
+
+// groupClasses returns the classes of the given groups, without repeats.
+func groupClasses(db *base.DbTopLevel, groups []base.Ref) []base.Ref {
+	classes := []base.Ref{}
+	for _, gref := range groups {
+		if c, err := db.GroupClass(gref); err == nil {
+			if !slices.Contains(classes, c.Id) {
+				classes = append(classes, c.Id)
+			}
+		}
+	}
+	return classes
+}
+
+// setForegrounds sets the text colour of all tiles to contrast with their
+// backgrounds.
+func setForegrounds(db *base.DbTopLevel, pages []ttPage) {
+	for _, p := range pages {
+		for i, tile := range p.Activities {
+			if tile.Background == "" {
+				continue
+			}
+			fg, err := contrastColour(tile.Background)
+			if err != nil {
+				db.Log.Warning.Printf("Tile background: %v\n", err)
+				p.Activities[i].Background = ""
+				continue
+			}
+			p.Activities[i].Foreground = fg
+		}
+	}
+}
+
+// contrastColour returns black or white, whichever is more readable on
+// the given background colour, which must be of the form "#RRGGBB". The
+// brightness is the Rec. 601 luma of the (gamma-encoded) RGB values, not
+// the relative luminance of WCAG.
+func contrastColour(background string) (string, error) {
+	if !base.ValidColour(background) {
+		return "", fmt.Errorf("invalid colour: %s", background)
+	}
+	rgb, _ := strconv.ParseUint(background[1:], 16, 32)
+	r, g, b := rgb>>16, (rgb>>8)&0xff, rgb&0xff
+	if 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) < 0.55*255 {
+		return "#ffffff", nil
+	}
+	return "#000000", nil
+}
@@ -5,9 +5,11 @@ import (
 	"W365toFET/ttbase"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -23,6 +25,7 @@ type Tile struct {
 	Teachers   []string `json:"teachers,omitempty"`
 	Rooms      []string `json:"rooms,omitempty"`
 	Background string   `json:"background,omitempty"`
+	Foreground string   `json:"foreground,omitempty"`
 }
 
 type Timetable struct {
@@ -48,51 +51,107 @@ type ttPage struct {
 	Name       string
 	Short      string
 	Activities []Tile
+	Legend     ttLegend
+}
+
+// The legend of a page lists the full names of the elements used on it.
+type ttLegend struct {
+	Subjects []LegendItem `json:",omitempty"`
+	Teachers []LegendItem `json:",omitempty"`
+	Rooms    []LegendItem `json:",omitempty"`
+	Groups   []LegendItem `json:",omitempty"`
+}
+
+type LegendItem struct {
+	Short string
+	Name  string
+}
+
+// A TypstTable is an entry in the list returned by GenTypstData: a PDF
+// file to be made by a Typst script from a JSON file (in "_data").
+type TypstTable struct {
+	Name   string // PDF file name (without extension)
+	Data   string // JSON file name (without extension)
+	Script string // e.g. "print_timetable.typ"
 }
 
 func GenTypstData(
 	ttinfo *ttbase.TtInfo,
 	datadir string,
 	stemfile string,
-) []string {
-	typst_files := []string{}
-	printTables := ttinfo.Db.PrintOptions.PrintTables
-	if len(printTables) == 0 {
-		printTables = []string{
-			"Class", "Teacher", "Room",
-			"Class_overview", "Teacher_overview", "Room_overview",
+) []TypstTable {
+	typst_files := []TypstTable{}
+	opts, err := ParsePrintOptions(ttinfo.Db)
+	if err != nil {
+		for _, e := range strings.Split(err.Error(), "\n") {
+			ttinfo.Db.Log.Error.Printf("Print options – %s\n", e)
 		}
 	}
+	for _, w := range opts.Warnings {
+		ttinfo.Db.Log.Warning.Printf("Print options – %s\n", w)
+	}
+	for key := range opts.Other {
+		ttinfo.Db.Log.Warning.Printf("Typst option %q unknown to W365toTypst,"+
+			" passed on to the script\n", key)
+	}
 	// The same JSON is used for overview tables as for individual tables,
 	// so suppress generation of doubles.
-	done := map[string]string{}
-	var f string
-	var ok bool
-	for _, ptable := range printTables {
-		p, overview := strings.CutSuffix(ptable, "_overview")
-		f, ok = done[p]
+	done := map[PrintTable]string{}
+	for _, pt := range opts.Tables {
+		overview := pt.Overview
+		pt.Overview = false
+		f, ok := done[pt]
 		if !ok {
-			switch p {
+			switch pt.Type {
 			case "Class":
 				f = getClasses(ttinfo, datadir, stemfile)
 			case "Teacher":
 				f = getTeachers(ttinfo, datadir, stemfile)
 			case "Room":
 				f = getRooms(ttinfo, datadir, stemfile)
+			case "Group":
+				if pt.Spec == "" {
+					f = getGroups(ttinfo, datadir, stemfile)
+				} else {
+					// Groups of a single class
+					f = getSelectedGroups(ttinfo, datadir, stemfile, pt.Spec)
+				}
+			case "Day":
+				f = getDayClasses(ttinfo, datadir, stemfile)
+			case "Day_Teacher":
+				f = getDayTeachers(ttinfo, datadir, stemfile)
+			case "FreeRooms":
+				// Possibly restricted to a room group
+				f = getFreeRooms(ttinfo, datadir, stemfile, pt.Spec)
 			default:
 				// Table for individual element
-				f = genTypstOneElement(ttinfo, datadir, stemfile, p)
+				f = genTypstOneElement(ttinfo, datadir, stemfile, pt.Spec)
 			}
-			done[p] = f
+			done[pt] = f
+		}
+		if f == "" {
+			// No table, an error has been reported
+			continue
 		}
+		tt := TypstTable{Name: f, Data: f, Script: "print_timetable.typ"}
 		if overview {
-			f += "_overview"
+			tt.Name = f + "_overview"
+			tt.Script = "print_overview.typ"
 		}
-		typst_files = append(typst_files, f)
+		typst_files = append(typst_files, tt)
 	}
 	return typst_files
 }
 
+// Names returns the PDF file names of a list of tables.
+func Names(tables []TypstTable) []string {
+	names := []string{}
+	for _, tt := range tables {
+		names = append(names, tt.Name)
+	}
+	return names
+}
+
 func genTypstOneElement(
 	ttinfo *ttbase.TtInfo,
 	datadir string,
@@ -116,7 +175,8 @@ func genTypstOneElement(
 		// Make room JSON, but with only one room
 		return getOneRoom(ttinfo, datadir, stemfile, r)
 	}
-	base.Error.Fatalf("Can't print timetable for invalid element: %+v\n", e)
+	ttinfo.Db.Log.Error.Fatalf(
+		"Can't print timetable for invalid element: %+v\n", e)
 	return ""
 }
 
@@ -124,7 +184,7 @@ func genTypstOneElement(
 func timetable(
 	db *base.DbTopLevel,
 	pages []ttPage,
-	tabletype string, // "Class" or "Teacher" or "Room"
+	tabletype string, // "Class", "Teacher", "Room", "Group", "Day", ...
 ) Timetable {
 	dlist := []ttDay{}
 	for _, d := range db.Days {
@@ -142,6 +202,23 @@ func timetable(
 			End:   h.End,
 		})
 	}
+	opts := printOptions(db)
+	if opts.NoRooms && tabletype != "FreeRooms" {
+		for _, p := range pages {
+			for i := range p.Activities {
+				p.Activities[i].Rooms = nil
+			}
+		}
+	}
+	if opts.NoGroups {
+		for _, p := range pages {
+			for i := range p.Activities {
+				p.Activities[i].Groups = nil
+			}
+		}
+	}
+	addLegends(db, pages, tabletype)
+	setForegrounds(db, pages)
 	info := map[string]any{
 		"Institution": db.Info.Institution,
 		"Days":        dlist,
@@ -150,59 +227,167 @@ func timetable(
 	return Timetable{
 		TableType: tabletype,
 		Info:      info,
-		Typst:     db.PrintOptions.Typst,
+		Typst:     opts.typstMap(tabletype),
 		Pages:     pages,
 	}
 }
 
-func makeTypstJson(tt Timetable, datadir string, outfile string) {
+// addLegends builds the legends for the pages from the elements used in
+// their tiles. Elements without a (different) name are not included.
+func addLegends(db *base.DbTopLevel, pages []ttPage, tabletype string) {
+	subjects := map[string]string{}
+	for _, s := range db.Subjects {
+		subjects[s.Tag] = s.Name
+	}
+	teachers := map[string]string{}
+	for _, t := range db.Teachers {
+		teachers[t.Tag] = strings.TrimSpace(t.Firstname + " " + t.Name)
+	}
+	rooms := map[string]string{}
+	for _, r := range db.Rooms {
+		rooms[r.Tag] = r.Name
+	}
+	groups := map[string]string{}
+	for _, c := range db.Classes {
+		cname := c.Name
+		if cname == "" {
+			cname = c.Tag
+		}
+		groups[c.Tag] = cname
+		for _, div := range c.Divisions {
+			for _, gref := range div.Groups {
+				gtag := db.Elements[gref].(*base.Group).Tag
+				groups[c.Tag+ttbase.CLASS_GROUP_SEP+gtag] =
+					cname + ", Gruppe " + gtag
+			}
+		}
+	}
+	// The tags map the shortcuts used in the tiles to the keys in the names
+	// map. These differ only for groups in class tables.
+	legend := func(
+		tags map[string]string, names map[string]string,
+	) []LegendItem {
+		items := []LegendItem{}
+		for tag, key := range tags {
+			name := names[key]
+			if name != "" && name != tag {
+				items = append(items, LegendItem{Short: tag, Name: name})
+			}
+		}
+		slices.SortFunc(items, func(a, b LegendItem) int {
+			return strings.Compare(a.Short, b.Short)
+		})
+		return items
+	}
+
+	for i, p := range pages {
+		stags := map[string]string{}
+		ttags := map[string]string{}
+		rtags := map[string]string{}
+		gtags := map[string]string{}
+		for _, a := range p.Activities {
+			stags[a.Subject] = a.Subject
+			for _, t := range a.Teachers {
+				ttags[t] = t
+			}
+			for _, r := range a.Rooms {
+				rtags[r] = r
+			}
+			for _, g := range a.Groups {
+				if _, ok := groups[g]; !ok && tabletype == "Class" {
+					// A group of the page's class
+					gtags[g] = p.Short + ttbase.CLASS_GROUP_SEP + g
+				} else {
+					gtags[g] = g
+				}
+			}
+		}
+		pages[i].Legend = ttLegend{
+			Subjects: legend(stags, subjects),
+			Teachers: legend(ttags, teachers),
+			Rooms:    legend(rtags, rooms),
+			Groups:   legend(gtags, groups),
+		}
+	}
+}
+
+func makeTypstJson(
+	ttinfo *ttbase.TtInfo,
+	tt Timetable,
+	datadir string,
+	outfile string,
+) {
 	b, err := json.MarshalIndent(tt, "", "  ")
 	if err != nil {
-		base.Error.Fatal(err)
+		ttinfo.Db.Log.Error.Fatal(err)
 	}
 	// os.Stdout.Write(b)
 	outdir := filepath.Join(datadir, "_data")
 	if _, err := os.Stat(outdir); errors.Is(err, os.ErrNotExist) {
 		err := os.Mkdir(outdir, os.ModePerm)
 		if err != nil {
-			base.Error.Fatal(err)
+			ttinfo.Db.Log.Error.Fatal(err)
 		}
 	}
 	jsonpath := filepath.Join(outdir, outfile+".json")
 	err = os.WriteFile(jsonpath, b, 0666)
 	if err != nil {
-		base.Error.Fatal(err)
+		ttinfo.Db.Log.Error.Fatal(err)
 	}
-	base.Message.Printf("Wrote: %s\n", jsonpath)
+	ttinfo.Db.Log.Message.Printf("Wrote: %s\n", jsonpath)
 }
 
+// MakePdf runs Typst for a single table, using the embedded scripts.
 func MakePdf(
+	log *base.LogSet,
 	script string,
 	datadir string,
 	stemfile string,
 	outfile string,
 	typst string,
 ) {
+	root, err := newTypstRoot(log, datadir, "")
+	if err != nil {
+		log.Error.Fatal(err)
+	}
+	defer root.remove()
+	if err := root.addData(datadir, stemfile); err != nil {
+		log.Error.Fatal(err)
+	}
+	err = makePdf(root, script, datadir, stemfile, outfile, typst)
+	if err != nil {
+		log.Error.Fatal(err)
+	}
+}
+
+// makePdf runs Typst to produce a PDF file in the "_pdf" subfolder. The JSON
+// file must already be in the Typst root. Errors are returned, so that it
+// can be used in parallel.
+func makePdf(
+	root *typstRoot,
+	script string,
+	datadir string,
+	stemfile string,
+	outfile string,
+	typst string,
+) error {
 	outdir := filepath.Join(datadir, "_pdf")
-	if _, err := os.Stat(outdir); errors.Is(err, os.ErrNotExist) {
-		err := os.Mkdir(outdir, os.ModePerm)
-		if err != nil {
-			base.Error.Fatalln(err)
-		}
+	if err := os.MkdirAll(outdir, os.ModePerm); err != nil {
+		return err
 	}
 	outpath := filepath.Join(outdir, outfile+".pdf")
 
 	cmd := exec.Command(typst, "compile",
-		"--font-path", filepath.Join(datadir, "_fonts"),
-		"--root", datadir,
+		"--font-path", root.fonts,
+		"--root", root.dir,
 		"--input", "ifile="+filepath.Join("/_data", stemfile+".json"),
-		filepath.Join(datadir, "scripts", script),
+		root.script(script),
 		outpath)
 	//fmt.Printf(" ::: %s\n", cmd.String())
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		base.Error.Println("(Typst) " + string(output))
-		base.Error.Fatal(err)
+		return fmt.Errorf("(Typst) %s: %w\n%s", outfile, err, output)
 	}
-	base.Message.Printf("Timetable written to: %s\n", outpath)
+	root.log.Message.Printf("Timetable written to: %s\n", outpath)
+	return nil
 }
@@ -0,0 +1,512 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Export of the timetables as a static HTML site, as an alternative to the
+// PDF files produced by Typst. The same Timetable structures are used, also
+// the "Typst" print options (titles, page headings, field placements) are
+// respected, with the same fallbacks as in the Typst scripts (these are
+// also used for the SVG output).
+//
+// The site consists of an index page, a page for each class, teacher and
+// room and an overview page for each of these types. The pages have no
+// external dependencies.
+
+// Field placement fallbacks for the individual timetables
+var tileFields = map[string]map[string]string{
+	"Class":     {"c": "SUBJECT", "tl": "TEACHER", "tr": "GROUP", "br": "ROOM"},
+	"Teacher":   {"c": "GROUP", "tl": "SUBJECT", "tr": "TEACHER", "br": "ROOM"},
+	"Room":      {"c": "GROUP", "tl": "SUBJECT", "br": "TEACHER"},
+	"Group":     {"c": "SUBJECT", "tl": "TEACHER", "tr": "GROUP", "br": "ROOM"},
+	"FreeRooms": {"c": "ROOM"},
+}
+
+// Field placement fallbacks for the overview tables
+var overviewFields = map[string]map[string]string{
+	"Class":   {"m": "SUBJECT", "t": "TEACHER", "b": "GROUP"},
+	"Teacher": {"m": "GROUP", "t": "SUBJECT", "b": "TEACHER"},
+	"Room":    {"m": "GROUP", "t": "SUBJECT", "b": "TEACHER"},
+	"Group":   {"m": "SUBJECT", "t": "TEACHER", "b": "GROUP"},
+}
+
+// Page heading fallbacks
+var pageHeadings = map[string]string{
+	"Class":     "Klasse %S",
+	"Teacher":   "%N (%S)",
+	"Room":      "Raumplan %N (%S)",
+	"Group":     "%N",
+	"FreeRooms": "%N",
+}
+
+// Title fallbacks
+var tableTitles = map[string]string{
+	"Class":     "Stundenplan der Klassen",
+	"Teacher":   "Stundenplan der Lehrkräfte",
+	"Room":      "Stundenplan der Räume",
+	"Group":     "Stundenplan der Gruppen",
+	"FreeRooms": "Freie Räume",
+}
+
+var overviewTitles = map[string]string{
+	"Class":   "Gesamtstundenplan der Klassen",
+	"Teacher": "Gesamtstundenplan der Lehrkräfte",
+	"Room":    "Gesamtstundenplan der Räume",
+	"Group":   "Gesamtstundenplan der Gruppen",
+}
+
+// GenHtml writes the timetables of all classes, teachers and rooms as
+// HTML pages to the folder htmldir, which is created if necessary. The
+// paths of the files written are returned.
+func GenHtml(ttinfo *ttbase.TtInfo, htmldir string) []string {
+	return MakeHtmlSite(ttinfo.Db.Log, []Timetable{
+		classTimetable(ttinfo),
+		teacherTimetable(ttinfo),
+		roomTimetable(ttinfo),
+	}, htmldir)
+}
+
+// MakeHtmlSite writes an HTML site for the given timetables (of different
+// types) to the folder htmldir. Messages are written to the given log.
+func MakeHtmlSite(
+	log *base.LogSet,
+	tts []Timetable,
+	htmldir string,
+) []string {
+	if _, err := os.Stat(htmldir); errors.Is(err, os.ErrNotExist) {
+		err := os.MkdirAll(htmldir, os.ModePerm)
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+	}
+	files := []string{}
+	write := func(fname string, tmpl string, data any) {
+		fpath := filepath.Join(htmldir, fname)
+		f, err := os.Create(fpath)
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+		defer f.Close()
+		err = htmlTemplates.ExecuteTemplate(f, tmpl, data)
+		if err != nil {
+			log.Error.Fatalf("(HTML) %s: %v\n", fname, err)
+		}
+		files = append(files, fpath)
+	}
+
+	index := htmlIndex{}
+	for _, tt := range tts {
+		if len(index.Institution) == 0 {
+			index.Institution = infoString(tt, "Institution")
+			index.Subtitle = typstString(tt, "Subtitle")
+		}
+		kind := strings.ToLower(tt.TableType)
+		ilist := htmlIndexList{
+			Title:    typstTitle(tt, "Titles", tableTitles),
+			Overview: kind + "_overview.html",
+		}
+		days, hours := htmlDaysHours(tt)
+		for i, p := range tt.Pages {
+			fname := fmt.Sprintf("%s_%d.html", kind, i+1)
+			ilist.Links = append(ilist.Links, htmlLink{
+				Name: p.Name, Short: p.Short, File: fname,
+			})
+			write(fname, "week", htmlWeekPage{
+				Institution: index.Institution,
+				Title:       pageHeading(tt, p),
+				Subtitle:    typstString(tt, "Subtitle"),
+				Days:        days,
+				Hours:       hours,
+				Style: template.CSS(fmt.Sprintf(
+					"--ndays:%d;--nhours:%d", len(days), len(hours))),
+				Columns: weekColumns(tt, p, len(days), len(hours)),
+			})
+		}
+		write(ilist.Overview, "overview", overviewPage(
+			tt, index.Institution, days, hours))
+		index.Lists = append(index.Lists, ilist)
+	}
+	write("index.html", "index", index)
+	log.Message.Printf("Wrote %d HTML files to: %s\n", len(files), htmldir)
+	return files
+}
+
+type htmlLink struct {
+	Name  string
+	Short string
+	File  string
+}
+
+type htmlIndexList struct {
+	Title    string
+	Overview string
+	Links    []htmlLink
+}
+
+type htmlIndex struct {
+	Institution string
+	Subtitle    string
+	Lists       []htmlIndexList
+}
+
+type htmlTile struct {
+	Style template.CSS // position, size and colours
+	C     string
+	TL    string
+	TR    string
+	BL    string
+	BR    string
+}
+
+type htmlWeekPage struct {
+	Institution string
+	Title       string
+	Subtitle    string
+	Days        []string
+	Hours       []string
+	Style       template.CSS
+	Columns     [][]htmlTile // tiles for each day
+}
+
+type htmlHeadDay struct {
+	Name  string
+	Style template.CSS
+}
+
+type htmlRow struct {
+	Name  string
+	Short string
+	Tiles []htmlTile
+}
+
+type htmlOverviewPage struct {
+	Institution string
+	Title       string
+	Subtitle    string
+	Days        []htmlHeadDay
+	Hours       []string // hour tags, repeated for each day
+	Style       template.CSS
+	RowStyle    template.CSS
+	Rows        []htmlRow
+}
+
+func htmlDaysHours(tt Timetable) ([]string, []string) {
+	days := []string{}
+	if dlist, ok := tt.Info["Days"].([]ttDay); ok {
+		for _, d := range dlist {
+			days = append(days, d.Name)
+		}
+	}
+	withTimes, _ := tt.Typst["WithTimes"].(bool)
+	hours := []string{}
+	if hlist, ok := tt.Info["Hours"].([]ttHour); ok {
+		for _, h := range hlist {
+			if withTimes && h.Start != "" {
+				hours = append(hours, h.Short+"|"+h.Start+" – "+h.End)
+			} else {
+				hours = append(hours, h.Short)
+			}
+		}
+	}
+	return days, hours
+}
+
+func weekColumns(tt Timetable, p ttPage, ndays int, nhours int) [][]htmlTile {
+	fields := fieldPlacements(tt, tileFields)
+	columns := make([][]htmlTile, ndays)
+	for _, a := range p.Activities {
+		if a.Day >= ndays || a.Hour >= nhours {
+			continue
+		}
+		duration, fraction, offset, total := tileSizes(a)
+		style := fmt.Sprintf(
+			"top:%s;height:%s;left:%s;width:%s;%s",
+			percent(a.Hour, nhours), percent(duration, nhours),
+			percent(offset, total), percent(fraction, total),
+			tileColours(a.Background))
+		texts := tileTexts(a)
+		columns[a.Day] = append(columns[a.Day], htmlTile{
+			Style: template.CSS(style),
+			C:     texts[fields["c"]],
+			TL:    texts[fields["tl"]],
+			TR:    texts[fields["tr"]],
+			BL:    texts[fields["bl"]],
+			BR:    texts[fields["br"]],
+		})
+	}
+	return columns
+}
+
+func overviewPage(
+	tt Timetable,
+	institution string,
+	days []string,
+	hours []string,
+) htmlOverviewPage {
+	fields := fieldPlacements(tt, overviewFields)
+	nhours := len(hours)
+	ncols := len(days) * nhours
+	hdays := []htmlHeadDay{}
+	htags := []string{}
+	for _, d := range days {
+		hdays = append(hdays, htmlHeadDay{
+			Name:  d,
+			Style: template.CSS(fmt.Sprintf("grid-column:span %d", nhours)),
+		})
+		for _, h := range hours {
+			// Only the tag, no times
+			htag, _, _ := strings.Cut(h, "|")
+			htags = append(htags, htag)
+		}
+	}
+	rows := []htmlRow{}
+	for _, p := range tt.Pages {
+		tiles := []htmlTile{}
+		for _, a := range p.Activities {
+			if a.Day >= len(days) || a.Hour >= nhours {
+				continue
+			}
+			duration, fraction, offset, total := tileSizes(a)
+			style := fmt.Sprintf(
+				"left:%s;width:%s;top:%s;height:%s;%s",
+				percent(a.Day*nhours+a.Hour, ncols), percent(duration, ncols),
+				percent(offset, total), percent(fraction, total),
+				tileColours(a.Background))
+			texts := tileTexts(a)
+			tiles = append(tiles, htmlTile{
+				Style: template.CSS(style),
+				C:     texts[fields["m"]],
+				TL:    texts[fields["t"]],
+				BR:    texts[fields["b"]],
+			})
+		}
+		rows = append(rows, htmlRow{Name: p.Name, Short: p.Short, Tiles: tiles})
+	}
+	rowHeight := "3em"
+	if tt.TableType == "Class" {
+		rowHeight = "6em" // larger because of divisions
+	}
+	return htmlOverviewPage{
+		Institution: institution,
+		Title:       typstTitle(tt, "Titles", overviewTitles),
+		Subtitle:    typstString(tt, "Subtitle"),
+		Days:        hdays,
+		Hours:       htags,
+		Style:       template.CSS(fmt.Sprintf("--ncols:%d", ncols)),
+		RowStyle:    template.CSS("height:" + rowHeight),
+		Rows:        rows,
+	}
+}
+
+// tileSizes returns the duration and the fraction, offset and total values
+// of a tile, with the defaults for missing values.
+func tileSizes(a Tile) (int, int, int, int) {
+	duration := max(a.Duration, 1)
+	total := a.Total
+	fraction := a.Fraction
+	if total == 0 {
+		total = 1
+		fraction = 1
+	}
+	return duration, fraction, a.Offset, total
+}
+
+func tileTexts(a Tile) map[string]string {
+	return map[string]string{
+		"SUBJECT": a.Subject,
+		"GROUP":   strings.Join(a.Groups, ","),
+		"TEACHER": strings.Join(a.Teachers, ","),
+		"ROOM":    strings.Join(a.Rooms, ","),
+	}
+}
+
+func percent(n int, d int) string {
+	return strconv.FormatFloat(float64(n)*100/float64(d), 'f', 3, 64) + "%"
+}
+
+// tileColours returns the CSS for the background, border and text colours.
+func tileColours(background string) string {
+	bg, border, text := getTileColours(background)
+	return fmt.Sprintf("background:%s;border-color:%s;color:%s",
+		bg, border, text)
+}
+
+// getTileColours returns the background, border and text colours for a
+// tile. As in the Typst scripts, the text is white on dark backgrounds.
+// Invalid backgrounds have already been reported (and removed) by
+// setForegrounds, they are just ignored here.
+func getTileColours(background string) (string, string, string) {
+	if background == "" {
+		return "#ffffff", "#000000", "#000000"
+	}
+	text, err := contrastColour(background)
+	if err != nil {
+		return "#ffffff", "#000000", "#000000"
+	}
+	return background, background, text
+}
+
+func infoString(tt Timetable, key string) string {
+	s, _ := tt.Info[key].(string)
+	return s
+}
+
+func typstString(tt Timetable, key string) string {
+	s, _ := tt.Typst[key].(string)
+	return s
+}
+
+// typstTitle gets the title for the table type from the given field of the
+// Typst options, otherwise from the fallbacks.
+func typstTitle(
+	tt Timetable, key string, fallbacks map[string]string,
+) string {
+	if titles, ok := tt.Typst[key].(map[string]any); ok {
+		if s, ok := titles[tt.TableType].(string); ok {
+			return s
+		}
+	}
+	return fallbacks[tt.TableType]
+}
+
+func pageHeading(tt Timetable, p ttPage) string {
+	h := typstTitle(tt, "PageHeading", pageHeadings)
+	return strings.NewReplacer("%N", p.Name, "%S", p.Short).Replace(h)
+}
+
+// fieldPlacements gets the field placements from the Typst options (already
+// resolved for the table type). Field placements for the other kind of
+// table (individual or overview) are ignored.
+func fieldPlacements(
+	tt Timetable, fallbacks map[string]map[string]string,
+) map[string]string {
+	fmap, ok := tt.Typst["FieldPlacements"].(map[string]any)
+	if ok {
+		fields := map[string]string{}
+		for k, v := range fmap {
+			if s, ok := v.(string); ok {
+				fields[k] = s
+			}
+		}
+		// The fields must fit the table (individual or overview)
+		for k := range fallbacks[tt.TableType] {
+			if _, ok := fields[k]; ok {
+				return fields
+			}
+		}
+	}
+	return fallbacks[tt.TableType]
+}
+
+var htmlTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"hourname": func(h string) string {
+		s, _, _ := strings.Cut(h, "|")
+		return s
+	},
+	"hourtime": func(h string) string {
+		_, s, _ := strings.Cut(h, "|")
+		return s
+	},
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="de">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+<style>
+body { font-family: "Nunito", "DejaVu Sans", sans-serif; margin: 1em; }
+h1 { font-size: 1.5em; margin: 0.2em 0; }
+nav { float: right; }
+.subtitle { margin: 0 0 0.5em 0; }
+.week { display: grid; border: 1px solid #707070;
+    grid-template-columns: 6em repeat(var(--ndays), 1fr); }
+.overview { display: grid; border: 1px solid #707070; font-size: 0.7em;
+    grid-template-columns: 6em repeat(var(--ncols), minmax(2.5em, 1fr)); }
+.hd { background: #f0f0f0; font-weight: bold; text-align: center;
+    border: 1px solid #707070; padding: 0.2em; overflow: hidden; }
+.hd small { display: block; font-weight: normal; }
+.day, .orow { position: relative; display: grid; }
+.day { grid-template-rows: repeat(var(--nhours), 4.5em); }
+.orow { grid-column: 2 / -1;
+    grid-template-columns: repeat(var(--ncols), 1fr); }
+.slot { border: 1px solid #707070; }
+.tile { position: absolute; box-sizing: border-box; border: 1px solid;
+    padding: 1px 3px; overflow: hidden; font-size: 0.8em;
+    display: flex; flex-direction: column; justify-content: space-between; }
+.tile .c { text-align: center; font-weight: bold; font-size: 1.2em; }
+.tile .tb { display: flex; justify-content: space-between; gap: 0.3em;
+    white-space: nowrap; }
+.index ul { columns: 12em; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "title"}}<nav><a href="index.html">{{.Institution}}</a></nav>
+<h1>{{.Title}}</h1>
+{{if .Subtitle}}<p class="subtitle">{{.Subtitle}}</p>{{end}}
+{{end}}
+
+{{define "index"}}{{template "head" .Institution}}
+<h1>{{.Institution}}</h1>
+{{if .Subtitle}}<p class="subtitle">{{.Subtitle}}</p>{{end}}
+{{range .Lists}}<div class="index">
+<h2>{{.Title}}</h2>
+<p><a href="{{.Overview}}">Gesamtplan</a></p>
+<ul>
+{{range .Links}}<li><a href="{{.File}}">{{.Short}}</a> – {{.Name}}</li>
+{{end}}</ul>
+</div>
+{{end}}</body>
+</html>
+{{end}}
+
+{{define "week"}}{{template "head" .Title}}{{template "title" .}}
+<div class="week" style="{{.Style}}">
+<div class="hd"></div>
+{{range .Days}}<div class="hd">{{.}}</div>
+{{end}}<div style="display: grid; grid-template-rows: repeat(var(--nhours), 4.5em);">
+{{range .Hours}}<div class="hd">{{hourname .}}{{with hourtime .}}<small>{{.}}</small>{{end}}</div>
+{{end}}</div>
+{{$hours := .Hours}}{{range .Columns}}<div class="day">
+{{range $hours}}<div class="slot"></div>{{end}}
+{{range .}}<div class="tile" style="{{.Style}}">
+<div class="tb"><span>{{.TL}}</span><span>{{.TR}}</span></div>
+<div class="c">{{.C}}</div>
+<div class="tb"><span>{{.BL}}</span><span>{{.BR}}</span></div>
+</div>
+{{end}}</div>
+{{end}}</div>
+</body>
+</html>
+{{end}}
+
+{{define "overview"}}{{template "head" .Title}}{{template "title" .}}
+<div class="overview" style="{{.Style}}">
+<div class="hd" style="grid-row: span 2;"></div>
+{{range .Days}}<div class="hd" style="{{.Style}}">{{.Name}}</div>
+{{end}}{{range .Hours}}<div class="hd">{{.}}</div>
+{{end}}{{$rowstyle := .RowStyle}}{{$hours := .Hours}}{{range .Rows}}<div class="hd" title="{{.Name}}">{{.Short}}</div>
+<div class="orow" style="{{$rowstyle}}">
+{{range $hours}}<div class="slot"></div>{{end}}
+{{range .Tiles}}<div class="tile" style="{{.Style}}">
+<div class="tb"><span>{{.TL}}</span></div>
+<div class="c">{{.C}}</div>
+<div class="tb"><span></span><span>{{.BR}}</span></div>
+</div>
+{{end}}</div>
+{{end}}</div>
+</body>
+</html>
+{{end}}
+`))
@@ -0,0 +1,295 @@
+package ttprint
+
+import (
+	"W365toFET/ttbase"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Export of the timetables as iCalendar (ICS) files, one per teacher, class
+// and room. Each lesson becomes a weekly recurring event within the term,
+// the holidays are excluded. The times are "floating" (local time, no time
+// zone).
+
+const ICS_DATE = "2006-01-02"
+
+// A DateRange covers the days from Start to End (inclusive).
+type DateRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// A Term is the period for which the events are generated.
+type Term struct {
+	DateRange
+	Holidays []DateRange
+}
+
+// ParseDateRange reads a date range in the form "2025-09-08:2026-07-24".
+// A single date is a range of one day.
+func ParseDateRange(s string) (DateRange, error) {
+	d1, d2, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		d2 = d1
+	}
+	t1, err := time.Parse(ICS_DATE, strings.TrimSpace(d1))
+	if err != nil {
+		return DateRange{}, err
+	}
+	t2, err := time.Parse(ICS_DATE, strings.TrimSpace(d2))
+	if err != nil {
+		return DateRange{}, err
+	}
+	if t2.Before(t1) {
+		return DateRange{}, fmt.Errorf("invalid date range: %s", s)
+	}
+	return DateRange{t1, t2}, nil
+}
+
+// ParseTerm reads the term dates ("start:end") and a comma-separated list
+// of holiday date ranges.
+func ParseTerm(term string, holidays string) (Term, error) {
+	dr, err := ParseDateRange(term)
+	if err != nil {
+		return Term{}, err
+	}
+	t := Term{DateRange: dr}
+	for _, h := range strings.Split(holidays, ",") {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		hr, err := ParseDateRange(h)
+		if err != nil {
+			return Term{}, err
+		}
+		t.Holidays = append(t.Holidays, hr)
+	}
+	return t, nil
+}
+
+func (t Term) isHoliday(d time.Time) bool {
+	for _, h := range t.Holidays {
+		if !d.Before(h.Start) && !d.After(h.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenICalendars writes the ICS files to the "_ics" subfolder of datadir.
+// The paths of the files written are returned.
+func GenICalendars(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+	term Term,
+) []string {
+	db := ttinfo.Db
+	if len(db.Days) > 7 {
+		ttinfo.Db.Log.Warning.Printf(
+			"iCalendar: only the first 7 days are used\n")
+	}
+	outdir := filepath.Join(datadir, "_ics")
+	if _, err := os.Stat(outdir); errors.Is(err, os.ErrNotExist) {
+		err := os.Mkdir(outdir, os.ModePerm)
+		if err != nil {
+			ttinfo.Db.Log.Error.Fatal(err)
+		}
+	}
+	ical := &icalWriter{
+		ttinfo:   ttinfo,
+		term:     term,
+		stamp:    time.Now().UTC().Format("20060102T150405Z"),
+		subjects: map[string]string{},
+		notimes:  map[int]bool{},
+	}
+	for _, s := range db.Subjects {
+		ical.subjects[s.Tag] = s.Name
+	}
+	icsfiles := []string{}
+	write := func(kind string, tag string, name string, tiles []Tile) {
+		f := filepath.Join(outdir, stemfile+"_"+kind+"_"+tag+".ics")
+		uid := stemfile + "_" + kind + "_" + tag
+		err := os.WriteFile(f, []byte(ical.calendar(uid, name, tiles)), 0666)
+		if err != nil {
+			ttinfo.Db.Log.Error.Fatal(err)
+		}
+		icsfiles = append(icsfiles, f)
+	}
+
+	data := getTeacherData(ttinfo)
+	for _, t := range db.Teachers {
+		if tiles, ok := data[t.Id]; ok {
+			write("teacher", t.Tag, t.Firstname+" "+t.Name, tiles)
+		}
+	}
+	data = getClassData(ttinfo)
+	for _, c := range db.Classes {
+		if c.Tag == "" {
+			continue
+		}
+		if tiles, ok := data[c.Id]; ok {
+			write("class", c.Tag, c.Name, tiles)
+		}
+	}
+	data = getRoomData(ttinfo)
+	for _, r := range db.Rooms {
+		if tiles, ok := data[r.Id]; ok {
+			write("room", r.Tag, r.Name, tiles)
+		}
+	}
+	ttinfo.Db.Log.Message.Printf("Wrote %d iCalendar files to: %s\n",
+		len(icsfiles), outdir)
+	return icsfiles
+}
+
+type icalWriter struct {
+	ttinfo   *ttbase.TtInfo
+	term     Term
+	stamp    string
+	subjects map[string]string // subject tag -> name
+	notimes  map[int]bool      // hours without valid times (warned)
+	lines    []string
+}
+
+func (w *icalWriter) add(key string, value string) {
+	w.lines = append(w.lines, key+":"+value)
+}
+
+// calendar builds the contents of an ICS file with the given tiles.
+// The events get unique ids based on uidstem.
+func (w *icalWriter) calendar(
+	uidstem string,
+	name string,
+	tiles []Tile,
+) string {
+	w.lines = []string{}
+	w.add("BEGIN", "VCALENDAR")
+	w.add("VERSION", "2.0")
+	w.add("PRODID", "-//W365toFET//Timetable//DE")
+	w.add("CALSCALE", "GREGORIAN")
+	w.add("X-WR-CALNAME", icsText(name))
+	// Sort the tiles so that the event ids are the same each time the
+	// calendar is generated (for unchanged data).
+	// Class tiles can be repeated (several groups of a class in one
+	// lesson), only one event is needed.
+	tmap := map[string]Tile{}
+	for _, tile := range tiles {
+		tmap[fmt.Sprintf("%02d:%02d:%+v", tile.Day, tile.Hour, tile)] = tile
+	}
+	n := 0
+	for _, key := range slices.Sorted(maps.Keys(tmap)) {
+		if w.event(tmap[key], fmt.Sprintf("%s-%d", uidstem, n)) {
+			n++
+		}
+	}
+	w.add("END", "VCALENDAR")
+	// Lines longer than 75 octets must be folded.
+	var b strings.Builder
+	for _, l := range w.lines {
+		for len(l) > 75 {
+			i := 75
+			for i > 0 && (l[i]&0xC0) == 0x80 {
+				i-- // don't split a UTF-8 sequence
+			}
+			b.WriteString(l[:i] + "\r\n")
+			l = " " + l[i:]
+		}
+		b.WriteString(l + "\r\n")
+	}
+	return b.String()
+}
+
+// event adds a weekly recurring event for the tile. If the times are
+// missing, no event is added and the result is false.
+func (w *icalWriter) event(tile Tile, uid string) bool {
+	db := w.ttinfo.Db
+	if tile.Day >= 7 {
+		return false
+	}
+	h1 := db.Hours[tile.Hour]
+	h2 := h1
+	if tile.Duration > 1 {
+		h2 = db.Hours[tile.Hour+tile.Duration-1]
+	}
+	start, ok1 := clockTime(h1.Start)
+	end, ok2 := clockTime(h2.End)
+	if !ok1 || !ok2 {
+		if !w.notimes[tile.Hour] {
+			w.notimes[tile.Hour] = true
+			w.ttinfo.Db.Log.Warning.Printf(
+				"iCalendar: no valid times for hour %s\n",
+				h1.Tag)
+		}
+		return false
+	}
+	// The first lesson date in the term
+	first := w.term.Start
+	wd := time.Weekday((tile.Day + 1) % 7) // the first day is Monday
+	for first.Weekday() != wd {
+		first = first.AddDate(0, 0, 1)
+	}
+	if first.After(w.term.End) {
+		return false
+	}
+	exdates := []string{}
+	for d := first; !d.After(w.term.End); d = d.AddDate(0, 0, 7) {
+		if w.term.isHoliday(d) {
+			exdates = append(exdates, d.Add(start).Format("20060102T150405"))
+		}
+	}
+
+	summary := w.subjects[tile.Subject]
+	if summary == "" {
+		summary = tile.Subject
+	}
+	desc := []string{}
+	if len(tile.Groups) != 0 {
+		desc = append(desc, strings.Join(tile.Groups, ", "))
+	}
+	if len(tile.Teachers) != 0 {
+		desc = append(desc, strings.Join(tile.Teachers, ", "))
+	}
+	w.add("BEGIN", "VEVENT")
+	w.add("UID", icsText(strings.ReplaceAll(uid, " ", "_"))+"@W365toFET")
+	w.add("DTSTAMP", w.stamp)
+	w.add("DTSTART", first.Add(start).Format("20060102T150405"))
+	w.add("DTEND", first.Add(end).Format("20060102T150405"))
+	w.add("RRULE", "FREQ=WEEKLY;UNTIL="+
+		w.term.End.Format("20060102")+"T235959")
+	for _, x := range exdates {
+		w.add("EXDATE", x)
+	}
+	w.add("SUMMARY", icsText(summary))
+	if len(tile.Rooms) != 0 {
+		w.add("LOCATION", icsText(strings.Join(tile.Rooms, ", ")))
+	}
+	if len(desc) != 0 {
+		w.add("DESCRIPTION", icsText(strings.Join(desc, "\n")))
+	}
+	w.add("END", "VEVENT")
+	return true
+}
+
+// clockTime converts "hh:mm" to a duration from midnight.
+func clockTime(s string) (time.Duration, bool) {
+	var h, m int
+	_, err := fmt.Sscanf(s, "%d:%d", &h, &m)
+	if err != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, true
+}
+
+// icsText escapes the special characters in text values.
+func icsText(s string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\", ";", "\\;", ",", "\\,", "\n", "\\n",
+	).Replace(s)
+}
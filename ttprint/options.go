@@ -0,0 +1,556 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The print options are read from the PrintOptions object of the input
+// data: the list of tables to print ("PrintTables"), the colour scheme
+// ("ColorScheme") and the options for the Typst scripts ("Typst"). They
+// are checked here and collected in a PrintOptions structure. The options
+// which are passed to the Typst scripts are then rebuilt from this, so
+// that the scripts always get the same (correctly spelled) keys.
+//
+// Unknown keys in the Typst options are passed on unchanged (in "Other"),
+// as a script may have options of its own. If such a key is probably a
+// misspelling of a known option, that is reported as an error. The keys
+// used by the older scripts ("subtitle", "titles") are still accepted,
+// with a warning.
+//
+// The options are described in docs/druckoptionen.md.
+
+type PrintOptions struct {
+	Tables          []PrintTable
+	ColorScheme     string
+	PageSize        PageSize // individual timetables
+	OverviewSize    PageSize // overview and day tables
+	Fonts           []string
+	Titles          map[string]string // table type -> title
+	Subtitle        string
+	PageHeadings    map[string]string // table type -> page heading
+	NoRooms         bool
+	NoGroups        bool
+	WithTimes       bool
+	WithBreaks      bool
+	WithLegend      bool
+	FullNames       []string
+	FieldPlacements map[string]any // as in the input, checked
+	Other           map[string]any // unknown keys, passed on unchanged
+	Warnings        []string       // problems which don't lose an option
+}
+
+// A PrintTable is an entry in the list of tables to print.
+type PrintTable struct {
+	Type     string // table type, empty for a single element
+	Overview bool   // an overview table
+	Spec     string // the element (Id), or the selection after the ":"
+}
+
+// A PageSize is in mm, zero means the script's default.
+type PageSize struct {
+	Width  float64
+	Height float64
+}
+
+// The table types which can be used in PrintTables
+var printTableTypes = []string{
+	"Class", "Teacher", "Room", "Group", "Day", "Day_Teacher", "FreeRooms",
+}
+
+// The table types with overview tables
+var overviewTableTypes = []string{"Class", "Teacher", "Room", "Group"}
+
+// Default list of tables to print
+var defaultPrintTables = []string{
+	"Class", "Teacher", "Room",
+	"Class_overview", "Teacher_overview", "Room_overview",
+}
+
+// The fields which can be placed in the tiles
+var tileFieldNames = []string{"SUBJECT", "TEACHER", "ROOM", "GROUP"}
+
+// The positions in the tiles (individual timetables and overviews)
+var tilePositions = []string{"c", "tl", "tr", "bl", "br", "m", "t", "b"}
+
+// Paper formats, landscape, in mm
+var pageFormats = map[string]PageSize{
+	"A2": {594, 420},
+	"A3": {420, 297},
+	"A4": {297, 210},
+	"A5": {210, 148},
+}
+
+// The known Typst options (keys as passed to the scripts)
+var typstOptionKeys = []string{
+	"PageFormat", "OverviewPageFormat", "Fonts", "Titles", "Subtitle",
+	"PageHeading", "NoRooms", "NoGroups", "WithTimes", "WithBreaks",
+	"WithLegend", "FullNames", "FieldPlacements",
+}
+
+// The keys of the older scripts, which are still accepted
+var typstOptionAliases = map[string]string{
+	"subtitle": "Subtitle",
+	"titles":   "Titles",
+}
+
+// String returns the table in the form used in PrintTables.
+func (pt PrintTable) String() string {
+	s := pt.Spec
+	if pt.Type != "" {
+		s = pt.Type
+		if pt.Spec != "" {
+			s += ":" + pt.Spec
+		}
+	}
+	if pt.Overview {
+		s += "_overview"
+	}
+	return s
+}
+
+// ParsePrintOptions reads and checks the print options of the database.
+// All problems are returned (joined) as the error. The result contains the
+// valid options, so it can be used even if there are errors.
+func ParsePrintOptions(db *base.DbTopLevel) (*PrintOptions, error) {
+	opts := &PrintOptions{
+		Titles:       map[string]string{},
+		PageHeadings: map[string]string{},
+		Other:        map[string]any{},
+	}
+	errs := []error{}
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// The tables
+	ptables := db.PrintOptions.PrintTables
+	if len(ptables) == 0 {
+		ptables = defaultPrintTables
+	}
+	for _, entry := range ptables {
+		pt, err := parsePrintTable(db, entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if slices.Contains(opts.Tables, pt) {
+			fail("PrintTables: %s given more than once", entry)
+			continue
+		}
+		opts.Tables = append(opts.Tables, pt)
+	}
+
+	// The colour scheme
+	cs := strings.ToLower(db.PrintOptions.ColorScheme)
+	switch cs {
+	case "":
+		cs = COLOURS_LESSON
+	case COLOURS_LESSON, COLOURS_SUBJECT, COLOURS_TEACHER, COLOURS_CLASS,
+		COLOURS_NONE:
+	default:
+		fail("ColorScheme: unknown value %q (possible: %s)",
+			db.PrintOptions.ColorScheme, strings.Join([]string{
+				COLOURS_LESSON, COLOURS_SUBJECT, COLOURS_TEACHER,
+				COLOURS_CLASS, COLOURS_NONE}, ", "))
+		cs = COLOURS_LESSON
+	}
+	opts.ColorScheme = cs
+
+	// The Typst options, in a fixed order for the messages
+	keys := []string{}
+	for key := range db.PrintOptions.Typst {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		val := db.PrintOptions.Typst[key]
+		if k, ok := typstOptionAliases[key]; ok {
+			if _, ok := db.PrintOptions.Typst[k]; ok {
+				opts.Warnings = append(opts.Warnings, fmt.Sprintf(
+					"Typst option %q ignored, %q is used", key, k))
+				continue
+			}
+			opts.Warnings = append(opts.Warnings, fmt.Sprintf(
+				"Typst option %q is deprecated, use %q", key, k))
+			key = k
+		}
+		var err error
+		switch key {
+		case "PageFormat":
+			opts.PageSize, err = parsePageFormat(val)
+		case "OverviewPageFormat":
+			opts.OverviewSize, err = parsePageFormat(val)
+		case "Fonts":
+			opts.Fonts, err = stringList(val)
+		case "Titles":
+			opts.Titles, err = tableTypeMap(val)
+		case "Subtitle":
+			opts.Subtitle, err = stringValue(val)
+		case "PageHeading", "PageHeadings":
+			opts.PageHeadings, err = tableTypeMap(val)
+		case "NoRooms":
+			opts.NoRooms, err = boolValue(val)
+		case "NoGroups":
+			opts.NoGroups, err = boolValue(val)
+		case "WithTimes":
+			opts.WithTimes, err = boolValue(val)
+		case "WithBreaks":
+			opts.WithBreaks, err = boolValue(val)
+		case "WithLegend":
+			opts.WithLegend, err = boolValue(val)
+		case "FullNames":
+			opts.FullNames, err = parseFullNames(val)
+		case "FieldPlacements":
+			opts.FieldPlacements, err = parseFieldPlacements(val)
+		default:
+			if k := similarKey(key); k != "" {
+				fail("Typst option %q unknown – did you mean %q?", key, k)
+			} else {
+				opts.Other[key] = val
+			}
+			continue
+		}
+		if err != nil {
+			fail("Typst option %s: %v", key, err)
+		}
+	}
+	return opts, errors.Join(errs...)
+}
+
+// printOptions returns the print options without reporting errors, which
+// is done in GenTypstData.
+func printOptions(db *base.DbTopLevel) *PrintOptions {
+	opts, _ := ParsePrintOptions(db)
+	return opts
+}
+
+func parsePrintTable(db *base.DbTopLevel, entry string) (PrintTable, error) {
+	pt := PrintTable{}
+	p, overview := strings.CutSuffix(entry, "_overview")
+	pt.Overview = overview
+	t, spec, withspec := strings.Cut(p, ":")
+	if slices.Contains(printTableTypes, t) {
+		pt.Type = t
+		if withspec {
+			if spec == "" {
+				return pt, fmt.Errorf("PrintTables: %s – empty selection",
+					entry)
+			}
+			switch t {
+			case "Group":
+				if err := checkGroupSpec(db, spec); err != nil {
+					return pt, fmt.Errorf("PrintTables: %s – %v", entry, err)
+				}
+			case "FreeRooms":
+				if r, _, _ := findRoomGroup(db, spec); r == "" {
+					return pt, fmt.Errorf(
+						"PrintTables: %s – unknown room group %q", entry, spec)
+				}
+			default:
+				return pt, fmt.Errorf(
+					"PrintTables: %s – no selection possible for %s",
+					entry, t)
+			}
+			pt.Spec = spec
+		}
+		if overview && !slices.Contains(overviewTableTypes, t) {
+			return pt, fmt.Errorf("PrintTables: %s – no overview for %s",
+				entry, t)
+		}
+		return pt, nil
+	}
+	// A single element
+	switch db.Elements[base.Ref(p)].(type) {
+	case *base.Class, *base.Teacher, *base.Room:
+		if overview {
+			return pt, fmt.Errorf(
+				"PrintTables: %s – no overview for a single element", entry)
+		}
+		pt.Spec = p
+		return pt, nil
+	}
+	for _, tt := range printTableTypes {
+		if strings.EqualFold(tt, t) {
+			return pt, fmt.Errorf("PrintTables: %s – did you mean %s?",
+				entry, strings.Replace(entry, t, tt, 1))
+		}
+	}
+	return pt, fmt.Errorf("PrintTables: %s – neither a table type (%s)"+
+		" nor the Id of a class, teacher or room",
+		entry, strings.Join(printTableTypes, ", "))
+}
+
+// checkGroupSpec checks the class and group tags of a "Group:" entry.
+// Whether there are pupils in all the groups can only be checked when the
+// table is built.
+func checkGroupSpec(db *base.DbTopLevel, spec string) error {
+	tags := strings.Split(spec, ttbase.CLASS_GROUP_SEP)
+	i := slices.IndexFunc(db.Classes, func(c *base.Class) bool {
+		return c.Tag != "" && c.Tag == tags[0]
+	})
+	if i < 0 {
+		return fmt.Errorf("unknown class %q", tags[0])
+	}
+	c := db.Classes[i]
+	for _, gtag := range tags[1:] {
+		found := false
+		for _, d := range c.Divisions {
+			for _, gref := range d.Groups {
+				if g, ok := db.Elements[gref].(*base.Group); ok &&
+					g.Tag == gtag {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown group %q in class %s", gtag, c.Tag)
+		}
+	}
+	return nil
+}
+
+// parsePageFormat accepts the name of a paper format (landscape) or the
+// size in mm as "width x height", e.g. "420x297".
+func parsePageFormat(val any) (PageSize, error) {
+	s, err := stringValue(val)
+	if err != nil {
+		return PageSize{}, err
+	}
+	if ps, ok := pageFormats[strings.ToUpper(s)]; ok {
+		return ps, nil
+	}
+	w, h, ok := strings.Cut(strings.ToLower(s), "x")
+	if ok {
+		width, err1 := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		height, err2 := strconv.ParseFloat(strings.TrimSpace(h), 64)
+		if err1 == nil && err2 == nil && width > 0 && height > 0 {
+			return PageSize{width, height}, nil
+		}
+	}
+	return PageSize{}, fmt.Errorf("invalid page format %q (A2 – A5 or"+
+		" width x height in mm, e.g. \"420x297\")", s)
+}
+
+func parseFullNames(val any) ([]string, error) {
+	if b, ok := val.(bool); ok {
+		if b {
+			return []string{"TEACHER"}, nil
+		}
+		return nil, nil
+	}
+	fields, err := stringList(val)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if !slices.Contains(tileFieldNames, f) {
+			return nil, fmt.Errorf("unknown field %q (possible: %s)",
+				f, strings.Join(tileFieldNames, ", "))
+		}
+	}
+	return fields, nil
+}
+
+// parseFieldPlacements checks the field placements, which can be given
+// for each table type or as a single mapping for all types.
+func parseFieldPlacements(val any) (map[string]any, error) {
+	m, ok := val.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected an object")
+	}
+	checkPlacements := func(pm map[string]any) error {
+		for pos, f := range pm {
+			if !slices.Contains(tilePositions, pos) {
+				return fmt.Errorf("unknown position %q (possible: %s)",
+					pos, strings.Join(tilePositions, ", "))
+			}
+			if s, ok := f.(string); !ok ||
+				(s != "" && !slices.Contains(tileFieldNames, s)) {
+				return fmt.Errorf("unknown field %v at %s (possible: %s)",
+					f, pos, strings.Join(tileFieldNames, ", "))
+			}
+		}
+		return nil
+	}
+	for k, v := range m {
+		if pm, ok := v.(map[string]any); ok {
+			if !slices.Contains(printTableTypes, k) {
+				return nil, fmt.Errorf("unknown table type %q", k)
+			}
+			if err := checkPlacements(pm); err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+		} else if err := checkPlacements(map[string]any{k: v}); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func stringValue(val any) (string, error) {
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, not %v", val)
+	}
+	return s, nil
+}
+
+func boolValue(val any) (bool, error) {
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected true or false, not %v", val)
+	}
+	return b, nil
+}
+
+// stringList accepts a string or a list of strings.
+func stringList(val any) ([]string, error) {
+	if s, ok := val.(string); ok {
+		return []string{s}, nil
+	}
+	list, ok := val.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list of strings, not %v", val)
+	}
+	slist := []string{}
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, not %v", v)
+		}
+		slist = append(slist, s)
+	}
+	return slist, nil
+}
+
+// tableTypeMap reads a mapping table type -> string.
+func tableTypeMap(val any) (map[string]string, error) {
+	m, ok := val.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected an object (table type: text)")
+	}
+	smap := map[string]string{}
+	for k, v := range m {
+		if !slices.Contains(printTableTypes, k) {
+			return nil, fmt.Errorf("unknown table type %q (possible: %s)",
+				k, strings.Join(printTableTypes, ", "))
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a string, not %v", k, v)
+		}
+		smap[k] = s
+	}
+	return smap, nil
+}
+
+// similarKey returns the known option which key probably should be, or an
+// empty string.
+func similarKey(key string) string {
+	for _, k := range typstOptionKeys {
+		if strings.EqualFold(k, key) || editDistance(
+			strings.ToLower(k), strings.ToLower(key)) <= 2 {
+			return k
+		}
+	}
+	return ""
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// typstMap builds the options for the Typst scripts for a table of the
+// given type.
+func (opts *PrintOptions) typstMap(tabletype string) map[string]any {
+	m := map[string]any{}
+	for k, v := range opts.Other {
+		m[k] = v
+	}
+	pageSize := func(ps PageSize) map[string]float64 {
+		return map[string]float64{"width": ps.Width, "height": ps.Height}
+	}
+	if opts.PageSize.Width != 0 {
+		m["PageSize"] = pageSize(opts.PageSize)
+	}
+	if opts.OverviewSize.Width != 0 {
+		m["OverviewPageSize"] = pageSize(opts.OverviewSize)
+	}
+	if len(opts.Fonts) != 0 {
+		m["Fonts"] = opts.Fonts
+	}
+	anyMap := func(sm map[string]string) map[string]any {
+		am := map[string]any{}
+		for k, v := range sm {
+			am[k] = v
+		}
+		return am
+	}
+	if len(opts.Titles) != 0 {
+		m["Titles"] = anyMap(opts.Titles)
+	}
+	if opts.Subtitle != "" {
+		m["Subtitle"] = opts.Subtitle
+	}
+	if len(opts.PageHeadings) != 0 {
+		m["PageHeading"] = anyMap(opts.PageHeadings)
+	}
+	if opts.WithTimes {
+		m["WithTimes"] = true
+	}
+	if opts.WithBreaks {
+		m["WithBreaks"] = true
+	}
+	if opts.WithLegend {
+		m["WithLegend"] = true
+	}
+	if len(opts.FullNames) != 0 {
+		m["FullNames"] = opts.FullNames
+	}
+	if fmap := opts.fieldPlacements(tabletype); len(fmap) != 0 {
+		m["FieldPlacements"] = fmap
+	}
+	return m
+}
+
+// fieldPlacements returns the field placements for a table type as a
+// single mapping position -> field, which is what the scripts expect.
+// Entries for the table type override those given for all types.
+func (opts *PrintOptions) fieldPlacements(tabletype string) map[string]any {
+	fmap := map[string]any{}
+	for k, v := range opts.FieldPlacements {
+		if _, ok := v.(string); ok {
+			fmap[k] = v
+		}
+	}
+	if pm, ok := opts.FieldPlacements[tabletype].(map[string]any); ok {
+		for k, v := range pm {
+			fmap[k] = v
+		}
+	}
+	return fmap
+}
@@ -0,0 +1,219 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// Generation of the PDF files for the entries returned by GenTypstData. The
+// Typst runs are distributed over a limited number of workers. A table is
+// only rebuilt if its JSON input, its Typst script or the fonts (the files
+// in the "_fonts" folder used) have changed since the last run (or the PDF
+// file is missing). For this, hashes of the inputs are
+// kept in the file "_pdf_cache.json", next to the "_pdf" folder. The scripts
+// are the embedded ones, unless a template folder is given (see typstroot.go).
+
+const PDF_CACHE_FILE = "_pdf_cache.json"
+
+// MakePdfs builds the PDF files for the given entries (as returned by
+// GenTypstData) using at most "workers" parallel Typst processes. If workers
+// is less than 1, the number of CPUs is used. If force is true, all files
+// are rebuilt. If templates is not empty, it is a folder with custom Typst
+// scripts (and fonts). Messages are written to the given log. The number of
+// PDF files actually built is returned.
+func MakePdfs(
+	log *base.LogSet,
+	typst_files []TypstTable,
+	datadir string,
+	templates string,
+	typst string,
+	workers int,
+	force bool,
+) int {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	root, err := newTypstRoot(log, datadir, templates)
+	if err != nil {
+		log.Error.Fatalf("Typst scripts: %v\n", err)
+	}
+	defer root.remove()
+	cachepath := filepath.Join(datadir, PDF_CACHE_FILE)
+	cache := readPdfCache(log, cachepath)
+	newcache := map[string]string{}
+
+	type pdfJob struct {
+		script   string
+		jsonstem string
+		outfile  string
+		hash     string
+	}
+	jobs := []pdfJob{}
+	for _, tt := range typst_files {
+		tfile := tt.Name
+		hash, err := pdfHash(root, datadir, tt.Script, tt.Data)
+		if err != nil {
+			// Let Typst report the problem
+			log.Warning.Printf("PDF cache (%s): %v\n", tfile, err)
+		}
+		pdfpath := filepath.Join(datadir, "_pdf", tfile+".pdf")
+		if !force && hash != "" && cache[tfile] == hash {
+			if _, err := os.Stat(pdfpath); err == nil {
+				log.Message.Printf("Unchanged, not rebuilt: %s\n", pdfpath)
+				newcache[tfile] = hash
+				continue
+			}
+		}
+		jobs = append(jobs, pdfJob{tt.Script, tt.Data, tfile, hash})
+	}
+
+	// The JSON files are copied before starting the workers, as several
+	// tables can share one file.
+	copied := map[string]bool{}
+	for _, job := range jobs {
+		if !copied[job.jsonstem] {
+			copied[job.jsonstem] = true
+			if err := root.addData(datadir, job.jsonstem); err != nil {
+				// Let Typst report the problem
+				log.Warning.Printf("Typst data: %v\n", err)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := []error{}
+	jobchan := make(chan pdfJob)
+	for range min(workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobchan {
+				err := makePdf(root,
+					job.script, datadir, job.jsonstem, job.outfile, typst)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else if job.hash != "" {
+					newcache[job.outfile] = job.hash
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		jobchan <- job
+	}
+	close(jobchan)
+	wg.Wait()
+
+	// Keep the entries for tables which were not handled in this run.
+	names := Names(typst_files)
+	for tfile, hash := range cache {
+		_, ok := newcache[tfile]
+		if !ok && !slices.Contains(names, tfile) {
+			newcache[tfile] = hash
+		}
+	}
+	writePdfCache(log, cachepath, newcache)
+	if len(errs) != 0 {
+		log.Error.Fatal(errors.Join(errs...))
+	}
+	return len(jobs)
+}
+
+// pdfHash returns a hash of the inputs for a PDF file: the Typst script,
+// the JSON data and the list of the font files (with their sizes and
+// modification times).
+func pdfHash(
+	root *typstRoot,
+	datadir string,
+	script string,
+	jsonstem string,
+) (string, error) {
+	h := sha256.New()
+	if err := hashFonts(h, root.fonts); err != nil {
+		return "", err
+	}
+	for _, f := range []string{
+		root.script(script),
+		filepath.Join(datadir, "_data", jsonstem+".json"),
+	} {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(filepath.Base(f)))
+		h.Write([]byte{0})
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFonts adds the names, sizes and modification times of the files in
+// the font folder to the hash. A missing folder is not an error.
+func hashFonts(h io.Writer, fontdir string) error {
+	err := filepath.WalkDir(fontdir, func(
+		path string, d fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(fontdir, path)
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00",
+			rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func readPdfCache(log *base.LogSet, cachepath string) map[string]string {
+	cache := map[string]string{}
+	b, err := os.ReadFile(cachepath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warning.Printf("PDF cache not read: %v\n", err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(b, &cache); err != nil {
+		log.Warning.Printf("PDF cache invalid, ignored: %s\n", cachepath)
+		return map[string]string{}
+	}
+	return cache
+}
+
+func writePdfCache(
+	log *base.LogSet,
+	cachepath string,
+	cache map[string]string,
+) {
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		log.Error.Fatal(err)
+	}
+	if err := os.WriteFile(cachepath, b, 0666); err != nil {
+		log.Warning.Printf("PDF cache not written: %v\n", err)
+	}
+}
@@ -11,6 +11,14 @@ func getClasses(
 	datadir string,
 	stemfile string, // basic name part of source file
 ) string {
+	tt := classTimetable(ttinfo)
+	f := stemfile + "_classes"
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+// classTimetable collects the class timetables, one page for each class.
+func classTimetable(ttinfo *ttbase.TtInfo) Timetable {
 	data := getClassData(ttinfo)
 	pages := []ttPage{}
 	for _, c := range ttinfo.Db.Classes {
@@ -27,10 +35,7 @@ func getClasses(
 			Activities: tiles,
 		})
 	}
-	tt := timetable(ttinfo.Db, pages, "Class")
-	f := stemfile + "_classes"
-	makeTypstJson(tt, datadir, f)
-	return f
+	return timetable(ttinfo.Db, pages, "Class")
 }
 
 func getOneClass(
@@ -53,7 +58,7 @@ func getOneClass(
 	}
 	tt := timetable(ttinfo.Db, pages, "Class")
 	f := stemfile + "_class_" + e.Tag
-	makeTypstJson(tt, datadir, f)
+	makeTypstJson(ttinfo, tt, datadir, f)
 	return f
 }
 
@@ -67,6 +72,7 @@ func getClassData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 	}
 	// Generate the tiles.
 	classTiles := map[base.Ref][]Tile{}
+	cs := newColourScheme(db)
 	for cref, cinfo := range ttinfo.CourseInfo {
 		subject := ref2id[cinfo.Subject]
 		// For SuperCourses gather the resources from the relevant SubCourses.
@@ -147,7 +153,7 @@ func getClassData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 							}
 							continue
 						}
-						base.Bug.Fatalf("Not a room: %s\n", rref)
+						ttinfo.Db.Log.Bug.Fatalf("Not a room: %s\n", rref)
 					}
 
 					// The groups need special handling, to determine tile
@@ -159,17 +165,18 @@ func getClassData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 					for _, chip := range chips {
 						gstrings := append(chip.Groups, chip.ExtraGroups...)
 						tile := Tile{
-							Day:        l.Day,
-							Hour:       l.Hour,
-							Duration:   l.Duration,
-							Fraction:   chip.Fraction,
-							Offset:     chip.Offset,
-							Total:      chip.Total,
-							Subject:    subject,
-							Groups:     gstrings,
-							Teachers:   tstrings,
-							Rooms:      rstrings,
-							Background: l.Background,
+							Day:      l.Day,
+							Hour:     l.Hour,
+							Duration: l.Duration,
+							Fraction: chip.Fraction,
+							Offset:   chip.Offset,
+							Total:    chip.Total,
+							Subject:  subject,
+							Groups:   gstrings,
+							Teachers: tstrings,
+							Rooms:    rstrings,
+							Background: cs.background(l,
+								cinfo.Subject, tlist, []base.Ref{cref}),
 						}
 						classTiles[cref] = append(classTiles[cref], tile)
 					}
@@ -201,17 +208,18 @@ func getClassData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 					for _, chip := range chips {
 						gstrings := append(chip.Groups, chip.ExtraGroups...)
 						tile := Tile{
-							Day:        l.Day,
-							Hour:       l.Hour,
-							Duration:   l.Duration,
-							Fraction:   chip.Fraction,
-							Offset:     chip.Offset,
-							Total:      chip.Total,
-							Subject:    subject,
-							Groups:     gstrings,
-							Teachers:   tstrings,
-							Rooms:      rstrings,
-							Background: l.Background,
+							Day:      l.Day,
+							Hour:     l.Hour,
+							Duration: l.Duration,
+							Fraction: chip.Fraction,
+							Offset:   chip.Offset,
+							Total:    chip.Total,
+							Subject:  subject,
+							Groups:   gstrings,
+							Teachers: tstrings,
+							Rooms:    rstrings,
+							Background: cs.background(l,
+								cinfo.Subject, tlist, []base.Ref{cref}),
 						}
 						classTiles[cref] = append(classTiles[cref], tile)
 					}
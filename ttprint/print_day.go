@@ -0,0 +1,97 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+)
+
+// Day views: a page for each day with a column for each class (table type
+// "Day") or teacher (table type "Day_Teacher") and a row for each hour.
+// The columns are listed in Info.Columns, the "day" field of the tiles is
+// the index of the column.
+
+type ttColumn struct {
+	Name  string
+	Short string
+}
+
+func getDayClasses(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+) string {
+	tt := dayClassTimetable(ttinfo)
+	f := stemfile + "_classes_day"
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+func getDayTeachers(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+) string {
+	tt := dayTeacherTimetable(ttinfo)
+	f := stemfile + "_teachers_day"
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+func dayClassTimetable(ttinfo *ttbase.TtInfo) Timetable {
+	columns := []ttColumn{}
+	refs := []base.Ref{}
+	for _, c := range ttinfo.Db.Classes {
+		if c.Tag == "" {
+			continue
+		}
+		columns = append(columns, ttColumn{Name: c.Name, Short: c.Tag})
+		refs = append(refs, c.Id)
+	}
+	return dayTimetable(ttinfo, getClassData(ttinfo), columns, refs, "Day")
+}
+
+func dayTeacherTimetable(ttinfo *ttbase.TtInfo) Timetable {
+	columns := []ttColumn{}
+	refs := []base.Ref{}
+	for _, t := range ttinfo.Db.Teachers {
+		columns = append(columns, ttColumn{
+			Name:  t.Firstname + " " + t.Name,
+			Short: t.Tag,
+		})
+		refs = append(refs, t.Id)
+	}
+	return dayTimetable(
+		ttinfo, getTeacherData(ttinfo), columns, refs, "Day_Teacher")
+}
+
+// dayTimetable rearranges the tiles of the classes or teachers (refs, with
+// the corresponding columns) into day pages.
+func dayTimetable(
+	ttinfo *ttbase.TtInfo,
+	data map[base.Ref][]Tile,
+	columns []ttColumn,
+	refs []base.Ref,
+	tabletype string,
+) Timetable {
+	db := ttinfo.Db
+	pages := []ttPage{}
+	for d, day := range db.Days {
+		tiles := []Tile{}
+		for i, ref := range refs {
+			for _, tile := range data[ref] {
+				if tile.Day == d {
+					tile.Day = i
+					tiles = append(tiles, tile)
+				}
+			}
+		}
+		pages = append(pages, ttPage{
+			Name:       day.Name,
+			Short:      day.Tag,
+			Activities: tiles,
+		})
+	}
+	tt := timetable(db, pages, tabletype)
+	tt.Info["Columns"] = columns
+	return tt
+}
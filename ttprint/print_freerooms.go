@@ -0,0 +1,80 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+)
+
+// A table of the free rooms: a page with the weekly grid, each slot lists
+// the rooms which are free at that time. The print table entry is
+// "FreeRooms", or "FreeRooms:" followed by the Id or tag of a room group
+// or room-choice group to restrict the rooms.
+
+func getFreeRooms(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+	within string, // room group (Id or tag), can be empty
+) string {
+	var wref base.Ref
+	name := "Freie Räume"
+	short := ""
+	if within != "" {
+		wref, name, short = findRoomGroup(ttinfo.Db, within)
+		if wref == "" {
+			ttinfo.Db.Log.Error.Printf(
+				"Print table FreeRooms – unknown room group: %s\n", within)
+			return ""
+		}
+		name = "Freie Räume – " + name
+	}
+	table, err := ttinfo.FreeRoomTable(wref)
+	if err != nil {
+		ttinfo.Db.Log.Error.Printf("Print table FreeRooms: %v\n", err)
+		return ""
+	}
+	tiles := []Tile{}
+	for p, rooms := range table {
+		if len(rooms) == 0 {
+			continue
+		}
+		tiles = append(tiles, Tile{
+			Day:   p / ttinfo.NHours,
+			Hour:  p % ttinfo.NHours,
+			Rooms: ttinfo.SortList(rooms),
+		})
+	}
+	pages := []ttPage{{Name: name, Short: short, Activities: tiles}}
+	tt := timetable(ttinfo.Db, pages, "FreeRooms")
+	f := stemfile + "_freerooms"
+	if short != "" {
+		f += "_" + fileTag(short)
+	}
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+// findRoomGroup looks for a RoomGroup or RoomChoiceGroup by Id or tag. It
+// returns the Id, name and tag, the Id is empty if there is no such group.
+func findRoomGroup(
+	db *base.DbTopLevel,
+	key string,
+) (base.Ref, string, string) {
+	switch r := db.Elements[base.Ref(key)].(type) {
+	case *base.RoomGroup:
+		return r.Id, r.Name, r.Tag
+	case *base.RoomChoiceGroup:
+		return r.Id, r.Name, r.Tag
+	}
+	for _, r := range db.RoomGroups {
+		if r.Tag == key {
+			return r.Id, r.Name, r.Tag
+		}
+	}
+	for _, r := range db.RoomChoiceGroups {
+		if r.Tag == key {
+			return r.Id, r.Name, r.Tag
+		}
+	}
+	return "", "", ""
+}
@@ -0,0 +1,295 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Timetables for the pupils of a class division: a page for each atomic
+// group, or for a chosen combination of groups of a class. Only the
+// activities involving the atomic group(s) are shown.
+//
+// A combination is specified in the print tables as "Group:" followed by
+// the class tag and the tags of the chosen groups, separated by ".", e.g.
+// "Group:10.A.X". With only a class tag, e.g. "Group:10", there is a page
+// for each atomic group of the class.
+
+// A groupSelection is the basis for a page: one atomic group or the atomic
+// groups which have all of a chosen set of groups.
+type groupSelection struct {
+	name  string
+	short string
+	ags   []ttbase.ResourceIndex
+}
+
+func getGroups(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+) string {
+	tt := groupTimetable(ttinfo, allGroups(ttinfo))
+	f := stemfile + "_groups"
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+func getSelectedGroups(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+	spec string, // class tag and optional group tags, e.g. "10.A.X"
+) string {
+	sels, err := selectGroups(ttinfo, spec)
+	if err != nil {
+		ttinfo.Db.Log.Error.Printf("Print table Group:%s – %v\n", spec, err)
+		return ""
+	}
+	tt := groupTimetable(ttinfo, sels)
+	f := stemfile + "_group_" + fileTag(spec)
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+// allGroups returns a selection for each atomic group of each class.
+func allGroups(ttinfo *ttbase.TtInfo) []groupSelection {
+	sels := []groupSelection{}
+	for _, c := range ttinfo.Db.Classes {
+		if c.Tag == "" {
+			continue
+		}
+		sels = append(sels, classAtomicGroups(ttinfo, c)...)
+	}
+	return sels
+}
+
+func classAtomicGroups(
+	ttinfo *ttbase.TtInfo,
+	c *base.Class,
+) []groupSelection {
+	sels := []groupSelection{}
+	for _, ag := range ttinfo.AtomicGroups[c.ClassGroup] {
+		sels = append(sels, groupPage(ttinfo, c, ag.Groups,
+			[]ttbase.ResourceIndex{ag.Index}))
+	}
+	return sels
+}
+
+// groupPage builds a selection with readable names (rather than the tags
+// of the atomic groups, which are only for internal use).
+func groupPage(
+	ttinfo *ttbase.TtInfo,
+	c *base.Class,
+	groups []base.Ref,
+	ags []ttbase.ResourceIndex,
+) groupSelection {
+	gtags := []string{}
+	for _, gref := range groups {
+		gtags = append(gtags, ttinfo.Db.Elements[gref].(*base.Group).Tag)
+	}
+	name := c.Name
+	if name == "" {
+		name = c.Tag
+	}
+	short := c.Tag
+	if len(gtags) != 0 {
+		name += " (" + strings.Join(gtags, ", ") + ")"
+		short += ttbase.CLASS_GROUP_SEP +
+			strings.Join(gtags, ttbase.CLASS_GROUP_SEP)
+	}
+	return groupSelection{name: name, short: short, ags: ags}
+}
+
+// selectGroups handles a class, possibly with a combination of groups.
+func selectGroups(
+	ttinfo *ttbase.TtInfo,
+	spec string,
+) ([]groupSelection, error) {
+	db := ttinfo.Db
+	tags := strings.Split(spec, ttbase.CLASS_GROUP_SEP)
+	var class *base.Class
+	for _, c := range db.Classes {
+		if c.Tag == tags[0] {
+			class = c
+			break
+		}
+	}
+	if class == nil {
+		return nil, fmt.Errorf("unknown class: %s", tags[0])
+	}
+	if len(tags) == 1 {
+		return classAtomicGroups(ttinfo, class), nil
+	}
+	// Find the groups, they must be in the (used) divisions of the class.
+	groups := []base.Ref{}
+	for _, gtag := range tags[1:] {
+		var gref base.Ref
+		for _, div := range ttinfo.ClassDivisions[class.Id] {
+			for _, g := range div {
+				if db.Elements[g].(*base.Group).Tag == gtag {
+					gref = g
+				}
+			}
+		}
+		if gref == "" {
+			return nil, fmt.Errorf("group %s not found (or not used)"+
+				" in class %s", gtag, class.Tag)
+		}
+		groups = append(groups, gref)
+	}
+	ags := []ttbase.ResourceIndex{}
+	for _, ag := range ttinfo.AtomicGroups[class.ClassGroup] {
+		ok := true
+		for _, gref := range groups {
+			if !slices.Contains(ag.Groups, gref) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ags = append(ags, ag.Index)
+		}
+	}
+	if len(ags) == 0 {
+		return nil, errors.New("no pupils in all of these groups")
+	}
+	return []groupSelection{groupPage(ttinfo, class, groups, ags)}, nil
+}
+
+// activityAtomicGroups returns the atomic groups involved in an activity.
+// These are in the activity's Resources, but if PrepareCoreData has not
+// been called, they must be found via the course's groups.
+func activityAtomicGroups(
+	ttinfo *ttbase.TtInfo,
+	a *ttbase.Activity,
+) []ttbase.ResourceIndex {
+	ags := []ttbase.ResourceIndex{}
+	if len(ttinfo.Resources) != 0 {
+		for _, rix := range a.Resources {
+			if rix < ttinfo.NAtomicGroups {
+				ags = append(ags, rix)
+			}
+		}
+		return ags
+	}
+	for _, gref := range a.CourseInfo.Groups {
+		for _, ag := range ttinfo.AtomicGroups[gref] {
+			ags = append(ags, ag.Index)
+		}
+	}
+	return ags
+}
+
+// groupTimetable collects the timetables, one page for each selection.
+func groupTimetable(
+	ttinfo *ttbase.TtInfo,
+	sels []groupSelection,
+) Timetable {
+	db := ttinfo.Db
+	cs := newColourScheme(db)
+	pages := []ttPage{}
+	for _, sel := range sels {
+		in := func(agixs []ttbase.ResourceIndex) bool {
+			for _, agix := range agixs {
+				if slices.Contains(sel.ags, agix) {
+					return true
+				}
+			}
+			return false
+		}
+		tiles := []Tile{}
+		for aix := 1; aix < len(ttinfo.Activities); aix++ {
+			a := ttinfo.Activities[aix]
+			l := a.Lesson
+			if l.Day < 0 || !in(activityAtomicGroups(ttinfo, a)) {
+				continue
+			}
+			cinfo := a.CourseInfo
+			glist := []base.Ref{}
+			tlist := []base.Ref{}
+			rlist := []base.Ref{}
+			sc, ok := db.Elements[cinfo.Id].(*base.SuperCourse)
+			if ok {
+				// Only the relevant SubCourses
+				for _, subref := range sc.SubCourses {
+					sub := db.Elements[subref].(*base.SubCourse)
+					subags := []ttbase.ResourceIndex{}
+					for _, gref := range sub.Groups {
+						for _, ag := range ttinfo.AtomicGroups[gref] {
+							subags = append(subags, ag.Index)
+						}
+					}
+					if !in(subags) {
+						continue
+					}
+					glist = append(glist, sub.Groups...)
+					tlist = append(tlist, sub.Teachers...)
+					if sub.Room != "" {
+						rlist = append(rlist,
+							lessonRooms(db, l.Rooms, sub.Room)...)
+					}
+				}
+			} else {
+				glist = append(glist, cinfo.Groups...)
+				tlist = append(tlist, cinfo.Teachers...)
+				rlist = append(rlist, l.Rooms...)
+			}
+			tiles = append(tiles, Tile{
+				Day:      l.Day,
+				Hour:     l.Hour,
+				Duration: l.Duration,
+				Subject:  ttinfo.Ref2Tag[cinfo.Subject],
+				Groups:   ttinfo.SortList(uniqueRefs(glist)),
+				Teachers: ttinfo.SortList(uniqueRefs(tlist)),
+				Rooms:    ttinfo.SortList(uniqueRefs(rlist)),
+				Background: cs.background(l, cinfo.Subject, tlist,
+					groupClasses(db, glist)),
+			})
+		}
+		pages = append(pages, ttPage{
+			Name:       sel.name,
+			Short:      sel.short,
+			Activities: tiles,
+		})
+	}
+	return timetable(db, pages, "Group")
+}
+
+// lessonRooms returns those rooms of a lesson which are covered by the
+// given room (Room, RoomGroup or RoomChoiceGroup).
+func lessonRooms(
+	db *base.DbTopLevel,
+	rooms []base.Ref,
+	rref base.Ref,
+) []base.Ref {
+	rlist := []base.Ref{}
+	switch r := db.Elements[rref].(type) {
+	case *base.Room:
+		if slices.Contains(rooms, rref) {
+			rlist = append(rlist, rref)
+		}
+	case *base.RoomGroup:
+		for _, rr := range r.Rooms {
+			if slices.Contains(rooms, rr) {
+				rlist = append(rlist, rr)
+			}
+		}
+	case *base.RoomChoiceGroup:
+		for _, rr := range r.Rooms {
+			if slices.Contains(rooms, rr) {
+				rlist = append(rlist, rr)
+			}
+		}
+	default:
+		db.Log.Bug.Fatalf("Not a room: %s\n", rref)
+	}
+	return rlist
+}
+
+func uniqueRefs(refs []base.Ref) []base.Ref {
+	slices.Sort(refs)
+	return slices.Compact(refs)
+}
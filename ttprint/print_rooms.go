@@ -10,6 +10,14 @@ func getRooms(
 	datadir string,
 	stemfile string, // basic name part of source file
 ) string {
+	tt := roomTimetable(ttinfo)
+	f := stemfile + "_rooms"
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+// roomTimetable collects the room timetables, one page for each room.
+func roomTimetable(ttinfo *ttbase.TtInfo) Timetable {
 	data := getRoomData(ttinfo)
 	pages := []ttPage{}
 	for _, r := range ttinfo.Db.Rooms {
@@ -23,10 +31,7 @@ func getRooms(
 			Activities: rtiles,
 		})
 	}
-	tt := timetable(ttinfo.Db, pages, "Room")
-	f := stemfile + "_rooms"
-	makeTypstJson(tt, datadir, f)
-	return f
+	return timetable(ttinfo.Db, pages, "Room")
 }
 
 func getOneRoom(
@@ -49,7 +54,7 @@ func getOneRoom(
 	}
 	tt := timetable(ttinfo.Db, pages, "Room")
 	f := stemfile + "_room_" + e.Tag
-	makeTypstJson(tt, datadir, f)
+	makeTypstJson(ttinfo, tt, datadir, f)
 	return f
 }
 
@@ -57,6 +62,7 @@ func getRoomData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 	db := ttinfo.Db
 	// Generate the tiles.
 	roomTiles := map[base.Ref][]Tile{}
+	cs := newColourScheme(db)
 	type rdata struct { // for SuperCourses
 		groups   map[base.Ref]bool
 		teachers map[base.Ref]bool
@@ -85,8 +91,9 @@ func getRoomData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 							if ok {
 								rlist = append(rlist, r.Rooms...)
 							} else {
-								base.Bug.Fatalf("Invalid room in course %s:"+
-									"\n  %+v\n", ttinfo.View(cinfo), r0)
+								ttinfo.Db.Log.Bug.Fatalf(
+									"Invalid room in course %s:"+
+										"\n  %+v\n", ttinfo.View(cinfo), r0)
 							}
 						}
 					}
@@ -139,7 +146,8 @@ func getRoomData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 						Subject:  subject,
 						Groups:   gstrings,
 						Teachers: tstrings,
-						//TODO: Background:
+						Background: cs.background(l, cinfo.Subject,
+							tlist, groupClasses(db, glist)),
 					}
 					roomTiles[rref] = append(roomTiles[rref], tile)
 				}
@@ -170,7 +178,8 @@ func getRoomData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 						Subject:  subject,
 						Groups:   gstrings,
 						Teachers: tstrings,
-						//TODO: Background:
+						Background: cs.background(l, cinfo.Subject,
+							tlist, groupClasses(db, glist)),
 					}
 					roomTiles[rref] = append(roomTiles[rref], tile)
 				}
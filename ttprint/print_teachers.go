@@ -11,6 +11,14 @@ func getTeachers(
 	datadir string,
 	stemfile string, // basic name part of source file
 ) string {
+	tt := teacherTimetable(ttinfo)
+	f := stemfile + "_teachers"
+	makeTypstJson(ttinfo, tt, datadir, f)
+	return f
+}
+
+// teacherTimetable collects the teacher timetables, one page for each teacher.
+func teacherTimetable(ttinfo *ttbase.TtInfo) Timetable {
 	data := getTeacherData(ttinfo)
 	pages := []ttPage{}
 	for _, t := range ttinfo.Db.Teachers {
@@ -24,10 +32,7 @@ func getTeachers(
 			Activities: ttiles,
 		})
 	}
-	tt := timetable(ttinfo.Db, pages, "Teacher")
-	f := stemfile + "_teachers"
-	makeTypstJson(tt, datadir, f)
-	return f
+	return timetable(ttinfo.Db, pages, "Teacher")
 }
 
 func getOneTeacher(
@@ -50,7 +55,7 @@ func getOneTeacher(
 	}
 	tt := timetable(ttinfo.Db, pages, "Teacher")
 	f := stemfile + "_teacher_" + e.Tag
-	makeTypstJson(tt, datadir, f)
+	makeTypstJson(ttinfo, tt, datadir, f)
 	return f
 }
 
@@ -58,6 +63,7 @@ func getTeacherData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 	db := ttinfo.Db
 	// Generate the tiles.
 	teacherTiles := map[base.Ref][]Tile{}
+	cs := newColourScheme(db)
 	type tdata struct { // for SuperCourses
 		groups   map[base.Ref]bool
 		rooms    map[base.Ref]bool
@@ -141,7 +147,7 @@ func getTeacherData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 							}
 							continue
 						}
-						base.Bug.Fatalf("Not a room: %s\n", rref)
+						ttinfo.Db.Log.Bug.Fatalf("Not a room: %s\n", rref)
 					}
 					gstrings := ttinfo.SortList(glist)
 					tstrings := ttinfo.SortList(tlist)
@@ -157,6 +163,9 @@ func getTeacherData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 						Groups:   gstrings,
 						Teachers: tstrings,
 						Rooms:    rstrings,
+						Background: cs.background(l, cinfo.Subject,
+							append([]base.Ref{tref}, tlist...),
+							groupClasses(db, glist)),
 					}
 					teacherTiles[tref] = append(teacherTiles[tref], tile)
 				}
@@ -199,6 +208,9 @@ func getTeacherData(ttinfo *ttbase.TtInfo) map[base.Ref][]Tile {
 						Groups:   gstrings,
 						Teachers: tstrings,
 						Rooms:    rstrings,
+						Background: cs.background(l, cinfo.Subject,
+							append([]base.Ref{tref}, tlist...),
+							groupClasses(db, glist)),
 					}
 					teacherTiles[tref] = append(teacherTiles[tref], tile)
 				}
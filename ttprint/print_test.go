@@ -4,11 +4,14 @@ import (
 	"W365toFET/base"
 	"W365toFET/fet"
 	"W365toFET/ttbase"
-	"bufio"
+	"W365toFET/typst_files"
+	"archive/zip"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -22,95 +25,700 @@ func TestPrint(t *testing.T) {
 	base.OpenLog("")
 	datadir, err := filepath.Abs("../typst_files/")
 	if err != nil {
-		base.Error.Fatal(err)
+		t.Fatal(err)
 	}
 	fmt.Println("\n############## TestPrint")
 	for _, f := range inputfiles {
-		f, err := filepath.Abs(f)
+		fmt.Println("\n ++++++++++++++++++++++")
+		// Note that times loaded from the activities.xml file are not checked
+		// by PrepareCoreData!
+		ttinfo, stempath := loadTestData(t, f)
+		doPrinting(ttinfo, datadir, stempath)
+	}
+}
+
+func TestICalendar(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestICalendar")
+	term, err := ParseTerm(
+		"2025-09-08:2026-07-24", "2025-10-27:2025-10-31,2025-12-22:2026-01-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	datadir := t.TempDir()
+	for _, f := range inputfiles {
+		fmt.Println("\n ++++++++++++++++++++++")
+		ttinfo, stempath := loadTestData(t, f)
+		icsfiles := GenICalendars(
+			ttinfo, datadir, filepath.Base(stempath), term)
+		if len(icsfiles) == 0 {
+			t.Errorf("No iCalendar files for %s", f)
+			continue
+		}
+		b, err := os.ReadFile(icsfiles[0])
 		if err != nil {
-			base.Error.Fatal(err)
+			t.Fatal(err)
 		}
+		fmt.Printf("%s: %d files, %d events in %s\n",
+			filepath.Base(f), len(icsfiles),
+			strings.Count(string(b), "BEGIN:VEVENT"),
+			filepath.Base(icsfiles[0]))
+	}
+}
+
+func TestHtml(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestHtml")
+	for _, f := range inputfiles {
 		fmt.Println("\n ++++++++++++++++++++++")
-		db := base.LoadDb(f)
-		db.PrepareDb()
-		ttinfo := ttbase.MakeTtInfo(db)
+		ttinfo, _ := loadTestData(t, f)
+		db := ttinfo.Db
+		htmldir := filepath.Join(t.TempDir(), "html")
+		hfiles := GenHtml(ttinfo, htmldir)
+		fmt.Printf("%s: %d HTML files\n", filepath.Base(f), len(hfiles))
 
-		// Disabling this will prevent testing of placements.
-		// Note that times loaded from the activities.xml file are not checked
-		// anyway!
-		ttinfo.PrepareCoreData()
+		// A page for each class, teacher and room with activities, an
+		// overview for each type and the index, which links to all pages
+		npages := 0
+		links := []string{}
+		for _, tt := range []Timetable{
+			classTimetable(ttinfo),
+			teacherTimetable(ttinfo),
+			roomTimetable(ttinfo),
+		} {
+			kind := strings.ToLower(tt.TableType)
+			links = append(links, kind+"_overview.html")
+			for i := range tt.Pages {
+				links = append(links, fmt.Sprintf("%s_%d.html", kind, i+1))
+			}
+			npages += len(tt.Pages)
+		}
+		if npages == 0 ||
+			npages > len(db.Classes)+len(db.Teachers)+len(db.Rooms) {
+			t.Errorf("%s: %d pages", filepath.Base(f), npages)
+		}
+		if len(hfiles) != npages+4 {
+			t.Errorf("%s: %d HTML files, expected %d",
+				filepath.Base(f), len(hfiles), npages+4)
+		}
+		b, err := os.ReadFile(filepath.Join(htmldir, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, link := range links {
+			if !strings.Contains(string(b), `href="`+link+`"`) {
+				t.Errorf("%s: index.html has no link to %s",
+					filepath.Base(f), link)
+			}
+			if !slices.Contains(hfiles, filepath.Join(htmldir, link)) {
+				t.Errorf("%s: %s not written", filepath.Base(f), link)
+			}
+		}
+	}
+}
 
-		stempath := strings.TrimSuffix(f, filepath.Ext(f))
-		stempath = strings.TrimSuffix(stempath, "_db")
-		doPrinting(ttinfo, datadir, stempath)
+func TestXlsx(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestXlsx")
+	for _, f := range inputfiles {
+		fmt.Println("\n ++++++++++++++++++++++")
+		ttinfo, _ := loadTestData(t, f)
+		xlsxpath := filepath.Join(t.TempDir(), "overview.xlsx")
+		if !GenXlsx(ttinfo, xlsxpath) {
+			t.Fatalf("No XLSX file for %s", f)
+		}
+		// Check that all parts are well-formed XML.
+		zr, err := zip.OpenReader(xlsxpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, zf := range zr.File {
+			r, err := zf.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := xml.NewDecoder(r)
+			for {
+				_, err := d.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", zf.Name, err)
+				}
+			}
+			r.Close()
+			fmt.Printf("  -- %s\n", zf.Name)
+		}
+		zr.Close()
 	}
 }
 
-func doPrinting(ttinfo *ttbase.TtInfo, datadir string, stempath string) {
-	// Get activity mapping
-	mapfile := stempath + ".map"
-	activityMap := readActivityMap(mapfile)
+func TestSvg(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestSvg")
+	for _, f := range inputfiles {
+		fmt.Println("\n ++++++++++++++++++++++")
+		ttinfo, stempath := loadTestData(t, f)
+		svgfiles := GenSvg(ttinfo, t.TempDir(), filepath.Base(stempath))
+		// Check that the files are well-formed XML.
+		for _, svgfile := range svgfiles {
+			b, err := os.ReadFile(svgfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var x struct{}
+			if err := xml.Unmarshal(b, &x); err != nil {
+				t.Fatalf("%s: %v", svgfile, err)
+			}
+		}
+		fmt.Printf("%s: %d SVG files\n", filepath.Base(f), len(svgfiles))
+	}
+}
 
-	// Get placements
-	pfile := stempath + "_activities.xml"
-	pmap := map[base.Ref]fet.ActivityPlacement{}
-	for _, p := range fet.ReadPlacements(ttinfo, pfile) {
-		//fmt.Printf("### %+v\n  -- %s\n", p, activityMap[p.Id])
-		pmap[activityMap[p.Id]] = p
+func TestGroups(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestGroups")
+	ttinfo, _ := loadTestData(t, inputfiles[0])
+
+	tt := groupTimetable(ttinfo, allGroups(ttinfo))
+	for _, p := range tt.Pages {
+		fmt.Printf("  -- %s / %s: %d\n", p.Short, p.Name, len(p.Activities))
+	}
+	// The class timetables contain the tiles of all groups.
+	ctt := classTimetable(ttinfo)
+	for _, p := range ctt.Pages {
+		sels, err := selectGroups(ttinfo, p.Short)
+		if err != nil {
+			t.Errorf("Class %s: %v", p.Short, err)
+		}
+		for _, sel := range sels {
+			n := len(groupTimetable(
+				ttinfo, []groupSelection{sel}).Pages[0].Activities)
+			if n > len(p.Activities) {
+				t.Errorf("Group %s has more activities than the class", sel.short)
+			}
+		}
+	}
+	// An unknown group is reported, there is no table
+	spec := ctt.Pages[0].Short + ttbase.CLASS_GROUP_SEP + "ZZZ"
+	if _, err := selectGroups(ttinfo, spec); err == nil {
+		t.Errorf("Group %s: no error", spec)
+	}
+	if f := getSelectedGroups(ttinfo, t.TempDir(), "x", spec); f != "" {
+		t.Errorf("Group %s: table %s", spec, f)
+	}
+}
+
+func TestLegend(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestLegend")
+	ttinfo, _ := loadTestData(t, inputfiles[0])
+	db := ttinfo.Db
+
+	// Subjects without a different name are not in the legends
+	snames := map[string]string{}
+	for _, s := range db.Subjects {
+		snames[s.Tag] = s.Name
+	}
+	for _, tt := range []Timetable{
+		classTimetable(ttinfo),
+		teacherTimetable(ttinfo),
+		roomTimetable(ttinfo),
+	} {
+		p := tt.Pages[0]
+		fmt.Printf("  -- %s %s: %+v\n", tt.TableType, p.Short, p.Legend)
+		for _, a := range p.Activities {
+			if snames[a.Subject] == a.Subject || snames[a.Subject] == "" {
+				continue
+			}
+			if !slices.ContainsFunc(p.Legend.Subjects,
+				func(item LegendItem) bool {
+					return item.Short == a.Subject
+				}) {
+				t.Errorf("Subject %s not in legend", a.Subject)
+			}
+		}
+	}
+}
+
+func TestDay(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestDay")
+	ttinfo, _ := loadTestData(t, inputfiles[0])
+	db := ttinfo.Db
+
+	// All the tiles of the class (teacher) tables must be in the day tables.
+	for _, tts := range [][2]Timetable{
+		{classTimetable(ttinfo), dayClassTimetable(ttinfo)},
+		{teacherTimetable(ttinfo), dayTeacherTimetable(ttinfo)},
+	} {
+		n, nd := 0, 0
+		for _, p := range tts[0].Pages {
+			n += len(p.Activities)
+		}
+		for _, p := range tts[1].Pages {
+			fmt.Printf("  -- %s %s: %d\n",
+				tts[1].TableType, p.Short, len(p.Activities))
+			nd += len(p.Activities)
+		}
+		if n != nd {
+			t.Errorf("%s: %d tiles, expected %d", tts[1].TableType, nd, n)
+		}
+	}
+
+	// The script is chosen by the table type, not by the file name: a
+	// teacher tagged "day" gets a normal timetable.
+	tch := db.Teachers[0]
+	tch.Tag = "day"
+	db.PrintOptions = base.PrintOptions{PrintTables: []string{
+		"Day", "Day_Teacher", string(tch.Id), "Class_overview"}}
+	tables := GenTypstData(ttinfo, t.TempDir(), "x")
+	if len(tables) != 4 {
+		t.Errorf("%d tables, expected 4", len(tables))
+	}
+	for _, tt := range tables {
+		script := "print_timetable.typ"
+		if tt.Name == "x_classes_overview" {
+			script = "print_overview.typ"
+		}
+		if tt.Script != script || !strings.HasPrefix(tt.Name, tt.Data) {
+			t.Errorf("Table %s: script %s, data %s",
+				tt.Name, tt.Script, tt.Data)
+		}
+	}
+}
+
+func TestFreeRooms(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestFreeRooms")
+	ttinfo, _ := loadTestData(t, inputfiles[0])
+	db := ttinfo.Db
+	// The placements are now in the lessons, rebuild the time slots.
+	ttinfo = ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+
+	table, err := ttinfo.FreeRoomTable("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range db.Rooms {
+		for _, ts := range r.NotAvailable {
+			p := ts.Day*ttinfo.NHours + ts.Hour
+			if slices.Contains(table[p], r.Id) {
+				t.Errorf("Room %s free, but not available @ %d.%d",
+					r.Tag, ts.Day, ts.Hour)
+			}
+		}
 	}
 	for aix := 1; aix < len(ttinfo.Activities); aix++ {
 		a := ttinfo.Activities[aix]
-		l := a.Lesson
-		p, ok := pmap[l.Id]
-		if !ok {
-			fmt.Printf("### %+v\n  -%v- %s\n", p, ok, l.Id)
+		if a.Placement < 0 {
+			continue
+		}
+		// Only the compulsory rooms, the room choices are not necessarily
+		// all placed.
+		for _, rix := range a.Resources {
+			r, ok := ttinfo.Resources[rix].(*base.Room)
+			if !ok {
+				continue
+			}
+			for i := 0; i < a.Duration; i++ {
+				if slices.Contains(table[a.Placement+i], r.Id) {
+					t.Errorf("Room %s free, but used @ %d",
+						r.Tag, a.Placement+i)
+				}
+			}
+		}
+	}
+	for d := 0; d < ttinfo.NDays; d++ {
+		rooms, err := ttinfo.FreeRooms(d, 0, "")
+		if err != nil {
+			t.Fatal(err)
 		}
-		l.Rooms = p.Rooms
-		l.Day = p.Day
-		l.Hour = p.Hour
-		a.Placement = p.Day*ttinfo.NHours + p.Hour
+		fmt.Printf("  -- Day %d, hour 0: %v\n", d, ttinfo.SortList(rooms))
 	}
+	// Restricted to a room group
+	for _, rg := range db.RoomGroups {
+		rtable, err := ttinfo.FreeRoomTable(rg.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p, rooms := range rtable {
+			for _, rref := range rooms {
+				if !slices.Contains(rg.Rooms, rref) ||
+					!slices.Contains(table[p], rref) {
+					t.Errorf("Room %s @ %d not free in group %s",
+						ttinfo.Ref2Tag[rref], p, rg.Tag)
+				}
+			}
+		}
+	}
+	if getFreeRooms(ttinfo, t.TempDir(), "test", "") == "" {
+		t.Error("No free-room table")
+	}
+}
 
-	stemfile := filepath.Base(stempath)
+func TestPdfCache(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestPdfCache")
+	// A fake Typst, which just copies the script to the output file.
+	tmp := t.TempDir()
+	typst := filepath.Join(tmp, "typst")
+	err := os.WriteFile(typst, []byte("#!/bin/sh\n"+
+		"for a; do script=\"$out\"; out=\"$a\"; done\n"+
+		"cat \"$script\" > \"$out\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datadir := filepath.Join(tmp, "typst_files")
+	for f, text := range map[string]string{
+		"scripts/print_timetable.typ": "// script",
+		"scripts/print_overview.typ":  "// overview",
+		"_data/a.json":                "{}",
+		"_data/b.json":                "{}",
+	} {
+		path := filepath.Join(datadir, f)
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err := os.WriteFile(path, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tfiles := []TypstTable{
+		{"a", "a", "print_timetable.typ"},
+		{"b", "b", "print_timetable.typ"},
+		{"b_overview", "b", "print_overview.typ"},
+	}
+	for i, test := range []struct {
+		change string
+		force  bool
+		n      int
+	}{
+		{"", false, 3},
+		{"", false, 0},
+		{"_data/b.json", false, 2},
+		{"scripts/print_overview.typ", false, 1},
+		{"", true, 3},
+		// A new or changed font affects all tables
+		{"_fonts/x.ttf", false, 3},
+		{"_fonts/x.ttf", false, 3},
+		{"", false, 0},
+	} {
+		if test.change != "" {
+			path := filepath.Join(datadir, test.change)
+			os.MkdirAll(filepath.Dir(path), os.ModePerm)
+			f, err := os.OpenFile(path,
+				os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString("\n")
+			f.Close()
+		}
+		// The scripts in datadir override the embedded ones
+		n := MakePdfs(base.DefaultLog,
+			tfiles, datadir, datadir, typst, 2, test.force)
+		fmt.Printf("  -- Run %d: %d PDF files built\n", i, n)
+		if n != test.n {
+			t.Errorf("Run %d: %d PDF files built, expected %d", i, n, test.n)
+		}
+	}
+	pdf := filepath.Join(datadir, "_pdf", "a.pdf")
+	if b, _ := os.ReadFile(pdf); string(b) != "// script" {
+		t.Errorf("Template script not used: %s", b)
+	}
 
-	typst_files := GenTypstData(ttinfo, datadir, stemfile)
+	// Without a template folder the embedded scripts are used
+	MakePdfs(base.DefaultLog, tfiles, datadir, "", typst, 2, false)
+	b, _ := os.ReadFile(pdf)
+	script, err := typst_files.Scripts.ReadFile("scripts/print_timetable.typ")
+	if err != nil || string(b) != string(script) {
+		t.Errorf("Embedded script not used: %v", err)
+	}
+}
 
-	// Generate PDF files
-	typst := "typst"
-	for _, tfile := range typst_files {
-		t, overview := strings.CutSuffix(tfile, "_overview")
-		if overview {
-			MakePdf(
-				"print_overview.typ", datadir, t, tfile, typst)
+func TestColours(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestColours")
+	for _, c := range [][2]string{
+		{"#000000", "#ffffff"},
+		{"#ffff00", "#000000"},
+		{"#3333FF", "#ffffff"},
+		{"#dbd5dd", "#000000"},
+	} {
+		fg, err := contrastColour(c[0])
+		if err != nil || fg != c[1] {
+			t.Errorf("Text colour for %s: %s (%v), expected %s",
+				c[0], fg, err, c[1])
+		}
+	}
+	for _, c := range []string{"yellow", "#112233zz", "#1234", "#12345g"} {
+		if _, err := contrastColour(c); err == nil {
+			t.Errorf("Invalid colour accepted: %s", c)
+		}
+	}
+
+	ttinfo, _ := loadTestData(t, inputfiles[0])
+	db := ttinfo.Db
+
+	colours := map[string]string{}
+	for i, s := range db.Subjects {
+		if i%2 == 0 {
+			s.Color = "#3333ff"
 		} else {
-			MakePdf(
-				"print_timetable.typ", datadir, t, tfile, typst)
+			s.Color = "#ffff66"
+		}
+		colours[s.Tag] = s.Color
+	}
+	for _, scheme := range []string{"subject", "none", ""} {
+		db.PrintOptions.ColorScheme = scheme
+		for _, tt := range []Timetable{
+			classTimetable(ttinfo),
+			teacherTimetable(ttinfo),
+			roomTimetable(ttinfo),
+		} {
+			n := 0
+			for _, p := range tt.Pages {
+				for _, a := range p.Activities {
+					switch scheme {
+					case "subject":
+						if a.Background != colours[a.Subject] ||
+							a.Foreground == "" {
+							t.Errorf("%s %s, %s: colours %s / %s",
+								tt.TableType, p.Short, a.Subject,
+								a.Background, a.Foreground)
+						}
+					case "none":
+						if a.Background != "" || a.Foreground != "" {
+							t.Errorf("%s %s, %s: unexpected colours",
+								tt.TableType, p.Short, a.Subject)
+						}
+					}
+					if a.Background != "" {
+						n++
+					}
+				}
+			}
+			fmt.Printf("  -- %q, %s: %d coloured tiles\n",
+				scheme, tt.TableType, n)
 		}
 	}
 }
 
-func readActivityMap(mapfile string) map[int]base.Ref {
-	amap := map[int]base.Ref{}
-	infile, err := os.Open(mapfile)
+func TestPrintOptions(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestPrintOptions")
+	ttinfo, _ := loadTestData(t, inputfiles[0])
+	db := ttinfo.Db
+
+	// The defaults
+	opts, err := ParsePrintOptions(db)
 	if err != nil {
-		base.Error.Fatal(err)
-	}
-	// Remember to close the file at the end of the function
-	defer infile.Close()
-	// Read the file line by line using scanner
-	scanner := bufio.NewScanner(infile)
-	for scanner.Scan() {
-		line := strings.Split(scanner.Text(), ":")
-		iref := line
-		i, err := strconv.Atoi(strings.TrimSpace(iref[0]))
+		t.Errorf("Default options: %v", err)
+	}
+	if len(opts.Tables) != len(defaultPrintTables) {
+		t.Errorf("Default tables: %+v", opts.Tables)
+	}
+
+	cref := string(db.Classes[0].Id)
+	badgroup := "Group:" + db.Classes[0].Tag + ".ZZZ"
+	for i, test := range []struct {
+		tables  []string
+		scheme  string
+		typst   map[string]any
+		nerrors int
+	}{
+		{[]string{"Class", "Room_overview", cref, "Group:" +
+			db.Classes[0].Tag}, "", nil, 0},
+		{[]string{"class", "Day_overview", cref + "_overview", "Class",
+			"Class", "Gruppe", "Group:XXX", badgroup}, "", nil, 7},
+		{nil, "Subjects", nil, 1},
+		{nil, "subject", map[string]any{
+			"PageFormat":         "a3",
+			"OverviewPageFormat": "594 x 420",
+			"Fonts":              "Nunito",
+			"Titles":             map[string]any{"Class": "Klassen"},
+			"PageHeadings":       map[string]any{"Teacher": "%N"},
+			"NoRooms":            true,
+			"FullNames":          true,
+			"FieldPlacements": map[string]any{
+				"Class": map[string]any{"c": "SUBJECT", "br": "ROOM"},
+			},
+			"MyOption": 3,
+		}, 0},
+		{nil, "", map[string]any{
+			"subtitle":        "x",
+			"Subtitel":        "y",
+			"WithTime":        true,
+			"PageFormat":      "A7",
+			"WithBreaks":      "yes",
+			"FullNames":       []any{"TEACHERS"},
+			"FieldPlacements": map[string]any{"x": "SUBJECT"},
+			"Titles":          map[string]any{"Klasse": "Klassen"},
+		}, 7},
+	} {
+		db.PrintOptions = base.PrintOptions{
+			PrintTables: test.tables,
+			ColorScheme: test.scheme,
+			Typst:       test.typst,
+		}
+		opts, err := ParsePrintOptions(db)
+		n := 0
 		if err != nil {
-			base.Error.Fatalf("Invalid line in %s:\n  \"%s\"\n", mapfile, line)
+			n = len(strings.Split(err.Error(), "\n"))
+			fmt.Printf("  -- %d:\n%v\n", i, err)
+		}
+		if n != test.nerrors {
+			t.Errorf("Test %d: %d errors, expected %d", i, n, test.nerrors)
+		}
+		if i == 4 && (opts.Subtitle != "x" || len(opts.Warnings) != 1) {
+			// The old key is accepted, with a warning
+			t.Errorf("Test %d: subtitle %q, warnings %v", i,
+				opts.Subtitle, opts.Warnings)
+		}
+		if i == 3 {
+			if opts.PageSize != (PageSize{420, 297}) ||
+				opts.OverviewSize != (PageSize{594, 420}) ||
+				opts.PageHeadings["Teacher"] != "%N" ||
+				opts.Other["MyOption"] != 3 {
+				t.Errorf("Test %d: %+v", i, opts)
+			}
+			tt := classTimetable(ttinfo)
+			if _, ok := tt.Typst["PageSize"]; !ok {
+				t.Errorf("Test %d: Typst options %+v", i, tt.Typst)
+			}
+			// The scripts expect a single mapping position -> field
+			fmap, _ := tt.Typst["FieldPlacements"].(map[string]any)
+			if fmap["c"] != "SUBJECT" || fmap["br"] != "ROOM" ||
+				len(fmap) != 2 {
+				t.Errorf("Test %d: FieldPlacements %+v", i,
+					tt.Typst["FieldPlacements"])
+			}
+			tt = roomTimetable(ttinfo)
+			if _, ok := tt.Typst["FieldPlacements"]; ok {
+				t.Errorf("Test %d: Room FieldPlacements %+v", i,
+					tt.Typst["FieldPlacements"])
+			}
+			for _, p := range tt.Pages {
+				for _, a := range p.Activities {
+					if len(a.Rooms) != 0 {
+						t.Errorf("Test %d: rooms with NoRooms", i)
+					}
+				}
+			}
+		}
+	}
+	db.PrintOptions = base.PrintOptions{}
+}
+
+func TestFetResult(t *testing.T) {
+	base.OpenLog("")
+	fmt.Println("\n############## TestFetResult")
+	db, stempath := loadTestDb(t, inputfiles[0])
+	stemfile := filepath.Base(stempath)
+
+	// Search the folder (x01 also has a result there)
+	actfile, err := fet.FindActivitiesFile(filepath.Dir(stempath), stemfile)
+	if err != nil || actfile != stempath+"_activities.xml" {
+		t.Fatalf("Activities file: %s (%v)", actfile, err)
+	}
+	_, err = fet.FindActivitiesFile(filepath.Dir(stempath), "xxx")
+	if err == nil {
+		t.Error("Several activities files not reported")
+	}
+	err = fet.ApplyPlacements(db, stempath+".map", actfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+	// Placements which fail the checks are reported and dropped, the
+	// others must be in the timetable.
+	unplaced := 0
+	for aix, a := range ttinfo.Activities {
+		if aix == 0 {
+			continue
+		}
+		if a.Placement < 0 {
+			unplaced++
+			continue
 		}
-		//fmt.Printf("line: %d: %+v\n", i, iref[1])
-		amap[i] = base.Ref(strings.TrimSpace(iref[1]))
+		if a.Placement != a.Lesson.Day*ttinfo.NHours+a.Lesson.Hour {
+			t.Errorf("Activity %d: placement %d, lesson at %d.%d",
+				aix, a.Placement, a.Lesson.Day, a.Lesson.Hour)
+		}
+		for _, rix := range a.Resources {
+			if ttinfo.TtSlots[rix*ttinfo.SlotsPerWeek+a.Placement] !=
+				ttbase.ActivityIndex(aix) {
+				t.Errorf("Activity %d not in timetable", aix)
+				break
+			}
+		}
+	}
+	fmt.Printf("  -- %d activities, %d not placed\n",
+		len(ttinfo.Activities)-1, unplaced)
+	if unplaced*2 > len(ttinfo.Activities) {
+		t.Errorf("%d activities not placed", unplaced)
+	}
+
+	if err := fet.ApplyPlacements(db, stempath+".xxx", actfile); err == nil {
+		t.Error("Missing map file not reported")
 	}
-	if err := scanner.Err(); err != nil {
-		base.Error.Fatal(err)
+}
+
+func doPrinting(ttinfo *ttbase.TtInfo, datadir string, stempath string) {
+	stemfile := filepath.Base(stempath)
+
+	typst_files := GenTypstData(ttinfo, datadir, stemfile)
+
+	// Generate PDF files
+	typst := "typst"
+	for _, tt := range typst_files {
+		MakePdf(ttinfo.Db.Log, tt.Script, datadir, tt.Data, tt.Name, typst)
+	}
+}
+
+// loadTestDb loads and prepares a db from the test data. The path without
+// "_db.json" is also returned.
+func loadTestDb(t *testing.T, f string) (*base.DbTopLevel, string) {
+	t.Helper()
+	f, err := filepath.Abs(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := base.LoadDb(f)
+	db.PrepareDb()
+	stempath := strings.TrimSuffix(f, filepath.Ext(f))
+	stempath = strings.TrimSuffix(stempath, "_db")
+	return db, stempath
+}
+
+// loadTestData loads a db from the test data (see loadTestDb) and makes
+// its TtInfo with the placements from the FET result.
+func loadTestData(t *testing.T, f string) (*ttbase.TtInfo, string) {
+	t.Helper()
+	db, stempath := loadTestDb(t, f)
+	ttinfo := ttbase.MakeTtInfo(db)
+	ttinfo.PrepareCoreData()
+	loadPlacements(t, ttinfo, stempath)
+	return ttinfo, stempath
+}
+
+// loadPlacements reads the placements from the FET result.
+func loadPlacements(t *testing.T, ttinfo *ttbase.TtInfo, stempath string) {
+	t.Helper()
+	err := fet.ApplyPlacements(
+		ttinfo.Db, stempath+".map", stempath+"_activities.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for aix := 1; aix < len(ttinfo.Activities); aix++ {
+		a := ttinfo.Activities[aix]
+		l := a.Lesson
+		if l.Day < 0 {
+			a.Placement = -1
+		} else {
+			a.Placement = l.Day*ttinfo.NHours + l.Hour
+		}
 	}
-	return amap
 }
@@ -0,0 +1,251 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rendering of the individual timetables (classes, teachers, rooms) as SVG
+// files, one for each page, without Typst. The layout follows that of the
+// Typst script "print_timetable.typ" (without the "WithBreaks" variant).
+// All measurements are in mm.
+
+const (
+	SVG_PAGE_WIDTH      = 297.0
+	SVG_PAGE_HEIGHT     = 210.0
+	SVG_PAGE_BORDER     = 15.0
+	SVG_TITLE_HEIGHT    = 15.0
+	SVG_H_HEADER_HEIGHT = 15.0
+	SVG_V_HEADER_WIDTH  = 30.0
+	SVG_BIG_SIZE        = 6.0
+	SVG_NORMAL_SIZE     = 5.0
+	SVG_SMALL_SIZE      = 3.8
+	SVG_FRAME_COLOUR    = "#707070"
+	SVG_HEADER_COLOUR   = "#f0f0f0"
+	SVG_FONT            = "Nunito, 'DejaVu Sans', sans-serif"
+)
+
+// GenSvg writes SVG files for all classes, teachers and rooms to the "_svg"
+// subfolder of datadir. The paths of the files written are returned.
+func GenSvg(
+	ttinfo *ttbase.TtInfo,
+	datadir string,
+	stemfile string, // basic name part of source file
+) []string {
+	outdir := filepath.Join(datadir, "_svg")
+	svgfiles := []string{}
+	for _, tt := range []Timetable{
+		classTimetable(ttinfo),
+		teacherTimetable(ttinfo),
+		roomTimetable(ttinfo),
+	} {
+		svgfiles = append(svgfiles,
+			MakeSvg(ttinfo.Db.Log, tt, outdir, stemfile)...)
+	}
+	ttinfo.Db.Log.Message.Printf(
+		"Wrote %d SVG files to: %s\n", len(svgfiles), outdir)
+	return svgfiles
+}
+
+// MakeSvg writes an SVG file for each page of the timetable to outdir. The
+// file names are built from stemfile, the table type and the page's short
+// name. Errors are written to the given log.
+func MakeSvg(
+	log *base.LogSet,
+	tt Timetable,
+	outdir string,
+	stemfile string,
+) []string {
+	if _, err := os.Stat(outdir); errors.Is(err, os.ErrNotExist) {
+		err := os.MkdirAll(outdir, os.ModePerm)
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+	}
+	svgfiles := []string{}
+	kind := strings.ToLower(tt.TableType)
+	for _, p := range tt.Pages {
+		f := filepath.Join(outdir, fmt.Sprintf("%s_%s_%s.svg",
+			stemfile, kind, fileTag(p.Short)))
+		err := os.WriteFile(f, []byte(svgPage(tt, p)), 0666)
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+		svgfiles = append(svgfiles, f)
+	}
+	return svgfiles
+}
+
+// fileTag makes a name usable as part of a file name.
+func fileTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>| `, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+type svgWriter struct {
+	b strings.Builder
+}
+
+func (w *svgWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&w.b, format, args...)
+}
+
+func (w *svgWriter) rect(x, y, width, height float64, fill, stroke string) {
+	w.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f"`+
+		` fill="%s" stroke="%s" stroke-width="0.2"/>`+"\n",
+		x, y, width, height, fill, stroke)
+}
+
+// text writes a line of text. The anchor is "start", "middle" or "end".
+// If the text is probably too wide for the available width, it is
+// compressed.
+func (w *svgWriter) text(
+	x, y float64,
+	text string,
+	size float64,
+	bold bool,
+	anchor string,
+	width float64,
+	colour string,
+) {
+	if text == "" {
+		return
+	}
+	attrs := ""
+	if bold {
+		attrs = ` font-weight="bold"`
+	}
+	// Rough estimate of the text width
+	if float64(utf8.RuneCountInString(text))*size*0.55 > width {
+		attrs += fmt.Sprintf(
+			` textLength="%.2f" lengthAdjust="spacingAndGlyphs"`, width)
+	}
+	w.printf(`<text x="%.2f" y="%.2f" font-size="%.2f" text-anchor="%s"`+
+		` fill="%s"%s>%s</text>`+"\n",
+		x, y, size, anchor, colour, attrs, xmlText(text))
+}
+
+func svgPage(tt Timetable, p ttPage) string {
+	days := []ttDay{}
+	if dlist, ok := tt.Info["Days"].([]ttDay); ok {
+		days = dlist
+	}
+	hours := []ttHour{}
+	if hlist, ok := tt.Info["Hours"].([]ttHour); ok {
+		hours = hlist
+	}
+	ndays := max(len(days), 1)
+	nhours := max(len(hours), 1)
+	x0 := SVG_PAGE_BORDER
+	y0 := SVG_PAGE_BORDER + SVG_TITLE_HEIGHT
+	width := SVG_PAGE_WIDTH - 2*SVG_PAGE_BORDER
+	height := SVG_PAGE_HEIGHT - 2*SVG_PAGE_BORDER - SVG_TITLE_HEIGHT
+	colwidth := (width - SVG_V_HEADER_WIDTH) / float64(ndays)
+	rowheight := (height - SVG_H_HEADER_HEIGHT) / float64(nhours)
+	xday := func(d int) float64 {
+		return x0 + SVG_V_HEADER_WIDTH + float64(d)*colwidth
+	}
+	yhour := func(h int) float64 {
+		return y0 + SVG_H_HEADER_HEIGHT + float64(h)*rowheight
+	}
+
+	w := &svgWriter{}
+	w.printf(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1"`+
+		` width="%gmm" height="%gmm" viewBox="0 0 %g %g"`+
+		` font-family="%s">`+"\n",
+		SVG_PAGE_WIDTH, SVG_PAGE_HEIGHT, SVG_PAGE_WIDTH, SVG_PAGE_HEIGHT,
+		SVG_FONT)
+	w.rect(0, 0, SVG_PAGE_WIDTH, SVG_PAGE_HEIGHT, "#ffffff", "none")
+
+	// Title block
+	w.text(x0, SVG_PAGE_BORDER+SVG_BIG_SIZE, pageHeading(tt, p),
+		SVG_BIG_SIZE, true, "start", width*0.6, "#000000")
+	w.text(x0+width, SVG_PAGE_BORDER+SVG_BIG_SIZE,
+		infoString(tt, "Institution"),
+		SVG_NORMAL_SIZE, false, "end", width*0.35, "#000000")
+	w.text(x0, y0-2, typstString(tt, "Subtitle"),
+		SVG_SMALL_SIZE, false, "start", width, "#000000")
+
+	// Grid and headers
+	w.rect(x0, y0, SVG_V_HEADER_WIDTH, SVG_H_HEADER_HEIGHT,
+		SVG_HEADER_COLOUR, SVG_FRAME_COLOUR)
+	for d, day := range days {
+		w.rect(xday(d), y0, colwidth, SVG_H_HEADER_HEIGHT,
+			SVG_HEADER_COLOUR, SVG_FRAME_COLOUR)
+		w.text(xday(d)+colwidth/2, y0+SVG_H_HEADER_HEIGHT/2+SVG_BIG_SIZE/3,
+			day.Name, SVG_BIG_SIZE, true, "middle", colwidth-2, "#000000")
+	}
+	for h, hour := range hours {
+		y := yhour(h)
+		w.rect(x0, y, SVG_V_HEADER_WIDTH, rowheight,
+			SVG_HEADER_COLOUR, SVG_FRAME_COLOUR)
+		ym := y + rowheight/2
+		if hour.Start != "" {
+			w.text(x0+SVG_V_HEADER_WIDTH/2, ym, hour.Short,
+				SVG_NORMAL_SIZE, true, "middle",
+				SVG_V_HEADER_WIDTH-2, "#000000")
+			w.text(x0+SVG_V_HEADER_WIDTH/2, ym+SVG_SMALL_SIZE+0.5,
+				hour.Start+" – "+hour.End, SVG_SMALL_SIZE, false, "middle",
+				SVG_V_HEADER_WIDTH-2, "#000000")
+		} else {
+			w.text(x0+SVG_V_HEADER_WIDTH/2, ym+SVG_NORMAL_SIZE/3, hour.Short,
+				SVG_NORMAL_SIZE, true, "middle",
+				SVG_V_HEADER_WIDTH-2, "#000000")
+		}
+		for d := range days {
+			w.rect(xday(d), y, colwidth, rowheight, "#ffffff", SVG_FRAME_COLOUR)
+		}
+	}
+
+	// Tiles
+	fields := fieldPlacements(tt, tileFields)
+	inset := 0.4
+	for _, a := range p.Activities {
+		if a.Day >= len(days) || a.Hour >= len(hours) {
+			continue
+		}
+		duration, fraction, offset, total := tileSizes(a)
+		duration = min(duration, len(hours)-a.Hour)
+		cw := colwidth - 2*inset
+		x := xday(a.Day) + inset + cw*float64(offset)/float64(total)
+		tw := cw * float64(fraction) / float64(total)
+		y := yhour(a.Hour) + inset
+		th := rowheight*float64(duration) - 2*inset
+		bg, border, colour := getTileColours(a.Background)
+		w.rect(x, y, tw, th, bg, border)
+
+		texts := tileTexts(a)
+		c := texts[fields["c"]]
+		tl := texts[fields["tl"]]
+		tr := texts[fields["tr"]]
+		bl := texts[fields["bl"]]
+		br := texts[fields["br"]]
+		// Text lines with two items share the width.
+		lw := func(t1, t2 string) float64 {
+			if t1 != "" && t2 != "" {
+				return tw/2 - 1.5
+			}
+			return tw - 2
+		}
+		yt := y + SVG_SMALL_SIZE + 0.3
+		yb := y + th - 1
+		w.text(x+1, yt, tl, SVG_SMALL_SIZE, false, "start", lw(tl, tr), colour)
+		w.text(x+tw-1, yt, tr, SVG_SMALL_SIZE, false, "end", lw(tl, tr), colour)
+		w.text(x+tw/2, y+th/2+SVG_NORMAL_SIZE/3, c,
+			SVG_NORMAL_SIZE, true, "middle", tw-2, colour)
+		w.text(x+1, yb, bl, SVG_SMALL_SIZE, false, "start", lw(bl, br), colour)
+		w.text(x+tw-1, yb, br, SVG_SMALL_SIZE, false, "end", lw(bl, br), colour)
+	}
+	w.printf("</svg>\n")
+	return w.b.String()
+}
@@ -0,0 +1,106 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/typst_files"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Typst only reads files within its "root" folder, so the scripts and the
+// JSON data must be in the same tree. For each run a temporary root is
+// built, containing the embedded scripts ("scripts/") and copies of the
+// needed JSON files ("_data/").
+//
+// A template folder can be given to override the embedded scripts: the
+// scripts in its "scripts/" subfolder replace the embedded ones with the
+// same name (others are added). Fonts are taken from its "_fonts/"
+// subfolder, otherwise from "_fonts/" in the data folder.
+
+type typstRoot struct {
+	dir   string // the temporary root folder
+	fonts string // folder with additional fonts
+	log   *base.LogSet
+}
+
+func newTypstRoot(
+	log *base.LogSet,
+	datadir string,
+	templates string,
+) (*typstRoot, error) {
+	dir, err := os.MkdirTemp("", "w365typst")
+	if err != nil {
+		return nil, err
+	}
+	root := &typstRoot{dir, filepath.Join(datadir, "_fonts"), log}
+	scripts, err := fs.Sub(typst_files.Scripts, "scripts")
+	if err == nil {
+		err = copyScripts(scripts, filepath.Join(dir, "scripts"))
+	}
+	if err == nil && templates != "" {
+		tscripts := filepath.Join(templates, "scripts")
+		if _, err1 := os.Stat(tscripts); err1 == nil {
+			err = copyScripts(os.DirFS(tscripts), filepath.Join(dir, "scripts"))
+		} else {
+			err = err1
+		}
+		tfonts := filepath.Join(templates, "_fonts")
+		if _, err1 := os.Stat(tfonts); err1 == nil {
+			root.fonts = tfonts
+		}
+	}
+	if err != nil {
+		root.remove()
+		return nil, err
+	}
+	return root, nil
+}
+
+// copyScripts copies the Typst scripts (all "*.typ" files) from the given
+// file system (recursively) to the folder "dest", replacing existing files.
+func copyScripts(fsys fs.FS, dest string) error {
+	return fs.WalkDir(fsys, ".", func(
+		path string, d fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".typ" {
+			return nil
+		}
+		b, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dest, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			return err
+		}
+		return os.WriteFile(p, b, 0666)
+	})
+}
+
+// addData copies a JSON file from the data folder to the root.
+func (root *typstRoot) addData(datadir string, jsonstem string) error {
+	b, err := os.ReadFile(filepath.Join(datadir, "_data", jsonstem+".json"))
+	if err != nil {
+		return err
+	}
+	outdir := filepath.Join(root.dir, "_data")
+	if err := os.MkdirAll(outdir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outdir, jsonstem+".json"), b, 0666)
+}
+
+func (root *typstRoot) script(script string) string {
+	return filepath.Join(root.dir, "scripts", script)
+}
+
+func (root *typstRoot) remove() {
+	if err := os.RemoveAll(root.dir); err != nil {
+		root.log.Warning.Printf(
+			"Temporary Typst folder not removed: %v\n", err)
+	}
+}
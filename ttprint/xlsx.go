@@ -0,0 +1,284 @@
+package ttprint
+
+import (
+	"W365toFET/base"
+	"W365toFET/ttbase"
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Export of the overview tables ("Gesamtplan") as a spreadsheet (XLSX).
+// There is a sheet for each of the overview tables in the print tables,
+// with a row for each class, teacher or room and a column for each
+// day/hour slot. Only a minimal subset of the Office Open XML format is
+// used, the strings are "inline".
+
+var xlsxSheetNames = map[string]string{
+	"Class":   "Klassen",
+	"Teacher": "Lehrer",
+	"Room":    "Räume",
+	"Group":   "Gruppen",
+}
+
+// GenXlsx writes the overview tables to the file xlsxpath. If there are
+// no overview tables in the print tables, no file is written and the result
+// is false.
+func GenXlsx(ttinfo *ttbase.TtInfo, xlsxpath string) bool {
+	tts := []Timetable{}
+	for _, pt := range printOptions(ttinfo.Db).Tables {
+		if !pt.Overview || pt.Spec != "" {
+			continue
+		}
+		switch pt.Type {
+		case "Class":
+			tts = append(tts, classTimetable(ttinfo))
+		case "Teacher":
+			tts = append(tts, teacherTimetable(ttinfo))
+		case "Room":
+			tts = append(tts, roomTimetable(ttinfo))
+		case "Group":
+			tts = append(tts, groupTimetable(ttinfo, allGroups(ttinfo)))
+		}
+	}
+	if len(tts) == 0 {
+		ttinfo.Db.Log.Warning.Println("No overview tables for XLSX")
+		return false
+	}
+	MakeXlsx(ttinfo.Db.Log, tts, xlsxpath)
+	return true
+}
+
+// MakeXlsx writes a spreadsheet with a sheet for each of the timetables.
+// Messages are written to the given log.
+func MakeXlsx(log *base.LogSet, tts []Timetable, xlsxpath string) {
+	outdir := filepath.Dir(xlsxpath)
+	if _, err := os.Stat(outdir); errors.Is(err, os.ErrNotExist) {
+		err := os.MkdirAll(outdir, os.ModePerm)
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+	}
+	f, err := os.Create(xlsxpath)
+	if err != nil {
+		log.Error.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	add := func(name string, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+		_, err = w.Write([]byte(xml.Header + content))
+		if err != nil {
+			log.Error.Fatal(err)
+		}
+	}
+
+	sheets := []string{}
+	ctypes := []string{}
+	wbrels := []string{}
+	for i, tt := range tts {
+		n := i + 1
+		name := xlsxSheetNames[tt.TableType]
+		sheets = append(sheets, fmt.Sprintf(
+			`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`,
+			xmlText(name), n, n))
+		ctypes = append(ctypes, fmt.Sprintf(`<Override`+
+			` PartName="/xl/worksheets/sheet%d.xml" ContentType="applicat`+
+			`ion/vnd.openxmlformats-officedocument.spreadsheetml.worksheet`+
+			`+xml"/>`, n))
+		wbrels = append(wbrels, fmt.Sprintf(`<Relationship Id="rId%d"`+
+			` Type="http://schemas.openxmlformats.org/officeDocument/2006`+
+			`/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`,
+			n, n))
+		add(fmt.Sprintf("xl/worksheets/sheet%d.xml", n), xlsxSheet(tt))
+	}
+	// The styles get the next relationship id
+	wbrels = append(wbrels, fmt.Sprintf(`<Relationship Id="rId%d"`+
+		` Type="http://schemas.openxmlformats.org/officeDocument/2006`+
+		`/relationships/styles" Target="styles.xml"/>`, len(tts)+1))
+
+	add("[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats`+
+		`.org/package/2006/content-types">`+
+		`<Default Extension="rels" ContentType="application/vnd.openxml`+
+		`formats-package.relationships+xml"/>`+
+		`<Default Extension="xml" ContentType="application/xml"/>`+
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd`+
+		`.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`+
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd`+
+		`.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`+
+		strings.Join(ctypes, "")+`</Types>`)
+	add("_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats`+
+		`.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org`+
+		`/officeDocument/2006/relationships/officeDocument"`+
+		` Target="xl/workbook.xml"/></Relationships>`)
+	add("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats`+
+		`.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxml`+
+		`formats.org/officeDocument/2006/relationships"><sheets>`+
+		strings.Join(sheets, "")+`</sheets></workbook>`)
+	add("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas`+
+		`.openxmlformats.org/package/2006/relationships">`+
+		strings.Join(wbrels, "")+`</Relationships>`)
+	// Style 1: bold, centred (headers), style 2: wrapped text (slots)
+	add("xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats`+
+		`.org/spreadsheetml/2006/main">`+
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font>`+
+		`<font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`+
+		`<fills count="2"><fill><patternFill patternType="none"/></fill>`+
+		`<fill><patternFill patternType="gray125"/></fill></fills>`+
+		`<borders count="1"><border><left/><right/><top/><bottom/>`+
+		`<diagonal/></border></borders>`+
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0"`+
+		` borderId="0"/></cellStyleXfs>`+
+		`<cellXfs count="3">`+
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`+
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0"`+
+		` applyFont="1" applyAlignment="1"><alignment`+
+		` horizontal="center"/></xf>`+
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"`+
+		` applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>`+
+		`</cellXfs></styleSheet>`)
+
+	err = zw.Close()
+	if err != nil {
+		log.Error.Fatal(err)
+	}
+	log.Message.Printf("Wrote: %s\n", xlsxpath)
+}
+
+// xlsxSheet builds the worksheet for a timetable. The first two rows are
+// the days and hours, the first two columns the element tags and names.
+func xlsxSheet(tt Timetable) string {
+	days := []ttDay{}
+	if dlist, ok := tt.Info["Days"].([]ttDay); ok {
+		days = dlist
+	}
+	hours := []ttHour{}
+	if hlist, ok := tt.Info["Hours"].([]ttHour); ok {
+		hours = hlist
+	}
+	nhours := len(hours)
+	ncols := 2 + len(days)*nhours
+	lastcol := xlsxColumn(ncols - 1)
+
+	rows := []string{}
+	merges := []string{}
+	row1 := []string{xlsxCell(0, 1, "", 1), xlsxCell(1, 1, "", 1)}
+	row2 := []string{
+		xlsxCell(0, 2, "Kürzel", 1), xlsxCell(1, 2, "Name", 1)}
+	for d, day := range days {
+		col := 2 + d*nhours
+		row1 = append(row1, xlsxCell(col, 1, day.Short, 1))
+		if nhours > 1 {
+			merges = append(merges, fmt.Sprintf(`<mergeCell ref="%s1:%s1"/>`,
+				xlsxColumn(col), xlsxColumn(col+nhours-1)))
+		}
+		for h, hour := range hours {
+			row2 = append(row2, xlsxCell(col+h, 2, day.Short+"."+hour.Short, 1))
+		}
+	}
+	rows = append(rows, xlsxRow(1, row1), xlsxRow(2, row2))
+
+	for i, p := range tt.Pages {
+		r := i + 3
+		// Collect the texts for each slot.
+		slots := make([][]string, len(days)*nhours)
+		for _, a := range p.Activities {
+			if a.Day >= len(days) {
+				continue
+			}
+			text := xlsxTileText(a)
+			for h := a.Hour; h < a.Hour+max(a.Duration, 1) && h < nhours; h++ {
+				ix := a.Day*nhours + h
+				if !slices.Contains(slots[ix], text) {
+					slots[ix] = append(slots[ix], text)
+				}
+			}
+		}
+		cells := []string{
+			xlsxCell(0, r, p.Short, 0),
+			xlsxCell(1, r, p.Name, 0),
+		}
+		for ix, texts := range slots {
+			if len(texts) != 0 {
+				slices.Sort(texts)
+				cells = append(cells,
+					xlsxCell(2+ix, r, strings.Join(texts, "\n"), 2))
+			}
+		}
+		rows = append(rows, xlsxRow(r, cells))
+	}
+
+	var b strings.Builder
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org` +
+		`/spreadsheetml/2006/main">`)
+	// Freeze the header rows and the element columns
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane` +
+		` xSplit="2" ySplit="2" topLeftCell="C3" activePane="bottomRight"` +
+		` state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(fmt.Sprintf(`<cols><col min="1" max="1" width="10"`+
+		` customWidth="1"/><col min="2" max="2" width="25" customWidth="1"/>`+
+		`<col min="3" max="%d" width="14" customWidth="1"/></cols>`, ncols))
+	b.WriteString(`<sheetData>` + strings.Join(rows, "") + `</sheetData>`)
+	// The second header row can be used for filtering
+	b.WriteString(fmt.Sprintf(`<autoFilter ref="A2:%s%d"/>`,
+		lastcol, len(tt.Pages)+2))
+	if len(merges) != 0 {
+		b.WriteString(fmt.Sprintf(`<mergeCells count="%d">%s</mergeCells>`,
+			len(merges), strings.Join(merges, "")))
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// xlsxTileText gives the text for a tile: subject, groups and rooms.
+func xlsxTileText(a Tile) string {
+	text := a.Subject
+	if len(a.Groups) != 0 {
+		text += " " + strings.Join(a.Groups, ",")
+	}
+	if len(a.Rooms) != 0 {
+		text += " (" + strings.Join(a.Rooms, ",") + ")"
+	}
+	return text
+}
+
+func xlsxRow(r int, cells []string) string {
+	return fmt.Sprintf(`<row r="%d">%s</row>`, r, strings.Join(cells, ""))
+}
+
+// xlsxCell builds a cell with an inline string. The column index starts at
+// 0, the row at 1, style is the index in the "cellXfs" list.
+func xlsxCell(col int, row int, text string, style int) string {
+	s := ""
+	if style != 0 {
+		s = fmt.Sprintf(` s="%d"`, style)
+	}
+	return fmt.Sprintf(`<c r="%s%d"%s t="inlineStr">`+
+		`<is><t xml:space="preserve">%s</t></is></c>`,
+		xlsxColumn(col), row, s, xmlText(text))
+}
+
+// xlsxColumn converts a column index (from 0) to the column name
+// ("A", "B", ..., "Z", "AA", ...).
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
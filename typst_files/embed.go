@@ -0,0 +1,9 @@
+// Package typst_files provides the default Typst scripts. They are
+// embedded in the binaries, so that the programs can be run on input files
+// anywhere, without a "typst_files" folder next to them.
+package typst_files
+
+import "embed"
+
+//go:embed scripts/*.typ
+var Scripts embed.FS
@@ -16,15 +16,21 @@
  * known, as are the row heights, so this division is fairly straightforward.
  */
 
-// To use a different font:
+#let xdata = json(sys.inputs.ifile)
+#let typstMap = xdata.at("Typst", default: (:))
+
+// To use a different font (or use the option Typst.Fonts):
 // CHANGE_UG
-#set text(font: ("Nunito","DejaVu Sans"))
+#set text(font: typstMap.at("Fonts", default: ("Nunito","DejaVu Sans")))
 // If the font is not installed on the system, the .ttf or .otf files can be
 // placed in "typst_files/_fonts".
 
-// For A3 paper
-#let PAGE_HEIGHT = 297mm
-#let PAGE_WIDTH = 420mm
+// Default: A3 paper
+// The page size (in mm) is passed as Typst.OverviewPageSize (option
+// "OverviewPageFormat").
+#let pageSize = typstMap.at("OverviewPageSize", default: (width: 420, height: 297))
+#let PAGE_HEIGHT = pageSize.height * 1mm
+#let PAGE_WIDTH = pageSize.width * 1mm
 #let PAGE_BORDER = (top:15mm, bottom: 15mm, left: 15mm, right: 15mm)
 #let TITLE_HEIGHT = 15mm
 #let H_HEADER_HEIGHT1 = 10mm
@@ -65,6 +71,11 @@
         t: "SUBJECT",
         b: "TEACHER",
     ),
+    Group: (
+        m: "SUBJECT",
+        t: "TEACHER",
+        b: "GROUP",
+    ),
 )
 
 // Document title fallbacks
@@ -72,6 +83,7 @@
     Class: "Gesamtstundenplan der Klassen",
     Teacher: "Gesamtstundenplan der Lehrkräfte",
     Room: "Gesamtstundenplan der Räume",
+    Group: "Gesamtstundenplan der Gruppen",
 )
 
 // ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -84,9 +96,6 @@
 #let H_HEADER_HEIGHT = H_HEADER_HEIGHT1 + H_HEADER_HEIGHT2
 #let TABLE_BODY_HEIGHT = PLAN_AREA_HEIGHT - H_HEADER_HEIGHT
 
-#let xdata = json(sys.inputs.ifile)
-#let typstMap = xdata.at("Typst", default: (:))
-
 #let DAYS = ()
 #for ddata in xdata.Info.Days {
     //TODO: Which field to use
@@ -255,6 +264,7 @@
     teachers: (),
     rooms: (),
     background: "",
+    foreground: "", // text colour, computed if empty
 ) = {
     // Determine grid lines
     let ix = day * nhours + hour
@@ -283,6 +293,9 @@
     let textcolour = if bw.components().at(0) < 55% { white } else { black }
     // 2) This uses the WCAG2 guidelines:
     //let textcolour = fgWCAG2(bg)
+    if foreground != "" {
+        textcolour = rgb(foreground)
+    }
     set text(textcolour)
 
     // Determine size and offset of tile
@@ -313,13 +326,13 @@
     pad(left: 0mm, it))
 
 // Determine the document title
-#let titles = typstMap.at("titles", default: (:))
+#let titles = typstMap.at("Titles", default: (:))
 #if titles.len() == 0 {
     // fallback
     titles = titleFallbacks
 }
 #let title = titles.at(tableType, default: "")
-#let subtitle = typstMap.at("subtitle", default: "hallo")
+#let subtitle = typstMap.at("Subtitle", default: "")
 
 #set page(height: PAGE_HEIGHT, width: PAGE_WIDTH,
     //numbering: "1/1",
@@ -355,7 +368,7 @@
             #place(top)[= #title#h(1fr) #text(17pt)[ #xdata.Info.Institution]]
 
             #place(bottom)[
-                #typstMap.at("subtitle", default: "")
+                #typstMap.at("Subtitle", default: "")
                 #h(1fr)
                 #pageno / #pagetotal
             ]
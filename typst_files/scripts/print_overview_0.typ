@@ -253,6 +253,7 @@
     teachers: (),
     rooms: (),
     background: "",
+    foreground: "", // text colour, computed if empty
 ) = {
     // Determine grid lines
     let ix = day * nhours + hour
@@ -280,6 +281,9 @@
     let textcolour = if bw.components().at(0) < 55% { white } else { black }
     // 2) This uses the WCAG2 guidelines:
     //let textcolour = fgWCAG2(bg)
+    if foreground != "" {
+        textcolour = rgb(foreground)
+    }
     set text(textcolour)
 
     // Determine size and offset of tile
@@ -23,34 +23,63 @@
  *                     must be true.
  * If lesson period times are supplied, the parameter Typst.WithTimes must be
  * true (default: false) for them to be shown in the period headers.
+ *
+ * Day views (table types "Day" and "Day_Teacher") have a page for each day,
+ * showing the timetables of all classes or teachers in columns. These are
+ * taken from Info.Columns instead of Info.Days, the "day" field of the tiles
+ * is the column index. As there are usually many columns, the page size is
+ * Typst.OverviewPageSize (default A3) and the texts are smaller.
+ *
+ * If Typst.WithLegend is true, a legend (shortcut = full name) of the
+ * subjects, teachers, rooms and groups on the page is shown below the table.
+ * Typst.FullNames is a list of the fields ("SUBJECT", "TEACHER", "ROOM",
+ * "GROUP") which should be shown with full names in the tiles (true means
+ * just "TEACHER"). The names are taken from the page legends.
  */
 
-// To use a different font:
-#set text(font: ("Nunito","DejaVu Sans"))
+#let xdata = json(sys.inputs.ifile)
+#let typstMap = xdata.at("Typst", default: (:))
+
+// To use a different font (or use the option Typst.Fonts):
+#set text(font: typstMap.at("Fonts", default: ("Nunito","DejaVu Sans")))
 // If the font is not installed on the system, the .ttf or .otf files can be
 // placed in "typst_files/_fonts".
 
-#let PAGE_HEIGHT = 210mm
-#let PAGE_WIDTH = 297mm
+// Type of table ("Class", "Teacher", "Room", "Group", "FreeRooms", "Day"
+// or "Day_Teacher")
+#let tableType = xdata.TableType
+#let DAYVIEW = tableType in ("Day", "Day_Teacher")
+
+// The page size (in mm) is passed as Typst.PageSize (option "PageFormat"),
+// for the day views as Typst.OverviewPageSize (option "OverviewPageFormat").
+#let pageSize = if DAYVIEW {
+    typstMap.at("OverviewPageSize", default: (width: 420, height: 297))
+} else {
+    typstMap.at("PageSize", default: (width: 297, height: 210))
+}
+#let PAGE_HEIGHT = pageSize.height * 1mm
+#let PAGE_WIDTH = pageSize.width * 1mm
 #let PAGE_BORDER = (top:15mm, bottom: 15mm, left: 15mm, right: 15mm)
 #let TITLE_HEIGHT = 15mm
-#let H_HEADER_HEIGHT = 15mm
-#let V_HEADER_WIDTH = 30mm
+#let H_HEADER_HEIGHT = if DAYVIEW { 12mm } else { 15mm }
+#let V_HEADER_WIDTH = if DAYVIEW { 20mm } else { 30mm }
 
 #set page(height: PAGE_HEIGHT, width: PAGE_WIDTH,
 //  numbering: "1",
   margin: PAGE_BORDER,
 )
 #let CELL_BORDER = 0.5pt
-#let BIG_SIZE = 18pt
-#let NORMAL_SIZE = 16pt
-#let PLAIN_SIZE = 12pt
-#let SMALL_SIZE = 12pt
+#let (BIG_SIZE, NORMAL_SIZE, PLAIN_SIZE, SMALL_SIZE) = if DAYVIEW {
+    (14pt, 11pt, 9pt, 8pt)
+} else {
+    (18pt, 16pt, 12pt, 12pt)
+}
 
 #let FRAME_COLOUR = "#707070"
 #let HEADER_COLOUR = "#f0f0f0"
 #let EMPTY_COLOUR = "#ffffff"
 #let BREAK_COLOUR = EMPTY_COLOUR
+#let LEGEND_SIZE = 8pt
 
 // Field placement fallbacks
 #let boxText = (
@@ -75,6 +104,30 @@
         //bl: "",
         br: "TEACHER",
     ),
+    Group: (
+        c: "SUBJECT",
+        tl: "TEACHER",
+        tr: "GROUP",
+        //bl: "",
+        br: "ROOM",
+    ),
+    FreeRooms: (
+        c: "ROOM",
+    ),
+    Day: (
+        c: "SUBJECT",
+        tl: "TEACHER",
+        tr: "GROUP",
+        //bl: "",
+        br: "ROOM",
+    ),
+    Day_Teacher: (
+        c: "GROUP",
+        tl: "SUBJECT",
+        tr: "TEACHER",
+        //bl: "",
+        br: "ROOM",
+    ),
 )
 
 // Page heading fallbacks
@@ -82,6 +135,10 @@
     Class: "Klasse %S",
     Teacher: "%N (%S)",
     Room: "Raumplan %N (%S)",
+    Group: "%N",
+    FreeRooms: "%N",
+    Day: "Klassen – %N",
+    Day_Teacher: "Lehrkräfte – %N",
 )
 
 // ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -91,13 +148,27 @@
 #let PLAN_AREA_WIDTH = (PAGE_WIDTH - PAGE_BORDER.left
     - PAGE_BORDER.right)
 
-#let xdata = json(sys.inputs.ifile)
-#let typstMap = xdata.at("Typst", default: (:))
+#let WITHLEGEND = typstMap.at("WithLegend", default: false)
+#let LEGEND_HEIGHT = if WITHLEGEND { 20mm } else { 0mm }
+#let PLAN_AREA_HEIGHT = PLAN_AREA_HEIGHT - LEGEND_HEIGHT
+#let FULLNAMES = typstMap.at("FullNames", default: ())
+#if FULLNAMES == true {
+    FULLNAMES = ("TEACHER",)
+} else if FULLNAMES == false {
+    FULLNAMES = ()
+}
 
 #let DAYS = ()
-#for ddata in xdata.Info.Days {
-    //TODO: Which field to use
-    DAYS.push(ddata.Name)
+#if DAYVIEW {
+    // The columns (classes or teachers) take the place of the days.
+    for ddata in xdata.Info.Columns {
+        DAYS.push(ddata.Short)
+    }
+} else {
+    for ddata in xdata.Info.Days {
+        //TODO: Which field to use
+        DAYS.push(ddata.Name)
+    }
 }
 
 #let HOURS = ()
@@ -124,9 +195,6 @@
   }
 }
 
-// Type of table ("Class", "Teacher" or "Room")
-#let tableType = xdata.TableType
-
 // Determine the field placements in the tiles
 #let fieldPlacements = typstMap.at("FieldPlacements", default: (:))
 #if fieldPlacements.len() == 0 {
@@ -290,13 +358,19 @@
     teachers: (),
     rooms: (),
     background: "",
+    foreground: "", // text colour, computed if empty
+    names: (:), // field -> (shortcut -> full name), for FullNames
 ) = {
     // Prepare texts
+    let fullnames(field, items) = {
+        let m = names.at(field, default: (:))
+        items.map(x => m.at(x, default: x))
+    }
     let texts = (
-        SUBJECT: subject,
-        GROUP: groups.join(","),
-        TEACHER: teachers.join(","),
-        ROOM: rooms.join(","),
+        SUBJECT: fullnames("SUBJECT", (subject,)).at(0),
+        GROUP: fullnames("GROUP", groups).join(","),
+        TEACHER: fullnames("TEACHER", teachers).join(","),
+        ROOM: fullnames("ROOM", rooms).join(","),
     )
     let centre = texts.at(fieldPlacements.at("c", default: ""), default: "")
     let tl = texts.at(fieldPlacements.at("tl", default: ""), default: "")
@@ -317,6 +391,9 @@
     let textcolour = if bw.components().at(0) < 55% { white } else { black }
     // 2) This uses the WCAG2 guidelines:
     //let textcolour = fgWCAG2(bg)
+    if foreground != "" {
+        textcolour = rgb(foreground)
+    }
     set text(textcolour)
 
     // Determine grid lines
@@ -383,14 +460,47 @@
     block(height: TITLE_HEIGHT, above: 0mm, below: 0mm, inset: 2mm)[
         #place(top)[= #title #h(1fr)#text(17pt)[#xdata.Info.Institution]]
        // #place(left + horizon)[]
-        #place(bottom)[#typstMap.at("subtitle", default: "")]
+        #place(bottom)[#typstMap.at("Subtitle", default: "")]
     ]
 
+    // Full names (from the legend) for the tiles
+    let legend = p.at("Legend", default: (:))
+    let names = (:)
+    for (field, key) in (
+        ("SUBJECT", "Subjects"), ("TEACHER", "Teachers"),
+        ("ROOM", "Rooms"), ("GROUP", "Groups"),
+    ) {
+        if field in FULLNAMES {
+            let m = (:)
+            for item in legend.at(key, default: ()) {
+                m.insert(item.Short, item.Name)
+            }
+            names.insert(field, m)
+        }
+    }
+
     box([
         #tbody
         #for a in p.Activities {
-            ttcell(..a)
+            ttcell(..a, names: names)
         }
     ])
+
+    if WITHLEGEND {
+        let parts = ([],)
+        for (key, label) in (
+            ("Subjects", "Fächer"), ("Teachers", "Lehrkräfte"),
+            ("Rooms", "Räume"), ("Groups", "Gruppen"),
+        ) {
+            let items = legend.at(key, default: ())
+            if items.len() > 0 {
+                if parts.len() > 1 { parts.push(linebreak()) }
+                parts.push(strong(label + ": ")
+                    + items.map(it => it.Short + " = " + it.Name).join(", "))
+            }
+        }
+        block(height: LEGEND_HEIGHT, above: 1mm, below: 0mm, inset: 1mm,
+            clip: true, text(size: LEGEND_SIZE, parts.join()))
+    }
 }
 
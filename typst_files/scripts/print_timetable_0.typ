@@ -289,6 +289,7 @@
     teachers: (),
     rooms: (),
     background: "",
+    foreground: "", // text colour, computed if empty
 ) = {
     // Prepare texts
     let texts = (
@@ -314,6 +315,9 @@
     let textcolour = if bw.components().at(0) < 55% { white } else { black }
     // 2) This uses the WCAG2 guidelines:
     //let textcolour = fgWCAG2(bg)
+    if foreground != "" {
+        textcolour = rgb(foreground)
+    }
     set text(textcolour)
 
     // Determine grid lines
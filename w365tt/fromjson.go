@@ -30,7 +30,21 @@ func ReadJSON(jsonpath string) *DbTopLevel {
 }
 
 func LoadJSON(newdb *base.DbTopLevel, jsonpath string) {
-	db := ReadJSON(jsonpath)
+	data, err := os.ReadFile(jsonpath)
+	if err != nil {
+		newdb.Log.Error.Fatal(err)
+	}
+	newdb.Log.Message.Printf("*+ Reading: %s\n", jsonpath)
+	LoadJSONData(newdb, data)
+}
+
+// LoadJSONData reads W365 JSON data (already read, e.g. from an HTTP
+// request) into the given (empty) base db.
+func LoadJSONData(newdb *base.DbTopLevel, data []byte) {
+	db := &DbTopLevel{}
+	if err := json.Unmarshal(data, db); err != nil {
+		newdb.Log.Error.Fatalf("Could not unmarshal json: %s\n", err)
+	}
 	newdb.Info = base.Info(db.Info)
 	newdb.PrintOptions = base.PrintOptions(db.PrintOptions)
 	db.readDays(newdb)
@@ -49,6 +63,16 @@ func LoadJSON(newdb *base.DbTopLevel, jsonpath string) {
 	db.readConstraints(newdb)
 }
 
+// checkColour returns the colour if it is of the form "#RRGGBB", otherwise
+// a warning is logged and "" is returned.
+func checkColour(newdb *base.DbTopLevel, tag string, colour string) string {
+	if colour == "" || base.ValidColour(colour) {
+		return colour
+	}
+	newdb.Log.Warning.Printf("%s: Invalid colour: %s\n", tag, colour)
+	return ""
+}
+
 func (db *DbTopLevel) readDays(newdb *base.DbTopLevel) {
 	for _, e := range db.Days {
 		n := newdb.NewDay(e.Id)
@@ -102,6 +126,7 @@ func (db *DbTopLevel) readTeachers(newdb *base.DbTopLevel) {
 		n.MaxGapsPerWeek = e.MaxGapsPerWeek
 		n.MaxAfternoons = amax
 		n.LunchBreak = e.LunchBreak
+		n.Color = checkColour(newdb, e.Tag, e.Color)
 
 		db.TeacherMap[e.Id] = true
 	}
@@ -37,20 +37,20 @@ func (db *DbTopLevel) readClasses(newdb *base.DbTopLevel) {
 				flag, ok := pregroups[g]
 				if ok {
 					if flag {
-						base.Error.Fatalf("Group Defined in"+
+						newdb.Log.Error.Fatalf("Group Defined in"+
 							" multiple Divisions:\n  -- %s\n", g)
 					}
 					// Flag Group and add to division's group list
 					pregroups[g] = true
 					glist = append(glist, g)
 				} else {
-					base.Error.Printf("Unknown Group in Class %s,"+
+					newdb.Log.Error.Printf("Unknown Group in Class %s,"+
 						" Division %s:\n  %s\n", e.Tag, wdiv.Name, g)
 				}
 			}
 			// Accept Divisions which have too few Groups at this stage.
 			if len(glist) < 2 {
-				base.Warning.Printf("In Class %s,"+
+				newdb.Log.Warning.Printf("In Class %s,"+
 					" not enough valid Groups (>1) in Division %s\n",
 					e.Tag, wdiv.Name)
 			}
@@ -80,6 +80,7 @@ func (db *DbTopLevel) readClasses(newdb *base.DbTopLevel) {
 		n.LunchBreak = e.LunchBreak
 		n.ForceFirstHour = e.ForceFirstHour
 		n.ClassGroup = classGroup.Id
+		n.Color = checkColour(newdb, e.Tag, e.Color)
 	}
 
 	// Copy Groups.
@@ -89,7 +90,7 @@ func (db *DbTopLevel) readClasses(newdb *base.DbTopLevel) {
 			g.Tag = n.Tag
 			db.GroupRefMap[n.Id] = n.Id // mapping to itself is correct!
 		} else {
-			base.Error.Printf("Group not in Division, removing:\n  %s,",
+			newdb.Log.Error.Printf("Group not in Division, removing:\n  %s,",
 				n.Id)
 		}
 	}
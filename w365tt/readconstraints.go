@@ -64,6 +64,14 @@ func (db *DbTopLevel) readConstraints(newdb *base.DbTopLevel) {
 			c.Hours = a2i(e["hours"])
 			c.Course1 = a2r(e["course1"])
 			c.Course2 = a2r(e["course2"])
+		case "PARALLEL_COURSES":
+			c := newdb.NewParallelCourses()
+			c.Weight = a2i(e["weight"])
+			c.Courses = a2rr(e["courses"])
+		case "NOT_ON_SAME_DAY":
+			c := newdb.NewNotOnSameDay()
+			c.Weight = a2i(e["weight"])
+			c.Subjects = a2rr(e["subjects"])
 		case "DOUBLE_LESSON_NOT_OVER_BREAKS":
 			c := newdb.NewDoubleLessonNotOverBreaks()
 			c.Weight = a2i(e["weight"])
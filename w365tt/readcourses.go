@@ -12,7 +12,7 @@ func (db *DbTopLevel) readSubjects(newdb *base.DbTopLevel) {
 		// Perform some checks and add to the SubjectTags map.
 		_, nok := db.SubjectTags[e.Tag]
 		if nok {
-			base.Error.Fatalf("Subject Tag (Shortcut) defined twice: %s\n",
+			newdb.Log.Error.Fatalf("Subject Tag (Shortcut) defined twice: %s\n",
 				e.Tag)
 		}
 		db.SubjectTags[e.Tag] = e.Id
@@ -20,6 +20,7 @@ func (db *DbTopLevel) readSubjects(newdb *base.DbTopLevel) {
 		n := newdb.NewSubject(e.Id)
 		n.Tag = e.Tag
 		n.Name = e.Name
+		n.Color = checkColour(newdb, e.Tag, e.Color)
 		db.SubjectMap[e.Id] = n
 	}
 }
@@ -40,8 +41,8 @@ func (db *DbTopLevel) readCourses(newdb *base.DbTopLevel) {
 	for _, e := range db.Courses {
 		subject := db.getCourseSubject(newdb, e.Subjects, e.Id)
 		room := db.getCourseRoom(newdb, e.PreferredRooms, e.Id)
-		groups := db.getCourseGroups(e.Groups, e.Id)
-		teachers := db.getCourseTeachers(e.Teachers, e.Id)
+		groups := db.getCourseGroups(newdb, e.Groups, e.Id)
+		teachers := db.getCourseTeachers(newdb, e.Teachers, e.Id)
 		n := newdb.NewCourse(e.Id)
 		n.Subject = subject
 		n.Groups = groups
@@ -54,7 +55,6 @@ func (db *DbTopLevel) readCourses(newdb *base.DbTopLevel) {
 func (db *DbTopLevel) readSuperCourses(newdb *base.DbTopLevel) {
 	// In the input from W365 the subjects for the SuperCourses must be
 	// taken from the linked EpochPlan.
-	// The EpochPlans are otherwise not needed.
 	epochPlanSubjects := map[Ref]base.Ref{}
 	if db.EpochPlans != nil {
 		for _, n := range db.EpochPlans {
@@ -77,8 +77,8 @@ func (db *DbTopLevel) readSuperCourses(newdb *base.DbTopLevel) {
 			} else {
 				subject := db.getCourseSubject(newdb, e.Subjects, e.Id)
 				room := db.getCourseRoom(newdb, e.PreferredRooms, e.Id)
-				groups := db.getCourseGroups(e.Groups, e.Id)
-				teachers := db.getCourseTeachers(e.Teachers, e.Id)
+				groups := db.getCourseGroups(newdb, e.Groups, e.Id)
+				teachers := db.getCourseTeachers(newdb, e.Teachers, e.Id)
 				// Use a new Id for the SubCourse because it can also be
 				// the Id of a Course.
 				n := newdb.NewSubCourse("$$" + e.Id)
@@ -91,16 +91,63 @@ func (db *DbTopLevel) readSuperCourses(newdb *base.DbTopLevel) {
 			}
 		}
 
-		// Now add the SuperCourse.
-		subject, ok := epochPlanSubjects[spc.EpochPlan]
-		if !ok {
-			base.Error.Fatalf("Unknown EpochPlan in SuperCourse %s:\n  %s\n",
-				spc.Id, spc.EpochPlan)
+		// Now add the SuperCourse. A "subject" field takes precedence
+		// over the EpochPlan.
+		var subject Ref
+		if spc.Subject != "" {
+			_, ok := db.SubjectMap[spc.Subject]
+			if !ok {
+				newdb.Log.Error.Fatalf(
+					"Unknown Subject in SuperCourse %s:\n  %s\n",
+					spc.Id, spc.Subject)
+			}
+			subject = spc.Subject
+		} else {
+			var ok bool
+			subject, ok = epochPlanSubjects[spc.EpochPlan]
+			if !ok {
+				newdb.Log.Error.Fatalf(
+					"Unknown EpochPlan in SuperCourse %s:\n  %s\n",
+					spc.Id, spc.EpochPlan)
+			}
 		}
 		n := newdb.NewSuperCourse(spc.Id)
 		n.Subject = subject
+		if _, ok := epochPlanSubjects[spc.EpochPlan]; ok {
+			n.EpochPlan = spc.EpochPlan
+		}
 		db.CourseMap[n.Id] = true
 	}
+	db.readEpochPlans(newdb, sbcMap)
+}
+
+// The epochs can refer to Courses or SubCourses, so the EpochPlans are
+// read after the SuperCourses.
+func (db *DbTopLevel) readEpochPlans(
+	newdb *base.DbTopLevel,
+	sbcMap map[Ref]*base.SubCourse,
+) {
+	for _, e := range db.EpochPlans {
+		n := newdb.NewEpochPlan(e.Id)
+		n.Tag = e.Tag
+		n.Name = e.Name
+		for _, epoch := range e.Epochs {
+			var cref Ref
+			if sbc, ok := sbcMap[epoch.Course]; ok {
+				cref = sbc.Id
+			} else if db.CourseMap[epoch.Course] {
+				cref = epoch.Course
+			} else {
+				newdb.Log.Error.Printf("EpochPlan %s: Unknown Course: %s\n",
+					e.Tag, epoch.Course)
+				continue
+			}
+			n.Epochs = append(n.Epochs, base.Epoch{
+				Course: cref,
+				Weeks:  epoch.Weeks,
+			})
+		}
+	}
 }
 
 func (db *DbTopLevel) getCourseSubject(
@@ -122,7 +169,7 @@ func (db *DbTopLevel) getCourseSubject(
 		wsid := srefs[0]
 		_, ok := db.SubjectMap[wsid]
 		if !ok {
-			base.Error.Fatalf(msg, courseId, wsid)
+			newdb.Log.Error.Fatalf(msg, courseId, wsid)
 		}
 		subject = wsid
 	} else if len(srefs) > 1 {
@@ -134,7 +181,7 @@ func (db *DbTopLevel) getCourseSubject(
 			if ok {
 				sklist = append(sklist, s.Tag)
 			} else {
-				base.Error.Fatalf(msg, courseId, wsid)
+				newdb.Log.Error.Fatalf(msg, courseId, wsid)
 			}
 		}
 		sktag := strings.Join(sklist, "/")
@@ -148,7 +195,8 @@ func (db *DbTopLevel) getCourseSubject(
 			db.SubjectTags[sktag] = subject
 		}
 	} else {
-		base.Error.Fatalf("Course/SubCourse has no subject: %s\n", courseId)
+		newdb.Log.Error.Fatalf(
+			"Course/SubCourse has no subject: %s\n", courseId)
 	}
 	return subject
 }
@@ -171,7 +219,7 @@ func (db *DbTopLevel) getCourseRoom(
 		var estr string
 		room, estr = db.makeRoomChoiceGroup(newdb, rrefs)
 		if estr != "" {
-			base.Error.Printf("In Course %s:\n%s", courseId, estr)
+			newdb.Log.Error.Printf("In Course %s:\n%s", courseId, estr)
 		}
 	} else if len(rrefs) == 1 {
 		// Check that room is Room or RoomGroup.
@@ -184,7 +232,8 @@ func (db *DbTopLevel) getCourseRoom(
 			if ok {
 				room = rref0
 			} else {
-				base.Error.Printf("Invalid room in Course/SubCourse %s:\n  %s\n",
+				newdb.Log.Error.Printf(
+					"Invalid room in Course/SubCourse %s:\n  %s\n",
 					courseId, rref0)
 			}
 		}
@@ -193,6 +242,7 @@ func (db *DbTopLevel) getCourseRoom(
 }
 
 func (db *DbTopLevel) getCourseGroups(
+	newdb *base.DbTopLevel,
 	grefs []Ref,
 	courseId base.Ref,
 ) []base.Ref {
@@ -204,7 +254,8 @@ func (db *DbTopLevel) getCourseGroups(
 	for _, gref := range grefs {
 		ngref, ok := db.GroupRefMap[gref]
 		if !ok {
-			base.Error.Fatalf("Invalid group in Course/SubCourse %s:\n  %s\n",
+			newdb.Log.Error.Fatalf(
+				"Invalid group in Course/SubCourse %s:\n  %s\n",
 				courseId, gref)
 		}
 		glist = append(glist, ngref)
@@ -213,6 +264,7 @@ func (db *DbTopLevel) getCourseGroups(
 }
 
 func (db *DbTopLevel) getCourseTeachers(
+	newdb *base.DbTopLevel,
 	trefs []Ref,
 	courseId base.Ref,
 ) []base.Ref {
@@ -223,7 +275,7 @@ func (db *DbTopLevel) getCourseTeachers(
 	for _, tref := range trefs {
 		_, ok := db.TeacherMap[tref]
 		if !ok {
-			base.Error.Fatalf("Unknown teacher in Course %s:\n  %s\n",
+			newdb.Log.Error.Fatalf("Unknown teacher in Course %s:\n  %s\n",
 				courseId, tref)
 		}
 		tlist = append(tlist, tref)
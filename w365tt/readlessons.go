@@ -9,7 +9,7 @@ func (db *DbTopLevel) readLessons(newdb *base.DbTopLevel) {
 		// The course must be Course or Supercourse.
 		_, ok := db.CourseMap[e.Course]
 		if !ok {
-			base.Error.Fatalf(
+			newdb.Log.Error.Fatalf(
 				"Lesson %s:\n  Invalid course: %s\n",
 				e.Id, e.Course)
 		}
@@ -20,7 +20,7 @@ func (db *DbTopLevel) readLessons(newdb *base.DbTopLevel) {
 			if ok {
 				reflist = append(reflist, rref)
 			} else {
-				base.Error.Printf(
+				newdb.Log.Error.Printf(
 					"Invalid Room in Lesson %s:\n  %s\n",
 					e.Id, rref)
 			}
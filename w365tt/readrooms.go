@@ -15,7 +15,7 @@ func (db *DbTopLevel) readRooms(newdb *base.DbTopLevel) {
 		// Perform some checks and add to the RoomTags map.
 		_, nok := db.RoomTags[e.Tag]
 		if nok {
-			base.Error.Fatalf(
+			newdb.Log.Error.Fatalf(
 				"Room Tag (Shortcut) defined twice: %s\n",
 				e.Tag)
 		}
@@ -37,7 +37,7 @@ func (db *DbTopLevel) readRoomGroups(newdb *base.DbTopLevel) {
 		if e.Tag != "" {
 			_, nok := db.RoomTags[e.Tag]
 			if nok {
-				base.Error.Fatalf(
+				newdb.Log.Error.Fatalf(
 					"Room Tag (Shortcut) defined twice: %s\n",
 					e.Tag)
 			}
@@ -66,7 +66,7 @@ func (db *DbTopLevel) checkRoomGroups(newdb *base.DbTopLevel) {
 				continue
 
 			}
-			base.Error.Printf(
+			newdb.Log.Error.Printf(
 				"Invalid Room in RoomGroup %s:\n  %s\n",
 				e.Tag, rref)
 		}
@@ -136,6 +136,7 @@ func (db *DbTopLevel) makeRoomChoiceGroup(
 		r.Tag = tag
 		r.Name = name
 		r.Rooms = reflist
+		id = r.Id
 		db.RoomTags[tag] = id
 		db.RoomChoiceNames[name] = id
 	}
@@ -52,6 +52,7 @@ type Teacher struct {
 	MaxGapsPerWeek   int        `json:"maxGapsPerWeek"`
 	MaxAfternoons    int        `json:"maxAfternoons"`
 	LunchBreak       bool       `json:"lunchBreak"`
+	Color            string     `json:"color,omitempty"`
 }
 
 func (t *Teacher) UnmarshalJSON(data []byte) error {
@@ -68,10 +69,11 @@ func (t *Teacher) UnmarshalJSON(data []byte) error {
 }
 
 type Subject struct {
-	Id   Ref    `json:"id"`
-	Type string `json:"type"`
-	Name string `json:"name"`
-	Tag  string `json:"shortcut"`
+	Id    Ref    `json:"id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Tag   string `json:"shortcut"`
+	Color string `json:"color,omitempty"`
 }
 
 type Room struct {
@@ -114,6 +116,7 @@ type Class struct {
 	MaxAfternoons    int        `json:"maxAfternoons"`
 	LunchBreak       bool       `json:"lunchBreak"`
 	ForceFirstHour   bool       `json:"forceFirstHour"`
+	Color            string     `json:"color,omitempty"`
 }
 
 func (t *Class) UnmarshalJSON(data []byte) error {
@@ -135,7 +138,7 @@ type Group struct {
 }
 
 type Division struct {
-	Id     Ref    `json:"id"`
+	Id     Ref    `json:"id,omitempty"`
 	Type   string `json:"type"`
 	Name   string `json:"name"`
 	Groups []Ref  `json:"groups"`
@@ -153,7 +156,8 @@ type Course struct {
 type SuperCourse struct {
 	Id         Ref         `json:"id"`
 	Type       string      `json:"type"`
-	EpochPlan  Ref         `json:"epochPlan"`
+	EpochPlan  Ref         `json:"epochPlan,omitempty"`
+	Subject    Ref         `json:"subject,omitempty"`
 	SubCourses []SubCourse `json:"subCourses"`
 }
 
@@ -180,14 +184,23 @@ type Lesson struct {
 }
 
 type EpochPlan struct {
-	Id   Ref    `json:"id"`
-	Type string `json:"type"`
-	Tag  string `json:"shortcut"`
-	Name string `json:"name"`
+	Id     Ref     `json:"id"`
+	Type   string  `json:"type"`
+	Tag    string  `json:"shortcut"`
+	Name   string  `json:"name"`
+	Epochs []Epoch `json:"epochs,omitempty"`
+}
+
+// An Epoch links a Course or SubCourse to the school weeks (starting at 0)
+// in which it is taught.
+type Epoch struct {
+	Course Ref   `json:"course"`
+	Weeks  []int `json:"weeks"`
 }
 
 type PrintOptions struct {
 	PrintTables []string       `json:"printTables"`
+	ColorScheme string         `json:"colorScheme,omitempty"`
 	Typst       map[string]any `json:"typst"`
 }
 
@@ -205,7 +218,7 @@ type DbTopLevel struct {
 	Courses      []*Course        `json:"courses"`
 	SuperCourses []*SuperCourse   `json:"superCourses"`
 	Lessons      []*Lesson        `json:"lessons"`
-	EpochPlans   []*EpochPlan     `json:"epochPlans"`
+	EpochPlans   []*EpochPlan     `json:"epochPlans,omitempty"`
 	Constraints  []map[string]any `json:"constraints"`
 
 	// These fields do not belong in the JSON object.
@@ -0,0 +1,376 @@
+package w365tt
+
+import (
+	"W365toFET/base"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Build the W365 JSON structure from a base db. Loading the result with
+// LoadJSON should reproduce the base db, apart from the Ids of elements
+// which are not present in W365 (the whole-class Groups and the
+// RoomChoiceGroups), which are regenerated on loading.
+func ToW365(db *base.DbTopLevel) *DbTopLevel {
+	w := &DbTopLevel{
+		Info:         Info(db.Info),
+		PrintOptions: PrintOptions(db.PrintOptions),
+	}
+	// The whole-class Groups are not W365 elements, they are replaced by
+	// the Class in the course group lists.
+	classGroups := map[Ref]Ref{}
+	for _, c := range db.Classes {
+		if c.ClassGroup != "" {
+			classGroups[c.ClassGroup] = c.Id
+		}
+	}
+	w.writeDays(db)
+	w.writeHours(db)
+	w.writeTeachers(db)
+	w.writeSubjects(db)
+	w.writeRooms(db)
+	w.writeClasses(db, classGroups)
+	w.writeCourses(db, classGroups)
+	w.writeLessons(db)
+	w.writeEpochPlans(db)
+	w.writeConstraints(db)
+	return w
+}
+
+func SaveJSON(db *base.DbTopLevel, jsonpath string) bool {
+	j, err := json.MarshalIndent(ToW365(db), "", "  ")
+	if err != nil {
+		db.Log.Error.Println(err)
+		return false
+	}
+	if err := os.WriteFile(jsonpath, j, 0666); err != nil {
+		db.Log.Error.Println(err)
+		return false
+	}
+	db.Log.Message.Printf("Wrote: %s\n", jsonpath)
+	return true
+}
+
+func timeSlots(tslist []base.TimeSlot) []TimeSlot {
+	na := []TimeSlot{}
+	for _, ts := range tslist {
+		na = append(na, TimeSlot{Day: ts.Day, Hour: ts.Hour})
+	}
+	return na
+}
+
+func refList(rlist []Ref) []Ref {
+	if rlist == nil {
+		return []Ref{}
+	}
+	return rlist
+}
+
+func (w *DbTopLevel) writeDays(db *base.DbTopLevel) {
+	for _, e := range db.Days {
+		w.Days = append(w.Days, &Day{
+			Id:   e.Id,
+			Type: "Day",
+			Name: e.Name,
+			Tag:  e.Tag,
+		})
+	}
+}
+
+func (w *DbTopLevel) writeHours(db *base.DbTopLevel) {
+	for _, e := range db.Hours {
+		w.Hours = append(w.Hours, &Hour{
+			Id:    e.Id,
+			Type:  "Hour",
+			Name:  e.Name,
+			Tag:   e.Tag,
+			Start: e.Start,
+			End:   e.End,
+		})
+	}
+}
+
+func (w *DbTopLevel) writeTeachers(db *base.DbTopLevel) {
+	for _, e := range db.Teachers {
+		w.Teachers = append(w.Teachers, &Teacher{
+			Id:               e.Id,
+			Type:             "Teacher",
+			Name:             e.Name,
+			Tag:              e.Tag,
+			Firstname:        e.Firstname,
+			NotAvailable:     timeSlots(e.NotAvailable),
+			MinLessonsPerDay: e.MinLessonsPerDay,
+			MaxLessonsPerDay: e.MaxLessonsPerDay,
+			MaxDays:          e.MaxDays,
+			MaxGapsPerDay:    e.MaxGapsPerDay,
+			MaxGapsPerWeek:   e.MaxGapsPerWeek,
+			MaxAfternoons:    e.MaxAfternoons,
+			LunchBreak:       e.LunchBreak,
+			Color:            e.Color,
+		})
+	}
+}
+
+func (w *DbTopLevel) writeSubjects(db *base.DbTopLevel) {
+	for _, e := range db.Subjects {
+		w.Subjects = append(w.Subjects, &Subject{
+			Id:    e.Id,
+			Type:  "Subject",
+			Name:  e.Name,
+			Tag:   e.Tag,
+			Color: e.Color,
+		})
+	}
+}
+
+func (w *DbTopLevel) writeRooms(db *base.DbTopLevel) {
+	for _, e := range db.Rooms {
+		w.Rooms = append(w.Rooms, &Room{
+			Id:           e.Id,
+			Type:         "Room",
+			Name:         e.Name,
+			Tag:          e.Tag,
+			NotAvailable: timeSlots(e.NotAvailable),
+		})
+	}
+	for _, e := range db.RoomGroups {
+		w.RoomGroups = append(w.RoomGroups, &RoomGroup{
+			Id:    e.Id,
+			Type:  "RoomGroup",
+			Name:  e.Name,
+			Tag:   e.Tag,
+			Rooms: refList(e.Rooms),
+		})
+	}
+	// RoomChoiceGroups are not W365 elements, they are written as lists
+	// of preferred rooms in the courses.
+}
+
+func (w *DbTopLevel) writeClasses(
+	db *base.DbTopLevel,
+	classGroups map[Ref]Ref,
+) {
+	for _, e := range db.Classes {
+		divs := []Division{}
+		for _, d := range e.Divisions {
+			divs = append(divs, Division{
+				Type:   "Division",
+				Name:   d.Name,
+				Groups: refList(d.Groups),
+			})
+		}
+		w.Classes = append(w.Classes, &Class{
+			Id:               e.Id,
+			Type:             "Class",
+			Name:             e.Name,
+			Tag:              e.Tag,
+			Year:             e.Year,
+			Letter:           e.Letter,
+			NotAvailable:     timeSlots(e.NotAvailable),
+			Divisions:        divs,
+			MinLessonsPerDay: e.MinLessonsPerDay,
+			MaxLessonsPerDay: e.MaxLessonsPerDay,
+			MaxGapsPerDay:    e.MaxGapsPerDay,
+			MaxGapsPerWeek:   e.MaxGapsPerWeek,
+			MaxAfternoons:    e.MaxAfternoons,
+			LunchBreak:       e.LunchBreak,
+			ForceFirstHour:   e.ForceFirstHour,
+			Color:            e.Color,
+		})
+	}
+	for _, e := range db.Groups {
+		if _, ok := classGroups[e.Id]; ok {
+			continue
+		}
+		w.Groups = append(w.Groups, &Group{
+			Id:   e.Id,
+			Type: "Group",
+			Tag:  e.Tag,
+		})
+	}
+}
+
+// Convert the base "room" of a course to the W365 "preferredRooms" list.
+// A RoomChoiceGroup becomes the list of its rooms.
+func preferredRooms(db *base.DbTopLevel, rref Ref) []Ref {
+	if rref == "" {
+		return []Ref{}
+	}
+	rc, ok := db.Elements[rref].(*base.RoomChoiceGroup)
+	if ok {
+		return refList(rc.Rooms)
+	}
+	return []Ref{rref}
+}
+
+func courseGroups(grefs []Ref, classGroups map[Ref]Ref) []Ref {
+	glist := []Ref{}
+	for _, gref := range grefs {
+		cref, ok := classGroups[gref]
+		if ok {
+			glist = append(glist, cref)
+		} else {
+			glist = append(glist, gref)
+		}
+	}
+	return glist
+}
+
+func (w *DbTopLevel) writeCourses(
+	db *base.DbTopLevel,
+	classGroups map[Ref]Ref,
+) {
+	for _, e := range db.Courses {
+		w.Courses = append(w.Courses, &Course{
+			Id:             e.Id,
+			Type:           "Course",
+			Subjects:       []Ref{e.Subject},
+			Groups:         courseGroups(e.Groups, classGroups),
+			Teachers:       refList(e.Teachers),
+			PreferredRooms: preferredRooms(db, e.Room),
+		})
+	}
+	for _, spc := range db.SuperCourses {
+		subs := []SubCourse{}
+		for _, e := range db.SubCourses {
+			if !slices.Contains(e.SuperCourses, spc.Id) {
+				continue
+			}
+			// Remove the prefix added by readSuperCourses.
+			subs = append(subs, SubCourse{
+				Id:             Ref(strings.TrimPrefix(string(e.Id), "$$")),
+				Type:           "SubCourse",
+				Subjects:       []Ref{e.Subject},
+				Groups:         courseGroups(e.Groups, classGroups),
+				Teachers:       refList(e.Teachers),
+				PreferredRooms: preferredRooms(db, e.Room),
+			})
+		}
+		w.SuperCourses = append(w.SuperCourses, &SuperCourse{
+			Id:         spc.Id,
+			Type:       "SuperCourse",
+			EpochPlan:  spc.EpochPlan,
+			Subject:    spc.Subject,
+			SubCourses: subs,
+		})
+	}
+}
+
+func (w *DbTopLevel) writeLessons(db *base.DbTopLevel) {
+	for _, e := range db.Lessons {
+		w.Lessons = append(w.Lessons, &Lesson{
+			Id:         e.Id,
+			Type:       "Lesson",
+			Course:     e.Course,
+			Duration:   e.Duration,
+			Day:        e.Day,
+			Hour:       e.Hour,
+			Fixed:      e.Fixed,
+			Rooms:      refList(e.Rooms),
+			Flags:      e.Flags,
+			Background: e.Background,
+		})
+	}
+}
+
+func (w *DbTopLevel) writeEpochPlans(db *base.DbTopLevel) {
+	for _, e := range db.EpochPlans {
+		epochs := []Epoch{}
+		for _, epoch := range e.Epochs {
+			epochs = append(epochs, Epoch{
+				Course: Ref(strings.TrimPrefix(string(epoch.Course), "$$")),
+				Weeks:  epoch.Weeks,
+			})
+		}
+		w.EpochPlans = append(w.EpochPlans, &EpochPlan{
+			Id:     e.Id,
+			Type:   "EpochPlan",
+			Tag:    e.Tag,
+			Name:   e.Name,
+			Epochs: epochs,
+		})
+	}
+}
+
+// The constraint fields use the names expected by readConstraints.
+func (w *DbTopLevel) writeConstraints(db *base.DbTopLevel) {
+	w.Constraints = []map[string]any{}
+	for _, c := range db.Constraints {
+		var e map[string]any
+		switch cn := c.(type) {
+		case *base.LessonsEndDay:
+			e = map[string]any{
+				"constraint": "MARGIN_HOUR",
+				"weight":     cn.Weight,
+				"course":     cn.Course,
+			}
+		case *base.BeforeAfterHour:
+			e = map[string]any{
+				"constraint": "BEFORE_AFTER_HOUR",
+				"weight":     cn.Weight,
+				"courses":    refList(cn.Courses),
+				"after":      cn.After,
+				"hour":       cn.Hour,
+			}
+		case *base.AutomaticDifferentDays:
+			e = map[string]any{
+				"constraint":              "AUTOMATIC_DIFFERENT_DAYS",
+				"weight":                  cn.Weight,
+				"consecutive_if_same_day": cn.ConsecutiveIfSameDay,
+			}
+		case *base.DaysBetween:
+			e = map[string]any{
+				"constraint":              "DAYS_BETWEEN",
+				"weight":                  cn.Weight,
+				"courses":                 refList(cn.Courses),
+				"ndays":                   cn.DaysBetween,
+				"consecutive_if_same_day": cn.ConsecutiveIfSameDay,
+			}
+		case *base.DaysBetweenJoin:
+			e = map[string]any{
+				"constraint":              "DAYS_BETWEEN_JOIN",
+				"weight":                  cn.Weight,
+				"course1":                 cn.Course1,
+				"course2":                 cn.Course2,
+				"ndays":                   cn.DaysBetween,
+				"consecutive_if_same_day": cn.ConsecutiveIfSameDay,
+			}
+		case *base.ParallelCourses:
+			e = map[string]any{
+				"constraint": "PARALLEL_COURSES",
+				"weight":     cn.Weight,
+				"courses":    refList(cn.Courses),
+			}
+		case *base.DoubleLessonNotOverBreaks:
+			hours := cn.Hours
+			if hours == nil {
+				hours = []int{}
+			}
+			e = map[string]any{
+				"constraint": "DOUBLE_LESSON_NOT_OVER_BREAKS",
+				"weight":     cn.Weight,
+				"hours":      hours,
+			}
+		case *base.NotOnSameDay:
+			e = map[string]any{
+				"constraint": "NOT_ON_SAME_DAY",
+				"weight":     cn.Weight,
+				"subjects":   refList(cn.Subjects),
+			}
+		case *base.MinHoursFollowing:
+			e = map[string]any{
+				"constraint": "MIN_HOURS_FOLLOWING",
+				"weight":     cn.Weight,
+				"course1":    cn.Course1,
+				"course2":    cn.Course2,
+				"hours":      cn.Hours,
+			}
+		default:
+			db.Log.Error.Printf("Constraint not supported in W365 JSON: %s\n",
+				c.CType())
+			continue
+		}
+		w.Constraints = append(w.Constraints, e)
+	}
+}
@@ -0,0 +1,55 @@
+package w365tt
+
+import (
+	"W365toFET/base"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var roundtripfiles = []string{
+	"../testdata/Versuch_D_Margin_hour_constraint_w365.json",
+}
+
+func TestToJSON(t *testing.T) {
+	// Export a loaded _w365.json file, load the result and export again.
+	// The two exported files should be identical.
+	base.OpenLog("")
+	tmpdir := t.TempDir()
+	for _, fjson := range roundtripfiles {
+		db := base.NewDb()
+		LoadJSON(db, fjson)
+		f1 := filepath.Join(tmpdir, "export1_w365.json")
+		if !SaveJSON(db, f1) {
+			t.Fatalf("Export failed: %s", fjson)
+		}
+
+		db2 := base.NewDb()
+		LoadJSON(db2, f1)
+		f2 := filepath.Join(tmpdir, "export2_w365.json")
+		if !SaveJSON(db2, f2) {
+			t.Fatalf("Second export failed: %s", fjson)
+		}
+
+		b1, err := os.ReadFile(f1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b2, err := os.ReadFile(f2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("Export not stable: %s", fjson)
+		}
+		if len(db2.Lessons) != len(db.Lessons) ||
+			len(db2.Courses) != len(db.Courses) ||
+			len(db2.SubCourses) != len(db.SubCourses) ||
+			len(db2.Constraints) != len(db.Constraints) {
+			t.Errorf("Elements lost in export: %s", fjson)
+		}
+		fmt.Printf("\n ***** Round trip OK: %s *****\n", fjson)
+	}
+}